@@ -32,7 +32,7 @@ import (
 var (
 	dpi      = flag.Float64("dpi", 72, "screen resolution in Dots Per Inch")
 	fontfile = flag.String("fontfile", "../../testdata/luxisr.ttf", "filename of the ttf font")
-	hinting  = flag.String("hinting", "none", "none | full")
+	hinting  = flag.String("hinting", "none", "none | vertical | full")
 	size     = flag.Float64("size", 12, "font size in points")
 	spacing  = flag.Float64("spacing", 1.5, "line spacing (e.g. 2 means double spaced)")
 	wonb     = flag.Bool("whiteonblack", false, "white text on a black background")
@@ -110,6 +110,8 @@ func main() {
 	// Draw the text.
 	h := font.HintingNone
 	switch *hinting {
+	case "vertical":
+		h = font.HintingVertical
 	case "full":
 		h = font.HintingFull
 	}
@@ -30,7 +30,7 @@ import (
 var (
 	dpi      = flag.Float64("dpi", 72, "screen resolution in Dots Per Inch")
 	fontfile = flag.String("fontfile", "../../testdata/luxisr.ttf", "filename of the ttf font")
-	hinting  = flag.String("hinting", "none", "none | full")
+	hinting  = flag.String("hinting", "none", "none | vertical | full")
 	size     = flag.Float64("size", 12, "font size in points")
 	spacing  = flag.Float64("spacing", 1.5, "line spacing (e.g. 2 means double spaced)")
 	wonb     = flag.Bool("whiteonblack", false, "white text on a black background")
@@ -107,6 +107,8 @@ func main() {
 	switch *hinting {
 	default:
 		c.SetHinting(font.HintingNone)
+	case "vertical":
+		c.SetHinting(font.HintingVertical)
 	case "full":
 		c.SetHinting(font.HintingFull)
 	}
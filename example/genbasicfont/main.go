@@ -56,7 +56,6 @@ func parseHinting(h string) font.Hinting {
 	case "full":
 		return font.HintingFull
 	case "vertical":
-		log.Fatal("TODO: have package truetype implement vertical hinting")
 		return font.HintingVertical
 	}
 	return font.HintingNone
@@ -0,0 +1,120 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+// +build example
+//
+// This build tag means that "go install github.com/golang/freetype/..."
+// doesn't install this example program. Use "go run main.go" to run it or "go
+// install -tags=example" to install it.
+
+// Program ttdebug traces the hinting bytecode that runs when loading a glyph,
+// printing each instruction with the stack and the points that it moves. With
+// -step, it waits after each instruction: press Enter to step, "c" and Enter
+// to run to the end, or "q" and Enter to quit.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+var (
+	fontfile = flag.String("fontfile", "../../testdata/luxisr.ttf", "filename of the ttf font")
+	ppem     = flag.Int("ppem", 12, "pixels per em")
+	glyph    = flag.String("glyph", "A", "the character whose glyph to load")
+	index    = flag.Int("index", -1, "the index of the glyph to load, instead of -glyph")
+	programs = flag.String("programs", "glyf", "comma-separated programs to trace: fpgm, prep and glyf")
+	gs       = flag.Bool("gs", false, "print the graphics state after each instruction")
+	step     = flag.Bool("step", false, "wait for Enter after each instruction")
+	subpixel = flag.Bool("subpixel", false, "use subpixel hinting")
+)
+
+var zoneNames = [2]string{"twilight", "glyph"}
+
+func printStep(s *truetype.HintingStep) {
+	where := s.Program
+	if s.Function >= 0 {
+		where = fmt.Sprintf("%s fn %d", where, s.Function)
+	}
+	fmt.Printf("%-12s %5d  %-12s %v\n", where, s.PC, s.Name(), s.Stack)
+	if s.Err != nil {
+		fmt.Printf("    error: %v\n", s.Err)
+	}
+	for z, moved := range s.Moved {
+		for _, m := range moved {
+			fmt.Printf("    %s point %d: (%d, %d) -> (%d, %d)\n",
+				zoneNames[z], m.Index, m.From.X, m.From.Y, m.To.X, m.To.Y)
+		}
+	}
+	if *gs {
+		g := s.GraphicsState
+		fmt.Printf("    pv %v fv %v dv %v rp %v zp %v loop %d minDist %d round %d/%d/%d\n",
+			g.ProjectionVector, g.FreedomVector, g.DualProjectionVector, g.RP, g.ZP,
+			g.Loop, g.MinDistance, g.RoundPeriod, g.RoundPhase, g.RoundThreshold)
+	}
+}
+
+func main() {
+	flag.Parse()
+	b, err := ioutil.ReadFile(*fontfile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	f, err := truetype.Parse(b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	i := truetype.Index(*index)
+	if *index < 0 {
+		r, _ := utf8.DecodeRuneInString(*glyph)
+		i = f.Index(r)
+	}
+
+	traced := map[string]bool{}
+	for _, p := range strings.Split(*programs, ",") {
+		traced[strings.TrimSpace(p)] = true
+	}
+	stdin, stepping := bufio.NewReader(os.Stdin), *step
+	g := &truetype.GlyphBuf{
+		SubpixelHinting: *subpixel,
+		Trace: func(s *truetype.HintingStep) {
+			if !traced[s.Program] {
+				return
+			}
+			printStep(s)
+			if !stepping {
+				return
+			}
+			line, err := stdin.ReadString('\n')
+			switch strings.TrimSpace(line) {
+			case "c":
+				stepping = false
+			case "q":
+				os.Exit(0)
+			}
+			if err != nil {
+				stepping = false
+			}
+		},
+	}
+	if err := g.Load(f, fixed.I(*ppem), i, font.HintingFull); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("glyph %d at %d ppem: AdvanceWidth:%d\n", i, *ppem, g.AdvanceWidth)
+	for j, p := range g.Points {
+		fmt.Printf("%4d: %5d, %5d\n", j, p.X, p.Y)
+	}
+}
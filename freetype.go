@@ -11,9 +11,11 @@ package freetype // import "github.com/golang/freetype"
 import (
 	"errors"
 	"image"
+	"image/color"
 	"image/draw"
 
 	"github.com/golang/freetype/raster"
+	"github.com/golang/freetype/shaping"
 	"github.com/golang/freetype/truetype"
 	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
@@ -71,8 +73,26 @@ type Context struct {
 	fontSize, dpi float64
 	scale         fixed.Int26_6
 	hinting       font.Hinting
+	// layout are the options for substituting DrawString's glyphs.
+	layout *truetype.LayoutOptions
 	// cache is the glyph cache.
 	cache [nGlyphs * nXFractions * nYFractions]cacheEntry
+	// palette is the CPAL palette of color glyphs, and colorCache caches
+	// their images.
+	palette    int
+	colorCache map[truetype.Index]*truetype.Bitmap
+	// vertical is whether text is laid out top-to-bottom, and
+	// verticalCache caches the glyphs' vertical metrics.
+	vertical      bool
+	verticalCache map[truetype.Index]verticalMetrics
+}
+
+// verticalMetrics are a glyph's metrics for vertical layout.
+type verticalMetrics struct {
+	// origin is the glyph's vertical origin, relative to its horizontal
+	// origin, with positive Y going upwards.
+	origin  fixed.Point26_6
+	advance fixed.Int26_6
 }
 
 // PointToFixed converts the given number of points (as in "a 12 point font")
@@ -88,9 +108,11 @@ func (c *Context) drawContour(ps []truetype.Point, dx, dy fixed.Int26_6) {
 	}
 
 	// The low bit of each point's Flags value is whether the point is on the
-	// curve. Truetype fonts only have quadratic Bézier curves, not cubics.
+	// curve. Truetype outlines only have quadratic Bézier curves, not cubics.
 	// Thus, two consecutive off-curve points imply an on-curve point in the
-	// middle of those two.
+	// middle of those two. CFF outlines only have cubic Bézier curves, whose
+	// off-curve points have the truetype.FlagCubic bit set and come in
+	// pairs.
 	//
 	// See http://chanae.walon.org/pub/ttf/ttf_glyphs.htm for more details.
 
@@ -121,19 +143,26 @@ func (c *Context) drawContour(ps []truetype.Point, dx, dy fixed.Int26_6) {
 		}
 	}
 	c.r.Start(start)
-	q0, on0 := start, true
+	q0, on0, cubic0 := start, true, false
+	// ctrl is the first of a pair of cubic control points.
+	var ctrl fixed.Point26_6
 	for _, p := range others {
 		q := fixed.Point26_6{
 			X: dx + p.X,
 			Y: dy - p.Y,
 		}
 		on := p.Flags&0x01 != 0
+		cubic := !on && p.Flags&truetype.FlagCubic != 0
 		if on {
 			if on0 {
 				c.r.Add1(q)
+			} else if cubic0 {
+				c.r.Add3(ctrl, q0, q)
 			} else {
 				c.r.Add2(q0, q)
 			}
+		} else if cubic {
+			ctrl = q0
 		} else {
 			if on0 {
 				// No-op.
@@ -145,11 +174,13 @@ func (c *Context) drawContour(ps []truetype.Point, dx, dy fixed.Int26_6) {
 				c.r.Add2(q0, mid)
 			}
 		}
-		q0, on0 = q, on
+		q0, on0, cubic0 = q, on, cubic
 	}
 	// Close the curve.
 	if on0 {
 		c.r.Add1(start)
+	} else if cubic0 {
+		c.r.Add3(ctrl, q0, start)
 	} else {
 		c.r.Add2(q0, start)
 	}
@@ -227,36 +258,254 @@ func (c *Context) glyph(glyph truetype.Index, p fixed.Point26_6) (
 // affect pixels below and left of the point.
 //
 // p is a fixed.Point26_6 and can therefore represent sub-pixel positions.
+//
+// The runes of s are mapped to glyphs by the font's GSUB substitutions, such
+// as ligatures, as selected by SetLayoutOptions.
+//
+// If SetVertical has set vertical layout, then p is the vertical origin of
+// the first character, which is centered above it, and the vertical
+// alternates of the font's vert feature are used.
 func (c *Context) DrawString(s string, p fixed.Point26_6) (fixed.Point26_6, error) {
 	if c.f == nil {
 		return fixed.Point26_6{}, errors.New("freetype: DrawText called with a nil font")
 	}
-	prev, hasPrev := truetype.Index(0), false
-	for _, rune := range s {
-		index := c.f.Index(rune)
-		if hasPrev {
-			kern := c.f.Kern(c.scale, prev, index)
-			if c.hinting != font.HintingNone {
+	o := c.layout
+	if c.vertical {
+		v := truetype.LayoutOptions{}
+		if o != nil {
+			v = *o
+		}
+		v.Vertical = true
+		o = &v
+	}
+	return c.DrawGlyphs(c.f.Substitute([]rune(s), o), p)
+}
+
+// DrawGlyphs draws the glyphs, given by their index in the font, at p and
+// returns p advanced by their extent. It is like DrawString, without the
+// substitution of runes by glyphs.
+//
+// Marks, such as combining accents, that the font attaches to the preceding
+// glyph are positioned by the font's anchors and do not advance p.
+//
+// If SetVertical has set vertical layout, then p is advanced downwards by
+// the glyphs' advance heights, and glyph pairs are not kerned.
+func (c *Context) DrawGlyphs(glyphs []truetype.Index, p fixed.Point26_6) (fixed.Point26_6, error) {
+	if c.f == nil {
+		return fixed.Point26_6{}, errors.New("freetype: DrawGlyphs called with a nil font")
+	}
+	// base is the last glyph that was not attached as a mark, and basePos is
+	// where it was drawn. mark and markPos are the same for the last
+	// attached mark, if it follows base.
+	base, basePos, hasBase := truetype.Index(0), fixed.Point26_6{}, false
+	mark, markPos, hasMark := truetype.Index(0), fixed.Point26_6{}, false
+	for _, index := range glyphs {
+		if pos, ok := c.attach(index, mark, markPos, hasMark); ok {
+			if err := c.drawGlyph(index, pos); err != nil {
+				return fixed.Point26_6{}, err
+			}
+			mark, markPos = index, pos
+			continue
+		}
+		if pos, ok := c.attach(index, base, basePos, hasBase); ok {
+			if err := c.drawGlyph(index, pos); err != nil {
+				return fixed.Point26_6{}, err
+			}
+			mark, markPos, hasMark = index, pos, true
+			continue
+		}
+		if hasBase && !c.vertical {
+			kern := c.f.Kern(c.scale, base, index)
+			if c.hinting == font.HintingFull {
 				kern = (kern + 32) &^ 63
 			}
 			p.X += kern
 		}
-		advanceWidth, mask, offset, err := c.glyph(index, p)
+		// q is the glyph's horizontal origin.
+		q, vm := p, verticalMetrics{}
+		if c.vertical {
+			var err error
+			if vm, err = c.verticalMetrics(index); err != nil {
+				return fixed.Point26_6{}, err
+			}
+			q = fixed.Point26_6{X: p.X - vm.origin.X, Y: p.Y + vm.origin.Y}
+		}
+		advanceWidth, mask, offset, err := c.glyph(index, q)
 		if err != nil {
 			return fixed.Point26_6{}, err
 		}
-		p.X += advanceWidth
-		glyphRect := mask.Bounds().Add(offset)
-		dr := c.clip.Intersect(glyphRect)
-		if !dr.Empty() {
-			mp := image.Point{0, dr.Min.Y - glyphRect.Min.Y}
-			draw.DrawMask(c.dst, dr, c.src, image.ZP, mask, mp, draw.Over)
+		drawn, err := c.drawColorGlyph(index, q)
+		if err != nil {
+			return fixed.Point26_6{}, err
+		}
+		if !drawn {
+			c.drawMask(mask, offset)
+		}
+		base, basePos, hasBase = index, q, true
+		hasMark = false
+		if c.vertical {
+			p.Y += vm.advance
+		} else {
+			p.X += advanceWidth
+		}
+	}
+	return p, nil
+}
+
+// verticalMetrics returns the glyph's metrics for vertical layout.
+func (c *Context) verticalMetrics(index truetype.Index) (verticalMetrics, error) {
+	if m, ok := c.verticalCache[index]; ok {
+		return m, nil
+	}
+	if err := c.glyphBuf.Load(c.f, c.scale, index, c.hinting); err != nil {
+		return verticalMetrics{}, err
+	}
+	m := verticalMetrics{c.glyphBuf.VerticalOrigin, c.glyphBuf.AdvanceHeight}
+	if c.verticalCache == nil {
+		c.verticalCache = make(map[truetype.Index]verticalMetrics)
+	}
+	c.verticalCache[index] = m
+	return m, nil
+}
+
+// Shape shapes the text with the context's font, size and hinting, for
+// drawing with DrawRun. A nil o means to use the default options.
+func (c *Context) Shape(s string, o *shaping.Options) shaping.Run {
+	if c.f == nil {
+		return shaping.Run{}
+	}
+	opts := shaping.Options{}
+	if o != nil {
+		opts = *o
+	}
+	opts.Hinting = c.hinting
+	return shaping.Shape(c.f, c.scale, []rune(s), &opts)
+}
+
+// Layout shapes a paragraph of bidirectional text with the context's font,
+// size and hinting, for drawing with DrawRuns. A nil o means to use the
+// default options.
+func (c *Context) Layout(s string, o *shaping.Options) []shaping.Run {
+	if c.f == nil {
+		return nil
+	}
+	opts := shaping.Options{}
+	if o != nil {
+		opts = *o
+	}
+	opts.Hinting = c.hinting
+	return shaping.Layout(c.f, c.scale, []rune(s), &opts)
+}
+
+// DrawRuns draws the shaped runs, in order from left to right, at p and
+// returns p advanced by their total advance. Mixed left-to-right and
+// right-to-left text is drawn in visual order by passing the runs that
+// Layout returns.
+func (c *Context) DrawRuns(runs []shaping.Run, p fixed.Point26_6) (fixed.Point26_6, error) {
+	for _, run := range runs {
+		var err error
+		if p, err = c.DrawRun(run, p); err != nil {
+			return fixed.Point26_6{}, err
+		}
+	}
+	return p, nil
+}
+
+// DrawRun draws the shaped run at p, which is the origin of the run's
+// leftmost glyph, and returns p advanced by the run's advance. The run
+// should have been shaped with the context's font and size, such as by
+// Shape.
+func (c *Context) DrawRun(run shaping.Run, p fixed.Point26_6) (fixed.Point26_6, error) {
+	if c.f == nil {
+		return fixed.Point26_6{}, errors.New("freetype: DrawRun called with a nil font")
+	}
+	for _, g := range run.Glyphs {
+		q := fixed.Point26_6{X: p.X + g.XOffset, Y: p.Y + g.YOffset}
+		if err := c.drawGlyph(g.Index, q); err != nil {
+			return fixed.Point26_6{}, err
 		}
-		prev, hasPrev = index, true
+		p.X += g.XAdvance
 	}
 	return p, nil
 }
 
+// attach returns where to draw the mark glyph, if the font attaches it to the
+// glyph prev that was drawn at prevPos.
+func (c *Context) attach(mark, prev truetype.Index, prevPos fixed.Point26_6, hasPrev bool) (fixed.Point26_6, bool) {
+	if !hasPrev {
+		return fixed.Point26_6{}, false
+	}
+	d, ok := c.f.MarkAttachment(c.scale, prev, mark)
+	if !ok {
+		return fixed.Point26_6{}, false
+	}
+	if c.hinting == font.HintingFull {
+		d.X = (d.X + 32) &^ 63
+	}
+	if c.hinting != font.HintingNone {
+		d.Y = (d.Y + 32) &^ 63
+	}
+	// The font's Y axis points up, and the destination image's points down.
+	return fixed.Point26_6{X: prevPos.X + d.X, Y: prevPos.Y - d.Y}, true
+}
+
+// drawGlyph draws the glyph with the given index at p.
+func (c *Context) drawGlyph(index truetype.Index, p fixed.Point26_6) error {
+	if drawn, err := c.drawColorGlyph(index, p); drawn || err != nil {
+		return err
+	}
+	_, mask, offset, err := c.glyph(index, p)
+	if err != nil {
+		return err
+	}
+	c.drawMask(mask, offset)
+	return nil
+}
+
+// drawColorGlyph draws the font's COLR color glyph with the given index at
+// p, if it has one, and returns whether it did. Colors that the font leaves
+// to the text are the color of c.src, if it is an *image.Uniform.
+func (c *Context) drawColorGlyph(index truetype.Index, p fixed.Point26_6) (bool, error) {
+	if !c.f.HasColorGlyph(index) {
+		return false, nil
+	}
+	b, ok := c.colorCache[index]
+	if !ok {
+		var fg color.Color
+		if u, ok := c.src.(*image.Uniform); ok {
+			fg = u.C
+		}
+		var err error
+		if b, err = c.f.ColorGlyph(c.scale, index, c.palette, fg); err != nil {
+			return false, err
+		}
+		if c.colorCache == nil {
+			c.colorCache = make(map[truetype.Index]*truetype.Bitmap)
+		}
+		c.colorCache[index] = b
+	}
+	ib := b.Image.Bounds()
+	glyphRect := ib.Sub(ib.Min).Add(image.Point{
+		X: int((p.X+32)>>6) + b.Left,
+		Y: int((p.Y+32)>>6) - b.Top,
+	})
+	dr := c.clip.Intersect(glyphRect)
+	if !dr.Empty() {
+		draw.Draw(c.dst, dr, b.Image, ib.Min.Add(dr.Min.Sub(glyphRect.Min)), draw.Over)
+	}
+	return true, nil
+}
+
+// drawMask draws the glyph mask, whose top-left is at offset, onto c.dst.
+func (c *Context) drawMask(mask *image.Alpha, offset image.Point) {
+	glyphRect := mask.Bounds().Add(offset)
+	dr := c.clip.Intersect(glyphRect)
+	if !dr.Empty() {
+		mp := image.Point{0, dr.Min.Y - glyphRect.Min.Y}
+		draw.DrawMask(c.dst, dr, c.src, image.ZP, mask, mp, draw.Over)
+	}
+}
+
 // recalc recalculates scale and bounds values from the font size, screen
 // resolution and font metrics, and invalidates the glyph cache.
 func (c *Context) recalc() {
@@ -275,6 +524,8 @@ func (c *Context) recalc() {
 	for i := range c.cache {
 		c.cache[i] = cacheEntry{}
 	}
+	c.colorCache = nil
+	c.verticalCache = nil
 }
 
 // SetDPI sets the screen resolution in dots per inch.
@@ -304,12 +555,51 @@ func (c *Context) SetFontSize(fontSize float64) {
 	c.recalc()
 }
 
-// SetHinting sets the hinting policy.
+// SetHinting sets the hinting policy. font.HintingVertical snaps glyphs to
+// the pixel grid vertically, and leaves their horizontal positions and
+// advances unhinted.
 func (c *Context) SetHinting(hinting font.Hinting) {
 	c.hinting = hinting
 	for i := range c.cache {
 		c.cache[i] = cacheEntry{}
 	}
+	c.verticalCache = nil
+}
+
+// SetSubpixelHinting sets whether hinting preserves glyphs' horizontal shapes
+// and advance widths, like C Freetype's v40 interpreter. See
+// truetype.GlyphBuf's SubpixelHinting field for details.
+func (c *Context) SetSubpixelHinting(subpixel bool) {
+	c.glyphBuf.SubpixelHinting = subpixel
+	for i := range c.cache {
+		c.cache[i] = cacheEntry{}
+	}
+	c.verticalCache = nil
+}
+
+// SetAutoHinting sets whether glyphs are hinted by an automatic hinter,
+// instead of by the font's bytecode. See truetype.GlyphBuf's AutoHinting
+// field for details.
+func (c *Context) SetAutoHinting(autoHinting bool) {
+	c.glyphBuf.AutoHinting = autoHinting
+	for i := range c.cache {
+		c.cache[i] = cacheEntry{}
+	}
+	c.verticalCache = nil
+}
+
+// SetLayoutOptions sets the script, language system and features that
+// DrawString uses to substitute glyphs. A nil value means to use the font's
+// defaults.
+func (c *Context) SetLayoutOptions(o *truetype.LayoutOptions) {
+	c.layout = o
+}
+
+// SetVertical sets whether DrawString and DrawGlyphs lay text out
+// top-to-bottom, as for vertical Japanese, using the font's vertical metrics
+// and the vertical alternates of its vert feature.
+func (c *Context) SetVertical(vertical bool) {
+	c.vertical = vertical
 }
 
 // SetDst sets the destination image for draw operations.
@@ -321,6 +611,18 @@ func (c *Context) SetDst(dst draw.Image) {
 // image.Uniform.
 func (c *Context) SetSrc(src image.Image) {
 	c.src = src
+	c.colorCache = nil
+}
+
+// SetPalette sets the index of the CPAL palette that color glyphs, from the
+// font's COLR table, are drawn with. Color glyphs are drawn in their own
+// colors, instead of with the source image.
+func (c *Context) SetPalette(i int) {
+	if c.palette == i {
+		return
+	}
+	c.palette = i
+	c.colorCache = nil
 }
 
 // SetClip sets the clip rectangle for drawing.
@@ -0,0 +1,170 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package shaping
+
+import (
+	"unicode"
+
+	"github.com/golang/freetype/truetype"
+)
+
+// joiningType is a rune's Unicode joining type, as given by
+// ArabicShaping.txt.
+type joiningType uint8
+
+const (
+	joinNone        joiningType = iota // U: non-joining.
+	joinRight                          // R: joins the preceding rune.
+	joinDual                           // D: joins both neighbors.
+	joinLeft                           // L: joins the following rune.
+	joinCausing                        // C: joins both neighbors, but has no forms.
+	joinTransparent                    // T: ignored by joining.
+)
+
+// joiningTypes are the joining types of the Arabic and Arabic Supplement
+// blocks. Other runes are transparent if they are marks and non-joining
+// otherwise, except for ZWJ, which is join-causing.
+var joiningTypes = []struct {
+	lo, hi rune
+	t      joiningType
+}{
+	{0x0620, 0x0620, joinDual},
+	{0x0622, 0x0625, joinRight},
+	{0x0626, 0x0626, joinDual},
+	{0x0627, 0x0627, joinRight},
+	{0x0628, 0x0628, joinDual},
+	{0x0629, 0x0629, joinRight},
+	{0x062a, 0x062e, joinDual},
+	{0x062f, 0x0632, joinRight},
+	{0x0633, 0x063f, joinDual},
+	{0x0640, 0x0640, joinCausing},
+	{0x0641, 0x0647, joinDual},
+	{0x0648, 0x0648, joinRight},
+	{0x0649, 0x064a, joinDual},
+	{0x066e, 0x066f, joinDual},
+	{0x0671, 0x0673, joinRight},
+	{0x0675, 0x0677, joinRight},
+	{0x0678, 0x0687, joinDual},
+	{0x0688, 0x0699, joinRight},
+	{0x069a, 0x06bf, joinDual},
+	{0x06c0, 0x06c0, joinRight},
+	{0x06c1, 0x06c2, joinDual},
+	{0x06c3, 0x06cb, joinRight},
+	{0x06cc, 0x06cc, joinDual},
+	{0x06cd, 0x06cd, joinRight},
+	{0x06ce, 0x06ce, joinDual},
+	{0x06cf, 0x06cf, joinRight},
+	{0x06d0, 0x06d1, joinDual},
+	{0x06d2, 0x06d3, joinRight},
+	{0x06d5, 0x06d5, joinRight},
+	{0x06ee, 0x06ef, joinRight},
+	{0x06fa, 0x06fc, joinDual},
+	{0x06ff, 0x06ff, joinDual},
+	{0x0750, 0x0758, joinDual},
+	{0x0759, 0x075b, joinRight},
+	{0x075c, 0x076a, joinDual},
+	{0x076b, 0x076c, joinRight},
+	{0x076d, 0x0770, joinDual},
+	{0x0771, 0x0771, joinRight},
+	{0x0772, 0x0772, joinDual},
+	{0x0773, 0x0774, joinRight},
+	{0x0775, 0x0777, joinDual},
+	{0x0778, 0x0779, joinRight},
+	{0x077a, 0x077f, joinDual},
+	{0x200d, 0x200d, joinCausing},
+}
+
+// joining returns the rune's joining type.
+func joining(r rune) joiningType {
+	lo, hi := 0, len(joiningTypes)
+	for lo < hi {
+		m := (lo + hi) / 2
+		switch jt := joiningTypes[m]; {
+		case r < jt.lo:
+			hi = m
+		case r > jt.hi:
+			lo = m + 1
+		default:
+			return jt.t
+		}
+	}
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) && r != 0x200c {
+		return joinTransparent
+	}
+	return joinNone
+}
+
+// Masks of the Arabic positional forms.
+const (
+	maskIsol = 1 << iota
+	maskFina
+	maskMedi
+	maskInit
+)
+
+// joiningForms returns the mask of the positional form of each rune of the
+// text, which is zero for runes that have no forms.
+func joiningForms(text []rune) []uint32 {
+	types := make([]joiningType, len(text))
+	for i, r := range text {
+		types[i] = joining(r)
+	}
+	// neighbor returns the joining type of the nearest non-transparent rune
+	// in the given direction from i.
+	neighbor := func(i, step int) joiningType {
+		for i += step; 0 <= i && i < len(types); i += step {
+			if types[i] != joinTransparent {
+				return types[i]
+			}
+		}
+		return joinNone
+	}
+
+	masks := make([]uint32, len(text))
+	for i, t := range types {
+		if t != joinRight && t != joinDual && t != joinLeft {
+			continue
+		}
+		prev, next := neighbor(i, -1), neighbor(i, +1)
+		joinsPrev := t != joinLeft && (prev == joinDual || prev == joinLeft || prev == joinCausing)
+		joinsNext := t != joinRight && (next == joinDual || next == joinRight || next == joinCausing)
+		switch {
+		case joinsPrev && joinsNext:
+			masks[i] = maskMedi
+		case joinsPrev:
+			masks[i] = maskFina
+		case joinsNext:
+			masks[i] = maskInit
+		default:
+			masks[i] = maskIsol
+		}
+	}
+	return masks
+}
+
+// arabicShaper is the shaping model of the Arabic script, which selects
+// each letter's positional form by how it joins its neighbors.
+type arabicShaper struct{}
+
+func (arabicShaper) shape(f *truetype.Font, text []rune, o *truetype.LayoutOptions) []truetype.GlyphInfo {
+	glyphs := glyphInfos(f, text)
+	masks := joiningForms(text)
+	for i, g := range glyphs {
+		glyphs[i].Mask = masks[g.Cluster]
+	}
+	features := []truetype.Feature{
+		{Tag: "isol", Value: 1, Mask: maskIsol},
+		{Tag: "fina", Value: 1, Mask: maskFina},
+		{Tag: "medi", Value: 1, Mask: maskMedi},
+		{Tag: "init", Value: 1, Mask: maskInit},
+		{Tag: "mset", Value: 1},
+	}
+	return f.SubstituteGlyphs(glyphs, &truetype.LayoutOptions{
+		Script:   o.Script,
+		Language: o.Language,
+		Features: append(features, o.Features...),
+	})
+}
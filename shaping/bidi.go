@@ -0,0 +1,643 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package shaping
+
+// This file implements the Unicode Bidirectional Algorithm, as described at
+// http://www.unicode.org/reports/tr9/
+
+import (
+	"unicode"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/math/fixed"
+)
+
+// bidiClass is a rune's Bidi_Class.
+type bidiClass uint8
+
+const (
+	bidiL   bidiClass = iota // Left-to-right.
+	bidiR                    // Right-to-left.
+	bidiAL                   // Arabic letter.
+	bidiEN                   // European number.
+	bidiES                   // European separator.
+	bidiET                   // European terminator.
+	bidiAN                   // Arabic number.
+	bidiCS                   // Common separator.
+	bidiNSM                  // Non-spacing mark.
+	bidiBN                   // Boundary neutral.
+	bidiB                    // Paragraph separator.
+	bidiS                    // Segment separator.
+	bidiWS                   // Whitespace.
+	bidiON                   // Other neutral.
+	bidiLRE                  // Left-to-right embedding.
+	bidiLRO                  // Left-to-right override.
+	bidiRLE                  // Right-to-left embedding.
+	bidiRLO                  // Right-to-left override.
+	bidiPDF                  // Pop directional format.
+	bidiLRI                  // Left-to-right isolate.
+	bidiRLI                  // Right-to-left isolate.
+	bidiFSI                  // First strong isolate.
+	bidiPDI                  // Pop directional isolate.
+)
+
+// classOf returns the rune's Bidi_Class. It approximates the Unicode
+// Character Database by the rune's script and general category, with
+// explicit exceptions for digits, separators and terminators.
+func classOf(r rune) bidiClass {
+	switch r {
+	case 0x202a:
+		return bidiLRE
+	case 0x202b:
+		return bidiRLE
+	case 0x202c:
+		return bidiPDF
+	case 0x202d:
+		return bidiLRO
+	case 0x202e:
+		return bidiRLO
+	case 0x2066:
+		return bidiLRI
+	case 0x2067:
+		return bidiRLI
+	case 0x2068:
+		return bidiFSI
+	case 0x2069:
+		return bidiPDI
+	case 0x200e:
+		return bidiL
+	case 0x200f:
+		return bidiR
+	case 0x061c:
+		return bidiAL
+	case '\t', 0x0b, 0x1f:
+		return bidiS
+	case '\n', '\r', 0x1c, 0x1d, 0x1e, 0x85, 0x2029:
+		return bidiB
+	case ' ', 0x0c, 0x2028:
+		return bidiWS
+	case '+', '-', 0x207a, 0x207b, 0x208a, 0x208b, 0xfb29, 0xfe62, 0xfe63, 0xff0b, 0xff0d:
+		return bidiES
+	case ',', '.', '/', ':', 0xa0, 0x060c, 0x202f, 0x2044, 0xfe50, 0xfe52, 0xfe55,
+		0xff0c, 0xff0e, 0xff0f, 0xff1a:
+		return bidiCS
+	case '#', '$', '%', 0xa2, 0xa3, 0xa4, 0xa5, 0xb0, 0xb1, 0x0609, 0x060a, 0x066a,
+		0x212e, 0x2213, 0xfe5f, 0xfe69, 0xfe6a, 0xff03, 0xff04, 0xff05, 0xffe0, 0xffe1,
+		0xffe5, 0xffe6:
+		return bidiET
+	case 0xb2, 0xb3, 0xb9, 0x2070:
+		return bidiEN
+	}
+	switch {
+	case '0' <= r && r <= '9', 0x06f0 <= r && r <= 0x06f9, 0x2074 <= r && r <= 0x2079,
+		0x2080 <= r && r <= 0x2089, 0x2488 <= r && r <= 0x249b, 0xff10 <= r && r <= 0xff19:
+		return bidiEN
+	case 0x0600 <= r && r <= 0x0605, 0x0660 <= r && r <= 0x0669, r == 0x066b, r == 0x066c,
+		r == 0x06dd, r == 0x08e2:
+		return bidiAN
+	case 0x2030 <= r && r <= 0x2034, 0x20a0 <= r && r <= 0x20cf:
+		return bidiET
+	case unicode.In(r, unicode.Mn, unicode.Me):
+		return bidiNSM
+	case r < 0x20, 0x7f <= r && r <= 0x9f, unicode.Is(unicode.Cf, r):
+		return bidiBN
+	case unicode.Is(unicode.Zs, r):
+		return bidiWS
+	case 0x0590 <= r && r <= 0x05ff, 0x07c0 <= r && r <= 0x085f, 0xfb1d <= r && r <= 0xfb4f,
+		0x10800 <= r && r <= 0x10fff:
+		return bidiR
+	case 0x0600 <= r && r <= 0x07bf, 0x0860 <= r && r <= 0x08ff, 0xfb50 <= r && r <= 0xfdff,
+		0xfe70 <= r && r <= 0xfeff, 0x1ee00 <= r && r <= 0x1eeff:
+		return bidiAL
+	case unicode.In(r, unicode.P, unicode.S):
+		return bidiON
+	}
+	return bidiL
+}
+
+// isIsolate returns whether c is an isolate initiator or PDI.
+func isIsolate(c bidiClass) bool {
+	return c == bidiLRI || c == bidiRLI || c == bidiFSI || c == bidiPDI
+}
+
+// isRemoved returns whether rule X9 removes runes of class c.
+func isRemoved(c bidiClass) bool {
+	return c == bidiBN || (bidiLRE <= c && c <= bidiPDF)
+}
+
+// isControl returns whether r is an invisible bidi formatting rune, which
+// Layout does not draw.
+func isControl(r rune) bool {
+	c := classOf(r)
+	return bidiLRE <= c && c <= bidiPDI || r == 0x200e || r == 0x200f || r == 0x061c
+}
+
+// matchingPDIs returns, for each isolate initiator, the index of its
+// matching PDI, or len(classes) if it has none, and for each PDI, whether it
+// matches an initiator.
+func matchingPDIs(classes []bidiClass) (match []int, matched []bool) {
+	match = make([]int, len(classes))
+	matched = make([]bool, len(classes))
+	var stack []int
+	for i, c := range classes {
+		match[i] = len(classes)
+		switch c {
+		case bidiLRI, bidiRLI, bidiFSI:
+			stack = append(stack, i)
+		case bidiPDI:
+			if len(stack) > 0 {
+				match[stack[len(stack)-1]] = i
+				matched[i] = true
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	return match, matched
+}
+
+// firstStrong returns the level of the first strong rune of classes,
+// skipping isolated runes, or -1 if there is none. It implements rules P2
+// and P3.
+func firstStrong(classes []bidiClass) int {
+	depth := 0
+	for _, c := range classes {
+		switch c {
+		case bidiLRI, bidiRLI, bidiFSI:
+			depth++
+		case bidiPDI:
+			if depth > 0 {
+				depth--
+			}
+		case bidiL:
+			if depth == 0 {
+				return 0
+			}
+		case bidiR, bidiAL:
+			if depth == 0 {
+				return 1
+			}
+		}
+	}
+	return -1
+}
+
+// maxDepth is the maximum explicit embedding level.
+const maxDepth = 125
+
+// bidiLevels returns the resolved embedding level of each rune of a
+// paragraph with the given classes and paragraph level.
+func bidiLevels(classes []bidiClass, paraLevel int) []int {
+	n := len(classes)
+	match, matched := matchingPDIs(classes)
+	types := append([]bidiClass(nil), classes...)
+	levels := make([]int, n)
+
+	// Rules X1 to X8 resolve the explicit levels.
+	type status struct {
+		level    int
+		override bidiClass
+		isolate  bool
+	}
+	stack := []status{{paraLevel, bidiON, false}}
+	overflowIsolates, overflowEmbeddings, validIsolates := 0, 0, 0
+	for i, c := range classes {
+		top := stack[len(stack)-1]
+		switch c {
+		case bidiLRE, bidiRLE, bidiLRO, bidiRLO, bidiLRI, bidiRLI, bidiFSI:
+			isolate := c == bidiLRI || c == bidiRLI || c == bidiFSI
+			rtl := c == bidiRLE || c == bidiRLO || c == bidiRLI
+			if c == bidiFSI {
+				rtl = firstStrong(classes[i+1:match[i]]) == 1
+			}
+			levels[i] = top.level
+			if isolate && top.override != bidiON {
+				types[i] = top.override
+			}
+			level := (top.level + 2) &^ 1
+			if rtl {
+				level = (top.level + 1) | 1
+			}
+			if level <= maxDepth && overflowIsolates == 0 && overflowEmbeddings == 0 {
+				s := status{level, bidiON, isolate}
+				if c == bidiLRO {
+					s.override = bidiL
+				} else if c == bidiRLO {
+					s.override = bidiR
+				}
+				if isolate {
+					validIsolates++
+				}
+				stack = append(stack, s)
+			} else if isolate {
+				overflowIsolates++
+			} else if overflowIsolates == 0 {
+				overflowEmbeddings++
+			}
+		case bidiPDI:
+			if overflowIsolates > 0 {
+				overflowIsolates--
+			} else if validIsolates > 0 {
+				overflowEmbeddings = 0
+				for !stack[len(stack)-1].isolate {
+					stack = stack[:len(stack)-1]
+				}
+				stack = stack[:len(stack)-1]
+				validIsolates--
+			}
+			top = stack[len(stack)-1]
+			levels[i] = top.level
+			if top.override != bidiON {
+				types[i] = top.override
+			}
+		case bidiPDF:
+			levels[i] = top.level
+			if overflowIsolates > 0 {
+				// No-op.
+			} else if overflowEmbeddings > 0 {
+				overflowEmbeddings--
+			} else if !top.isolate && len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case bidiB:
+			levels[i] = paraLevel
+		default:
+			levels[i] = top.level
+			if top.override != bidiON && c != bidiBN {
+				types[i] = top.override
+			}
+		}
+	}
+
+	// Rule X10 groups the level runs, which rule X9 computes without the
+	// removed runes, into isolating run sequences.
+	var runs [][]int
+	for i := 0; i < n; i++ {
+		if isRemoved(classes[i]) {
+			continue
+		}
+		if k := len(runs) - 1; k >= 0 && levels[runs[k][len(runs[k])-1]] == levels[i] {
+			runs[k] = append(runs[k], i)
+			continue
+		}
+		runs = append(runs, []int{i})
+	}
+	runStartingAt := map[int][]int{}
+	for _, run := range runs {
+		runStartingAt[run[0]] = run
+	}
+	for _, run := range runs {
+		if classes[run[0]] == bidiPDI && matched[run[0]] {
+			continue
+		}
+		seq := run
+		for {
+			last := seq[len(seq)-1]
+			c := classes[last]
+			if c != bidiLRI && c != bidiRLI && c != bidiFSI || match[last] == n {
+				break
+			}
+			next, ok := runStartingAt[match[last]]
+			if !ok {
+				break
+			}
+			seq = append(seq[:len(seq):len(seq)], next...)
+		}
+		resolveSequence(seq, classes, types, levels, paraLevel, match)
+	}
+
+	// Removed runes take the level of the preceding rune.
+	for i, c := range classes {
+		if isRemoved(c) {
+			levels[i] = paraLevel
+			if i > 0 {
+				levels[i] = levels[i-1]
+			}
+		}
+	}
+
+	// Rule L1 resets separators, and any whitespace before them or at the
+	// end of the line, to the paragraph level.
+	trailing := true
+	for i := n - 1; i >= 0; i-- {
+		switch c := classes[i]; {
+		case c == bidiS || c == bidiB:
+			levels[i] = paraLevel
+			trailing = true
+		case c == bidiWS || isIsolate(c) || isRemoved(c):
+			if trailing {
+				levels[i] = paraLevel
+			}
+		default:
+			trailing = false
+		}
+	}
+	return levels
+}
+
+// resolveSequence resolves the weak and neutral types, and then the levels,
+// of the isolating run sequence seq, by rules W1 to I2.
+func resolveSequence(seq []int, classes, types []bidiClass, levels []int, paraLevel int, match []int) {
+	n := len(classes)
+	level := levels[seq[0]]
+	dirOf := func(l int) bidiClass {
+		if l&1 != 0 {
+			return bidiR
+		}
+		return bidiL
+	}
+	prevLevel := paraLevel
+	for i := seq[0] - 1; i >= 0; i-- {
+		if !isRemoved(classes[i]) {
+			prevLevel = levels[i]
+			break
+		}
+	}
+	nextLevel := paraLevel
+	if last := seq[len(seq)-1]; !(classes[last] == bidiLRI || classes[last] == bidiRLI || classes[last] == bidiFSI) {
+		for i := last + 1; i < n; i++ {
+			if !isRemoved(classes[i]) {
+				nextLevel = levels[i]
+				break
+			}
+		}
+	}
+	sos, eos := dirOf(maxInt(level, prevLevel)), dirOf(maxInt(level, nextLevel))
+
+	t := make([]bidiClass, len(seq))
+	for k, i := range seq {
+		t[k] = types[i]
+	}
+
+	// W1: non-spacing marks take the type of the preceding rune.
+	prev, prevIsolate := sos, false
+	for k, i := range seq {
+		if t[k] == bidiNSM {
+			t[k] = prev
+			if prevIsolate {
+				t[k] = bidiON
+			}
+		}
+		prev, prevIsolate = t[k], isIsolate(classes[i])
+	}
+	// W2 and W3: European numbers after Arabic letters are Arabic numbers,
+	// and Arabic letters are right-to-left.
+	strong := sos
+	for k := range t {
+		switch t[k] {
+		case bidiL, bidiR, bidiAL:
+			strong = t[k]
+		case bidiEN:
+			if strong == bidiAL {
+				t[k] = bidiAN
+			}
+		}
+	}
+	for k := range t {
+		if t[k] == bidiAL {
+			t[k] = bidiR
+		}
+	}
+	// W4: a single separator between two numbers of the same type takes
+	// their type.
+	for k := 1; k+1 < len(t); k++ {
+		if t[k-1] != t[k+1] {
+			continue
+		}
+		if t[k] == bidiES && t[k-1] == bidiEN || t[k] == bidiCS && (t[k-1] == bidiEN || t[k-1] == bidiAN) {
+			t[k] = t[k-1]
+		}
+	}
+	// W5: terminators next to European numbers are European numbers.
+	for k := 0; k < len(t); {
+		if t[k] != bidiET {
+			k++
+			continue
+		}
+		end := k
+		for end < len(t) && t[end] == bidiET {
+			end++
+		}
+		if k > 0 && t[k-1] == bidiEN || end < len(t) && t[end] == bidiEN {
+			for ; k < end; k++ {
+				t[k] = bidiEN
+			}
+		}
+		k = end
+	}
+	// W6: remaining separators and terminators are neutral.
+	for k := range t {
+		if t[k] == bidiES || t[k] == bidiET || t[k] == bidiCS {
+			t[k] = bidiON
+		}
+	}
+	// W7: European numbers after left-to-right text are left-to-right.
+	strong = sos
+	for k := range t {
+		switch t[k] {
+		case bidiL, bidiR:
+			strong = t[k]
+		case bidiEN:
+			if strong == bidiL {
+				t[k] = bidiL
+			}
+		}
+	}
+
+	// N1 and N2: neutrals between runes of the same direction take that
+	// direction, and other neutrals take the embedding direction.
+	neutral := func(c bidiClass) bool {
+		return c == bidiB || c == bidiS || c == bidiWS || c == bidiON || isIsolate(c)
+	}
+	strongDir := func(c bidiClass) bidiClass {
+		if c == bidiL {
+			return bidiL
+		}
+		return bidiR
+	}
+	for k := 0; k < len(t); {
+		if !neutral(t[k]) {
+			k++
+			continue
+		}
+		end := k
+		for end < len(t) && neutral(t[end]) {
+			end++
+		}
+		before, after := sos, eos
+		if k > 0 {
+			before = strongDir(t[k-1])
+		}
+		if end < len(t) {
+			after = strongDir(t[end])
+		}
+		d := dirOf(level)
+		if before == after {
+			d = before
+		}
+		for ; k < end; k++ {
+			t[k] = d
+		}
+	}
+
+	// I1 and I2 resolve the levels.
+	for k, i := range seq {
+		switch {
+		case level&1 == 0 && t[k] == bidiR:
+			levels[i]++
+		case level&1 == 0 && (t[k] == bidiAN || t[k] == bidiEN):
+			levels[i] += 2
+		case level&1 != 0 && (t[k] == bidiL || t[k] == bidiEN || t[k] == bidiAN):
+			levels[i]++
+		}
+	}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// mirrors maps each rune with the Bidi_Mirrored property to its mirror
+// image.
+var mirrors = func() map[rune]rune {
+	pairs := [][2]rune{
+		{'(', ')'}, {'<', '>'}, {'[', ']'}, {'{', '}'}, {0x00ab, 0x00bb},
+		{0x2039, 0x203a}, {0x2045, 0x2046}, {0x207d, 0x207e}, {0x208d, 0x208e},
+		{0x2208, 0x220b}, {0x2209, 0x220c}, {0x220a, 0x220d}, {0x2215, 0x29f5},
+		{0x223c, 0x223d}, {0x2243, 0x22cd}, {0x2252, 0x2253}, {0x2254, 0x2255},
+		{0x2264, 0x2265}, {0x2266, 0x2267}, {0x2268, 0x2269}, {0x226a, 0x226b},
+		{0x226e, 0x226f}, {0x2270, 0x2271}, {0x2272, 0x2273}, {0x2274, 0x2275},
+		{0x2276, 0x2277}, {0x2278, 0x2279}, {0x227a, 0x227b}, {0x227c, 0x227d},
+		{0x2282, 0x2283}, {0x2286, 0x2287}, {0x2288, 0x2289}, {0x228a, 0x228b},
+		{0x2308, 0x2309}, {0x230a, 0x230b}, {0x2329, 0x232a}, {0x27e6, 0x27e7},
+		{0x27e8, 0x27e9}, {0x27ea, 0x27eb}, {0x2983, 0x2984}, {0x2985, 0x2986},
+		{0x3008, 0x3009}, {0x300a, 0x300b}, {0x300c, 0x300d}, {0x300e, 0x300f},
+		{0x3010, 0x3011}, {0x3014, 0x3015}, {0x3016, 0x3017}, {0x3018, 0x3019},
+		{0x301a, 0x301b}, {0xff08, 0xff09}, {0xff1c, 0xff1e}, {0xff3b, 0xff3d},
+		{0xff5b, 0xff5d}, {0xff5f, 0xff60}, {0xff62, 0xff63},
+	}
+	m := make(map[rune]rune, 2*len(pairs))
+	for _, p := range pairs {
+		m[p[0]], m[p[1]] = p[1], p[0]
+	}
+	return m
+}()
+
+// mirrorRunes returns the text with each mirrored rune replaced by its
+// mirror image, if the font has a glyph for it. The text is copied only if
+// it changes.
+func mirrorRunes(f *truetype.Font, text []rune) []rune {
+	ret := text
+	for i, r := range text {
+		m, ok := mirrors[r]
+		if !ok || f.Index(m) == 0 {
+			continue
+		}
+		if &ret[0] == &text[0] {
+			ret = append([]rune(nil), text...)
+		}
+		ret[i] = m
+	}
+	return ret
+}
+
+// Layout shapes a paragraph of text that may mix left-to-right and
+// right-to-left scripts, such as Hebrew or Arabic with Latin and digits. It
+// applies the Unicode Bidirectional Algorithm to split the text into runs of
+// one direction and script, and returns the shaped runs in visual order,
+// from left to right. Each glyph's Cluster indexes the whole text.
+//
+// The paragraph direction is that of the text's first strongly directional
+// rune, or o.Direction if there is none. Invisible bidi formatting runes,
+// such as RLM and LRI, are not drawn. A nil o means to use the default
+// options.
+func Layout(f *truetype.Font, scale fixed.Int26_6, text []rune, o *Options) []Run {
+	if o == nil {
+		o = &Options{}
+	}
+	classes := make([]bidiClass, len(text))
+	for i, r := range text {
+		classes[i] = classOf(r)
+	}
+	paraLevel := firstStrong(classes)
+	if paraLevel < 0 {
+		paraLevel = int(o.Direction)
+	}
+	levels := bidiLevels(classes, paraLevel)
+
+	var (
+		runs      []Run
+		runLevels []int
+	)
+	for start := 0; start < len(text); {
+		if isControl(text[start]) {
+			start++
+			continue
+		}
+		s, end := scriptOf(text[start]), start+1
+		for ; end < len(text) && levels[end] == levels[start] && !isControl(text[end]); end++ {
+			if t := scriptOf(text[end]); t >= 0 {
+				if s >= 0 && s != t {
+					break
+				}
+				s = t
+			}
+		}
+
+		sh, script, _ := selectScript(f, text[start:end], o)
+		run := shape(f, scale, text[start:end], o, sh, script, Direction(levels[start]&1))
+		for i := range run.Glyphs {
+			run.Glyphs[i].Cluster += start
+		}
+		runs = append(runs, run)
+		runLevels = append(runLevels, levels[start])
+		start = end
+	}
+
+	// Rule L2 reverses each sequence of runs at or above each odd level,
+	// from the highest level down.
+	highest, lowestOdd := 0, maxDepth+2
+	for _, l := range runLevels {
+		highest = maxInt(highest, l)
+		if l&1 != 0 && l < lowestOdd {
+			lowestOdd = l
+		}
+	}
+	for l := highest; l >= lowestOdd; l-- {
+		for i := 0; i < len(runs); {
+			if runLevels[i] < l {
+				i++
+				continue
+			}
+			j := i
+			for j < len(runs) && runLevels[j] >= l {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				runs[a], runs[b] = runs[b], runs[a]
+				runLevels[a], runLevels[b] = runLevels[b], runLevels[a]
+			}
+			i = j
+		}
+	}
+	return runs
+}
+
+// scriptOf returns the index in scripts of the rune's script, or -1 for
+// runes that are Common, Inherited or in another script.
+func scriptOf(r rune) int {
+	if unicode.In(r, unicode.Common, unicode.Inherited) {
+		return -1
+	}
+	for i, s := range scripts {
+		if unicode.Is(s.table, r) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,123 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package shaping
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/image/math/fixed"
+)
+
+func TestBidiLevels(t *testing.T) {
+	testCases := []struct {
+		text string
+		want []int
+	}{
+		{"abc אבג 123", []int{0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2}},
+		{"אב cd", []int{1, 1, 1, 2, 2}},
+		// Digits after an Arabic letter are Arabic numbers.
+		{"ب 12", []int{1, 1, 2, 2}},
+		// A terminator before digits is part of the number.
+		{"א $12", []int{1, 1, 2, 2, 2}},
+		// A separator between digits is part of the number.
+		{"א 1.5", []int{1, 1, 2, 2, 2}},
+		// Trailing whitespace takes the paragraph level.
+		{"a אב ", []int{0, 0, 1, 1, 0}},
+		// An RLE embeds, and a PDF ends the embedding.
+		{"a‫bc‬d", []int{0, 0, 2, 2, 2, 0}},
+		// An RLO overrides the direction of the runes that it embeds.
+		{"a‮bc‬d", []int{0, 0, 1, 1, 1, 0}},
+		// Isolates do not affect the text around them.
+		{"a⁧b⁩c", []int{0, 0, 2, 0, 0}},
+		{"א⁨bc⁩", []int{1, 1, 2, 2, 1}},
+	}
+	for _, tc := range testCases {
+		runes := []rune(tc.text)
+		classes := make([]bidiClass, len(runes))
+		for i, r := range runes {
+			classes[i] = classOf(r)
+		}
+		level := firstStrong(classes)
+		got := bidiLevels(classes, level)
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("%+q: got %v, want %v", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestLayout(t *testing.T) {
+	f, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fupe := fixed.Int26_6(f.FUnitsPerEm())
+	testCases := []struct {
+		text       string
+		directions []Direction
+		clusters   []int
+	}{
+		{
+			"abc אבג 123",
+			[]Direction{LeftToRight, LeftToRight, RightToLeft},
+			[]int{0, 1, 2, 3, 8, 9, 10, 7, 6, 5, 4},
+		},
+		{
+			"אב cd",
+			[]Direction{LeftToRight, RightToLeft},
+			[]int{3, 4, 2, 1, 0},
+		},
+		{
+			// The RLM is not drawn.
+			"‏ab",
+			[]Direction{LeftToRight},
+			[]int{1, 2},
+		},
+	}
+	for _, tc := range testCases {
+		runs := Layout(f, fupe, []rune(tc.text), nil)
+		var (
+			directions []Direction
+			clusters   []int
+		)
+		for _, run := range runs {
+			directions = append(directions, run.Direction)
+			for _, g := range run.Glyphs {
+				clusters = append(clusters, g.Cluster)
+			}
+		}
+		if fmt.Sprint(directions) != fmt.Sprint(tc.directions) {
+			t.Errorf("%+q: directions: got %v, want %v", tc.text, directions, tc.directions)
+		}
+		if fmt.Sprint(clusters) != fmt.Sprint(tc.clusters) {
+			t.Errorf("%+q: clusters: got %v, want %v", tc.text, clusters, tc.clusters)
+		}
+	}
+
+	// Right-to-left parentheses are mirrored.
+	runs := Layout(f, fupe, []rune("א(ב)"), nil)
+	if len(runs) != 1 {
+		t.Fatalf("mirrored: got %d runs, want 1", len(runs))
+	}
+	var got []int
+	for _, g := range runs[0].Glyphs {
+		got = append(got, int(g.Index))
+	}
+	want := []int{int(f.Index('(')), 0, int(f.Index(')')), 0}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("mirrored: got %v, want %v", got, want)
+	}
+
+	// Text without strong runes takes the paragraph direction.
+	runs = Layout(f, fupe, []rune("(1)"), &Options{Direction: RightToLeft})
+	var dirs []Direction
+	for _, run := range runs {
+		dirs = append(dirs, run.Direction)
+	}
+	if want := []Direction{RightToLeft, LeftToRight, RightToLeft}; fmt.Sprint(dirs) != fmt.Sprint(want) {
+		t.Errorf("neutral: got directions %v, want %v", dirs, want)
+	}
+}
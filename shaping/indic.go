@@ -0,0 +1,289 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package shaping
+
+import (
+	"github.com/golang/freetype/truetype"
+)
+
+// indicCategory is the category of a rune in an Indic syllable.
+type indicCategory uint8
+
+const (
+	indicOther     indicCategory = iota
+	indicConsonant               // A consonant, such as KA.
+	indicVowel                   // An independent vowel.
+	indicMatra                   // A dependent vowel sign.
+	indicNukta                   // NUKTA.
+	indicHalant                  // VIRAMA, which kills a consonant's vowel.
+	indicModifier                // CANDRABINDU, ANUSVARA or VISARGA.
+	indicJoiner                  // ZWJ or ZWNJ.
+)
+
+// indicRa is the offset of RA in each Indic block.
+const indicRa = 0x30
+
+// category returns the category of the rune. The nine Indic blocks, from
+// Devanagari to Malayalam, share a layout that is derived from ISCII, so a
+// rune's category follows from its offset in the block.
+func category(r rune) indicCategory {
+	if r == 0x200c || r == 0x200d {
+		return indicJoiner
+	}
+	if r < 0x0900 || 0x0d7f < r {
+		return indicOther
+	}
+	switch off := r & 0x7f; {
+	case off <= 0x03:
+		return indicModifier
+	case off <= 0x14, 0x60 <= off && off <= 0x61:
+		return indicVowel
+	case off <= 0x39, 0x58 <= off && off <= 0x5f:
+		return indicConsonant
+	case off == 0x3c:
+		return indicNukta
+	case off == 0x3d:
+		return indicOther
+	case off == 0x4d:
+		return indicHalant
+	case off <= 0x4f, 0x55 <= off && off <= 0x57, 0x62 <= off && off <= 0x63:
+		return indicMatra
+	}
+	return indicOther
+}
+
+// preBaseMatras are the matras that are written before the consonant
+// cluster that they follow in logical order.
+var preBaseMatras = map[rune]bool{
+	0x093f: true, 0x094e: true, // Devanagari.
+	0x09bf: true, 0x09c7: true, 0x09c8: true, // Bengali.
+	0x0a3f: true,                             // Gurmukhi.
+	0x0abf: true,                             // Gujarati.
+	0x0b47: true,                             // Oriya.
+	0x0bc6: true, 0x0bc7: true, 0x0bc8: true, // Tamil.
+	0x0d46: true, 0x0d47: true, 0x0d48: true, // Malayalam.
+}
+
+// splitMatras are the two part matras, which are decomposed into a pre-base
+// and a post-base part.
+var splitMatras = map[rune][2]rune{
+	0x09cb: {0x09c7, 0x09be},
+	0x09cc: {0x09c7, 0x09d7},
+	0x0b48: {0x0b47, 0x0b56},
+	0x0b4b: {0x0b47, 0x0b3e},
+	0x0b4c: {0x0b47, 0x0b57},
+	0x0bca: {0x0bc6, 0x0bbe},
+	0x0bcb: {0x0bc7, 0x0bbe},
+	0x0bcc: {0x0bc6, 0x0bd7},
+	0x0d4a: {0x0d46, 0x0d3e},
+	0x0d4b: {0x0d47, 0x0d3e},
+	0x0d4c: {0x0d46, 0x0d57},
+}
+
+// syllable is a range of runes that is shaped as a unit.
+type syllable struct {
+	start, end int
+	// consonant is whether the syllable is a consonant syllable, which
+	// has a base consonant.
+	consonant bool
+}
+
+// syllables splits the runes into syllables.
+func syllables(runes []rune) []syllable {
+	var ret []syllable
+	cat := func(i int) indicCategory {
+		if i < len(runes) {
+			return category(runes[i])
+		}
+		return indicOther
+	}
+	for i := 0; i < len(runes); {
+		s := syllable{start: i}
+		switch cat(i) {
+		case indicConsonant:
+			// A consonant, and an optional nukta, followed by either a
+			// halant and the next consonant, or the end of the cluster.
+			s.consonant = true
+			for {
+				i++
+				if cat(i) == indicNukta {
+					i++
+				}
+				if cat(i) != indicHalant {
+					break
+				}
+				i++
+				if cat(i) == indicJoiner {
+					i++
+				}
+				if cat(i) != indicConsonant {
+					break
+				}
+			}
+		case indicVowel:
+			i++
+			if cat(i) == indicNukta {
+				i++
+			}
+		default:
+			i++
+			ret = append(ret, syllable{s.start, i, false})
+			continue
+		}
+		for c := cat(i); c == indicMatra || c == indicNukta || c == indicModifier; c = cat(i) {
+			i++
+		}
+		s.end = i
+		ret = append(ret, s)
+	}
+	return ret
+}
+
+// Masks of the Indic features that apply to some glyphs of a syllable.
+const (
+	maskRphf = 1 << iota
+	maskHalf
+)
+
+// reorder reorders a consonant syllable's runes, and their clusters, so that
+// pre-base matras precede the consonant cluster, and sets the masks of the
+// runes that form reph and half forms.
+func reorder(s syllable, runes []rune, clusters []int, masks []uint32) {
+	first := s.start
+	if s.end-s.start >= 3 && runes[s.start]&0x7f == indicRa &&
+		category(runes[s.start+1]) == indicHalant && category(runes[s.start+2]) == indicConsonant {
+		masks[s.start] |= maskRphf
+		masks[s.start+1] |= maskRphf
+		first = s.start + 2
+	}
+	base := -1
+	for i := first; i < s.end; i++ {
+		if category(runes[i]) == indicConsonant {
+			base = i
+		}
+	}
+	if base < 0 {
+		return
+	}
+	for i := first; i < base; i++ {
+		masks[i] |= maskHalf
+	}
+	for i := base + 1; i < s.end; i++ {
+		if !preBaseMatras[runes[i]] {
+			continue
+		}
+		r, c := runes[i], clusters[i]
+		copy(runes[first+1:i+1], runes[first:i])
+		copy(clusters[first+1:i+1], clusters[first:i])
+		copy(masks[first+1:i+1], masks[first:i])
+		runes[first], clusters[first], masks[first] = r, c, 0
+		first++
+	}
+}
+
+// indicShaper is the shaping model of the Indic scripts, which reorders each
+// syllable's runes from logical to visual order and forms conjuncts.
+type indicShaper struct{}
+
+func (indicShaper) shape(f *truetype.Font, text []rune, o *truetype.LayoutOptions) []truetype.GlyphInfo {
+	var (
+		runes    []rune
+		clusters []int
+	)
+	for i, r := range text {
+		if d, ok := splitMatras[r]; ok {
+			runes = append(runes, d[0], d[1])
+			clusters = append(clusters, i, i)
+			continue
+		}
+		runes = append(runes, r)
+		clusters = append(clusters, i)
+	}
+
+	masks := make([]uint32, len(runes))
+	// syllableOf maps each rune of the text to its syllable.
+	syllableOf := make([]int, len(text))
+	for n, s := range syllables(runes) {
+		for i := s.start; i < s.end; i++ {
+			syllableOf[clusters[i]] = n
+		}
+		if s.consonant {
+			reorder(s, runes, clusters, masks)
+		}
+	}
+	glyphs := make([]truetype.GlyphInfo, len(runes))
+	for i, r := range runes {
+		glyphs[i] = truetype.GlyphInfo{Index: f.Index(r), Cluster: clusters[i], Mask: masks[i]}
+	}
+
+	// The basic features form the reph, and are applied before moving it.
+	glyphs = f.SubstituteGlyphs(glyphs, &truetype.LayoutOptions{
+		Script:   o.Script,
+		Language: o.Language,
+		Features: []truetype.Feature{
+			{Tag: "locl", Value: 1},
+			{Tag: "ccmp", Value: 1},
+			{Tag: "nukt", Value: 1},
+			{Tag: "akhn", Value: 1},
+			{Tag: "rphf", Value: 1, Mask: maskRphf},
+		},
+		NoDefaultFeatures: true,
+	})
+	moveReph(f, glyphs, text, syllableOf)
+
+	features := []truetype.Feature{
+		{Tag: "rkrf", Value: 1},
+		{Tag: "pref", Value: 1},
+		{Tag: "blwf", Value: 1},
+		{Tag: "abvf", Value: 1},
+		{Tag: "half", Value: 1, Mask: maskHalf},
+		{Tag: "pstf", Value: 1},
+		{Tag: "vatu", Value: 1},
+		{Tag: "cjct", Value: 1},
+		{Tag: "pres", Value: 1},
+		{Tag: "abvs", Value: 1},
+		{Tag: "blws", Value: 1},
+		{Tag: "psts", Value: 1},
+		{Tag: "haln", Value: 1},
+		{Tag: "rlig", Value: 1},
+		{Tag: "calt", Value: 1},
+		{Tag: "clig", Value: 1},
+		{Tag: "liga", Value: 1},
+	}
+	return f.SubstituteGlyphs(glyphs, &truetype.LayoutOptions{
+		Script:            o.Script,
+		Language:          o.Language,
+		Features:          append(features, o.Features...),
+		NoDefaultFeatures: true,
+	})
+}
+
+// moveReph moves each reph glyph, which the rphf feature formed at the start
+// of a syllable, to the end of the syllable, before any modifiers.
+func moveReph(f *truetype.Font, glyphs []truetype.GlyphInfo, text []rune, syllableOf []int) {
+	for start := 0; start < len(glyphs); {
+		end := start + 1
+		n := syllableOf[glyphs[start].Cluster]
+		for end < len(glyphs) && syllableOf[glyphs[end].Cluster] == n {
+			end++
+		}
+		g := glyphs[start]
+		// If the font has no reph form of RA and HALANT, then they remain
+		// separate glyphs, both with the rphf mask.
+		formed := g.Mask&maskRphf != 0 &&
+			!(start+1 < end && glyphs[start+1].Mask&maskRphf != 0) &&
+			g.Index != f.Index(text[g.Cluster])
+		if formed {
+			to := end - 1
+			for to > start && category(text[glyphs[to].Cluster]) == indicModifier {
+				to--
+			}
+			copy(glyphs[start:to], glyphs[start+1:to+1])
+			glyphs[to] = g
+		}
+		start = end
+	}
+}
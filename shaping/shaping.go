@@ -0,0 +1,335 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+// Package shaping converts text to positioned glyphs, applying the
+// script-specific rules that complex scripts such as Arabic, Devanagari and
+// Thai need on top of a font's OpenType GSUB and GPOS tables.
+//
+// The shaper implements a subset of the OpenType script shaping models:
+// Arabic joining forms, Indic syllable reordering of pre-base matras and
+// reph, and Thai and Lao SARA AM decomposition. Other scripts use the
+// default shaping model.
+//
+// Bidirectional text is ordered by the Unicode Bidirectional Algorithm,
+// except for its paired bracket rule, N0.
+package shaping // import "github.com/golang/freetype/shaping"
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"unicode"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Direction is the direction of a run of text.
+type Direction int
+
+const (
+	LeftToRight Direction = iota
+	RightToLeft
+)
+
+// Glyph is a positioned glyph.
+type Glyph struct {
+	// Index is the glyph index in the font.
+	Index truetype.Index
+	// Cluster is the index, in the shaped text, of the first rune that the
+	// glyph was produced from.
+	Cluster int
+	// XAdvance is how far the glyph advances the pen.
+	XAdvance fixed.Int26_6
+	// XOffset and YOffset are the offset of the glyph's origin from the
+	// pen. As for image co-ordinates, the Y axis points down.
+	XOffset, YOffset fixed.Int26_6
+}
+
+// Run is a shaped run of text.
+type Run struct {
+	// Glyphs are the glyphs in visual order, from left to right, even for
+	// right-to-left text.
+	Glyphs []Glyph
+	// Direction is the run's direction.
+	Direction Direction
+	// Script is the OpenType script tag that the run was shaped with.
+	Script string
+	// Advance is the sum of the glyphs' advances.
+	Advance fixed.Int26_6
+}
+
+// Options are optional arguments to Shape. The zero value is valid.
+type Options struct {
+	// Script is the OpenType script tag, such as "arab" or "deva".
+	//
+	// An empty value means to detect the script from the text.
+	Script string
+
+	// Language is the OpenType language system tag, such as "URD" for Urdu.
+	//
+	// An empty value means to use the script's default language system.
+	Language string
+
+	// Features enable or disable features, in addition to those that the
+	// script's shaping model enables.
+	Features []truetype.Feature
+
+	// Direction is the paragraph direction that Layout uses for text
+	// without strongly directional runes, such as digits and punctuation.
+	// Shape ignores it, and uses the direction of the text's script.
+	Direction Direction
+
+	// Hinting selects how to quantize the glyph positions.
+	//
+	// A zero value means to use no hinting.
+	Hinting font.Hinting
+}
+
+// scripts map Unicode scripts to their OpenType script tags. Indic scripts
+// have a second, newer tag, which Shape prefers if the font supports it.
+var scripts = []struct {
+	table       *unicode.RangeTable
+	tag, newTag string
+	shaper      shaper
+	rightToLeft bool
+}{
+	{unicode.Arabic, "arab", "", arabicShaper{}, true},
+	{unicode.Hebrew, "hebr", "", defaultShaper{}, true},
+	{unicode.Devanagari, "deva", "dev2", indicShaper{}, false},
+	{unicode.Bengali, "beng", "bng2", indicShaper{}, false},
+	{unicode.Gurmukhi, "guru", "gur2", indicShaper{}, false},
+	{unicode.Gujarati, "gujr", "gjr2", indicShaper{}, false},
+	{unicode.Oriya, "orya", "ory2", indicShaper{}, false},
+	{unicode.Tamil, "taml", "tml2", indicShaper{}, false},
+	{unicode.Telugu, "telu", "tel2", indicShaper{}, false},
+	{unicode.Kannada, "knda", "knd2", indicShaper{}, false},
+	{unicode.Malayalam, "mlym", "mlm2", indicShaper{}, false},
+	{unicode.Thai, "thai", "", thaiShaper{}, false},
+	{unicode.Lao, "lao ", "", thaiShaper{}, false},
+	{unicode.Latin, "latn", "", defaultShaper{}, false},
+	{unicode.Greek, "grek", "", defaultShaper{}, false},
+	{unicode.Cyrillic, "cyrl", "", defaultShaper{}, false},
+}
+
+// shaper is a script's shaping model.
+type shaper interface {
+	// shape returns the substituted glyphs for the text.
+	shape(f *truetype.Font, text []rune, o *truetype.LayoutOptions) []truetype.GlyphInfo
+}
+
+// Shape shapes the text, using the font at the given scale, which is the
+// number of 26.6 fixed point units in 1 em. The text should be a single
+// run of one script and direction. A nil o means to use the default
+// options.
+func Shape(f *truetype.Font, scale fixed.Int26_6, text []rune, o *Options) Run {
+	if o == nil {
+		o = &Options{}
+	}
+	sh, script, dir := selectScript(f, text, o)
+	return shape(f, scale, text, o, sh, script, dir)
+}
+
+// selectScript returns the shaping model, OpenType script tag and direction
+// of the text's script, or of o.Script if set.
+func selectScript(f *truetype.Font, text []rune, o *Options) (sh shaper, script string, dir Direction) {
+	for _, s := range scripts {
+		if o.Script == "" && !detect(text, s.table) {
+			continue
+		}
+		if o.Script != "" && o.Script != s.tag && o.Script != s.newTag {
+			continue
+		}
+		script = o.Script
+		if script == "" {
+			script = s.tag
+			if s.newTag != "" && f.HasScript(s.newTag) {
+				script = s.newTag
+			}
+		}
+		if s.rightToLeft {
+			dir = RightToLeft
+		}
+		return s.shaper, script, dir
+	}
+	return defaultShaper{}, o.Script, LeftToRight
+}
+
+// shape shapes the text with the given shaping model, script and direction.
+// For right-to-left text, runes with mirrored forms, such as parentheses,
+// are replaced by their mirror images, if the font has them.
+func shape(f *truetype.Font, scale fixed.Int26_6, text []rune, o *Options,
+	sh shaper, script string, dir Direction) Run {
+
+	if dir == RightToLeft {
+		text = mirrorRunes(f, text)
+	}
+	run := Run{Direction: dir, Script: script}
+	glyphs := sh.shape(f, text, &truetype.LayoutOptions{
+		Script:   script,
+		Language: o.Language,
+		Features: o.Features,
+	})
+	run.Glyphs = position(f, scale, text, glyphs, dir, o.Hinting)
+	for _, g := range run.Glyphs {
+		run.Advance += g.XAdvance
+	}
+	return run
+}
+
+// detect returns whether the first rune of text that is in a specific
+// script, other than Common or Inherited, is in the given script.
+func detect(text []rune, script *unicode.RangeTable) bool {
+	for _, r := range text {
+		if unicode.In(r, unicode.Common, unicode.Inherited) {
+			continue
+		}
+		return unicode.Is(script, r)
+	}
+	return false
+}
+
+// glyphInfos maps each rune of the text to a glyph. A rune and a following
+// variation selector map to a single glyph.
+func glyphInfos(f *truetype.Font, text []rune) []truetype.GlyphInfo {
+	glyphs := make([]truetype.GlyphInfo, 0, len(text))
+	for i := 0; i < len(text); i++ {
+		g := truetype.GlyphInfo{Index: f.Index(text[i]), Cluster: i}
+		if i+1 < len(text) && truetype.IsVariationSelector(text[i+1]) {
+			g.Index = f.IndexVariation(text[i], text[i+1])
+			i++
+		}
+		glyphs = append(glyphs, g)
+	}
+	return glyphs
+}
+
+// defaultShaper is the shaping model of scripts without special rules.
+type defaultShaper struct{}
+
+func (defaultShaper) shape(f *truetype.Font, text []rune, o *truetype.LayoutOptions) []truetype.GlyphInfo {
+	return f.SubstituteGlyphs(glyphInfos(f, text), o)
+}
+
+// isMark returns whether the glyph, which was produced from the text's
+// cluster'th rune, is a mark. The font's GDEF glyph classes take precedence
+// over the rune's Unicode category.
+func isMark(f *truetype.Font, g truetype.GlyphInfo, text []rune) bool {
+	if c := f.GlyphClass(g.Index); c != 0 {
+		return c == 3
+	}
+	return unicode.In(text[g.Cluster], unicode.Mn, unicode.Me)
+}
+
+// position returns the positioned glyphs, in visual order, for the
+// substituted glyphs, which are in logical order.
+func position(f *truetype.Font, scale fixed.Int26_6, text []rune, glyphs []truetype.GlyphInfo,
+	dir Direction, hinting font.Hinting) []Glyph {
+
+	// Full hinting rounds horizontal positions, and both full and vertical
+	// hinting round vertical ones.
+	round := func(x fixed.Int26_6) fixed.Int26_6 {
+		if hinting == font.HintingFull {
+			return (x + 32) &^ 63
+		}
+		return x
+	}
+	roundY := func(y fixed.Int26_6) fixed.Int26_6 {
+		if hinting != font.HintingNone {
+			return (y + 32) &^ 63
+		}
+		return y
+	}
+
+	ret := make([]Glyph, len(glyphs))
+	// attach[i] is the glyph that the i'th glyph attaches to, or -1, and
+	// delta[i] is the offset from that glyph's origin, with Y up.
+	attach := make([]int, len(glyphs))
+	delta := make([]fixed.Point26_6, len(glyphs))
+	// base is the last glyph that is not a mark, and mark is the last mark
+	// that follows it.
+	base, mark := -1, -1
+	for i, g := range glyphs {
+		ret[i] = Glyph{Index: g.Index, Cluster: g.Cluster}
+		attach[i] = -1
+		if isMark(f, g, text) {
+			if mark >= 0 {
+				if d, ok := f.MarkAttachment(scale, glyphs[mark].Index, g.Index); ok {
+					attach[i], delta[i] = mark, d
+				}
+			}
+			if attach[i] < 0 && base >= 0 {
+				if d, ok := f.MarkAttachment(scale, glyphs[base].Index, g.Index); ok {
+					attach[i], delta[i] = base, d
+				}
+			}
+			if attach[i] < 0 && f.GlyphClass(g.Index) != 3 {
+				ret[i].XAdvance = round(f.HMetric(scale, g.Index).AdvanceWidth)
+			}
+			mark = i
+			continue
+		}
+		ret[i].XAdvance = round(f.HMetric(scale, g.Index).AdvanceWidth)
+		if base >= 0 {
+			ret[base].XAdvance += round(f.Kern(scale, glyphs[base].Index, g.Index))
+		}
+		base, mark = i, -1
+	}
+
+	// Lay out the glyphs in visual order, and then offset each attached mark
+	// from its base.
+	order := make([]int, len(glyphs))
+	for i := range order {
+		order[i] = i
+		if dir == RightToLeft {
+			order[i] = len(glyphs) - 1 - i
+		}
+	}
+	pen := make([]fixed.Int26_6, len(glyphs))
+	x := fixed.Int26_6(0)
+	for _, i := range order {
+		pen[i] = x
+		x += ret[i].XAdvance
+	}
+	for i, j := range attach {
+		if j < 0 {
+			continue
+		}
+		ret[i].XOffset = pen[j] + ret[j].XOffset + round(delta[i].X) - pen[i]
+		ret[i].YOffset = ret[j].YOffset - roundY(delta[i].Y)
+	}
+
+	visual := make([]Glyph, len(glyphs))
+	for k, i := range order {
+		visual[k] = ret[i]
+	}
+	return visual
+}
+
+// DrawRun draws the shaped run at the drawer's dot, which is the origin of
+// the run's leftmost glyph, and advances the dot by the run's advance. The
+// run should have been shaped at the same scale as the drawer's face, which
+// must implement truetype.IndexFace.
+func DrawRun(d *font.Drawer, run Run) error {
+	face, ok := d.Face.(truetype.IndexFace)
+	if !ok {
+		return errors.New("shaping: DrawRun called with a face that is not a truetype.IndexFace")
+	}
+	for _, g := range run.Glyphs {
+		dot := fixed.Point26_6{X: d.Dot.X + g.XOffset, Y: d.Dot.Y + g.YOffset}
+		dr, mask, maskp, _, ok := face.IndexGlyph(dot, g.Index)
+		if !ok {
+			// No-op.
+		} else if m, ok := mask.(*image.RGBA); ok {
+			// Color glyphs are drawn in their own colors.
+			draw.Draw(d.Dst, dr, m, maskp, draw.Over)
+		} else {
+			draw.DrawMask(d.Dst, dr, d.Src, image.Point{}, mask, maskp, draw.Over)
+		}
+		d.Dot.X += g.XAdvance
+	}
+	return nil
+}
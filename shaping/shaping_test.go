@@ -0,0 +1,148 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package shaping
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+func parseTestdataFont(name string) (*truetype.Font, error) {
+	b, err := ioutil.ReadFile(fmt.Sprintf("../testdata/%s.ttf", name))
+	if err != nil {
+		return nil, err
+	}
+	return truetype.Parse(b)
+}
+
+func TestJoiningForms(t *testing.T) {
+	testCases := []struct {
+		text string
+		want []uint32
+	}{
+		// BEH, YEH, TEH: initial, medial and final.
+		{"بيت", []uint32{maskInit, maskMedi, maskFina}},
+		// ALEF is right-joining, so the BEH after it is not medial.
+		{"باب", []uint32{maskInit, maskFina, maskIsol}},
+		// A transparent FATHA does not break the join.
+		{"بَب", []uint32{maskInit, 0, maskFina}},
+		// ZWNJ breaks it, and TATWEEL causes it.
+		{"ب‌ب", []uint32{maskIsol, 0, maskIsol}},
+		{"ـبـ", []uint32{0, maskMedi, 0}},
+		{"aبb", []uint32{0, maskIsol, 0}},
+	}
+	for _, tc := range testCases {
+		got := joiningForms([]rune(tc.text))
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("%+q: got %v, want %v", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestIndicReorder(t *testing.T) {
+	testCases := []struct {
+		text, want string
+		masks      []uint32
+	}{
+		// KA, I: the I matra moves before the KA.
+		{"कि", "िक", []uint32{0, 0}},
+		// RA, VIRAMA, KA, I: the RA and VIRAMA form the reph, and the I
+		// matra moves after them.
+		{"र्कि", "र्िक",
+			[]uint32{maskRphf, maskRphf, 0, 0}},
+		// SA, VIRAMA, TA, I: the SA and VIRAMA form a half form.
+		{"स्ति", "िस्त",
+			[]uint32{0, maskHalf, maskHalf, 0}},
+		// Bengali KA, E: the E matra moves before the KA.
+		{"কে", "েক", []uint32{0, 0}},
+	}
+	for _, tc := range testCases {
+		runes := []rune(tc.text)
+		clusters := make([]int, len(runes))
+		masks := make([]uint32, len(runes))
+		ss := syllables(runes)
+		if len(ss) != 1 || !ss[0].consonant {
+			t.Errorf("%+q: got syllables %v, want one consonant syllable", tc.text, ss)
+			continue
+		}
+		reorder(ss[0], runes, clusters, masks)
+		if got := string(runes); got != tc.want {
+			t.Errorf("%+q: got %+q, want %+q", tc.text, got, tc.want)
+		}
+		if fmt.Sprint(masks) != fmt.Sprint(tc.masks) {
+			t.Errorf("%+q: got masks %v, want %v", tc.text, masks, tc.masks)
+		}
+	}
+}
+
+func TestSyllables(t *testing.T) {
+	// KA, I; SA, VIRAMA, TA, ANUSVARA; AA; space; KA, VIRAMA.
+	text := []rune("किस्तंआ क्")
+	want := []syllable{{0, 2, true}, {2, 6, true}, {6, 7, false}, {7, 8, false}, {8, 10, true}}
+	if got := syllables(text); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestThaiSaraAm(t *testing.T) {
+	f, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// KO KAI, MAI EK, SARA AM: the NIKHAHIT precedes the MAI EK.
+	glyphs := thaiShaper{}.shape(f, []rune("ก่ำ"), &truetype.LayoutOptions{})
+	var clusters []int
+	for _, g := range glyphs {
+		clusters = append(clusters, g.Cluster)
+	}
+	if want := []int{0, 1, 1, 1}; fmt.Sprint(clusters) != fmt.Sprint(want) {
+		t.Errorf("clusters: got %v, want %v", clusters, want)
+	}
+}
+
+func TestShape(t *testing.T) {
+	f, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fupe := fixed.Int26_6(f.FUnitsPerEm())
+	A, V := f.Index('A'), f.Index('V')
+	run := Shape(f, fupe, []rune("AV"), nil)
+	if run.Direction != LeftToRight || run.Script != "latn" {
+		t.Errorf("got direction %v, script %q, want LeftToRight, \"latn\"", run.Direction, run.Script)
+	}
+	want := []Glyph{
+		{Index: A, Cluster: 0, XAdvance: f.HMetric(fupe, A).AdvanceWidth + f.Kern(fupe, A, V)},
+		{Index: V, Cluster: 1, XAdvance: f.HMetric(fupe, V).AdvanceWidth},
+	}
+	if fmt.Sprint(run.Glyphs) != fmt.Sprint(want) {
+		t.Errorf("glyphs: got %v, want %v", run.Glyphs, want)
+	}
+	if w := want[0].XAdvance + want[1].XAdvance; run.Advance != w {
+		t.Errorf("advance: got %v, want %v", run.Advance, w)
+	}
+
+	// Right-to-left runs are in visual order.
+	run = Shape(f, fupe, []rune("שלום"), &Options{Hinting: font.HintingFull})
+	if run.Direction != RightToLeft || run.Script != "hebr" {
+		t.Errorf("got direction %v, script %q, want RightToLeft, \"hebr\"", run.Direction, run.Script)
+	}
+	var clusters []int
+	for _, g := range run.Glyphs {
+		clusters = append(clusters, g.Cluster)
+		if g.XAdvance&63 != 0 {
+			t.Errorf("hinted advance %v is not a whole pixel", g.XAdvance)
+		}
+	}
+	if want := []int{3, 2, 1, 0}; fmt.Sprint(clusters) != fmt.Sprint(want) {
+		t.Errorf("clusters: got %v, want %v", clusters, want)
+	}
+}
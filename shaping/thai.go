@@ -0,0 +1,63 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package shaping
+
+import (
+	"github.com/golang/freetype/truetype"
+)
+
+// saraAm maps the Thai and Lao SARA AM to the NIKHAHIT and SARA AA that it
+// decomposes to.
+var saraAm = map[rune][2]rune{
+	0x0e33: {0x0e4d, 0x0e32},
+	0x0eb3: {0x0ecd, 0x0eb2},
+}
+
+// isAboveMark returns whether r is a Thai or Lao above-base mark, such as a
+// tone mark.
+func isAboveMark(r rune) bool {
+	switch {
+	case r == 0x0e31, 0x0e34 <= r && r <= 0x0e37, 0x0e47 <= r && r <= 0x0e4e:
+		return true
+	case r == 0x0eb1, 0x0eb4 <= r && r <= 0x0eb7, r == 0x0ebb, 0x0ec8 <= r && r <= 0x0ecd:
+		return true
+	}
+	return false
+}
+
+// thaiShaper is the shaping model of the Thai and Lao scripts, which
+// decomposes SARA AM so that its NIKHAHIT is positioned as a mark.
+type thaiShaper struct{}
+
+func (thaiShaper) shape(f *truetype.Font, text []rune, o *truetype.LayoutOptions) []truetype.GlyphInfo {
+	glyphs := make([]truetype.GlyphInfo, 0, len(text))
+	for i, r := range text {
+		d, ok := saraAm[r]
+		if !ok {
+			glyphs = append(glyphs, truetype.GlyphInfo{Index: f.Index(r), Cluster: i})
+			continue
+		}
+		// The NIKHAHIT precedes any above-base marks before the SARA AM,
+		// which merge into the SARA AM's cluster.
+		j := i
+		for j > 0 && isAboveMark(text[j-1]) {
+			j--
+		}
+		n := len(glyphs) - (i - j)
+		cluster := i
+		if n < len(glyphs) {
+			cluster = glyphs[n].Cluster
+		}
+		glyphs = append(glyphs, truetype.GlyphInfo{}, truetype.GlyphInfo{})
+		copy(glyphs[n+1:], glyphs[n:len(glyphs)-2])
+		glyphs[n] = truetype.GlyphInfo{Index: f.Index(d[0])}
+		glyphs[len(glyphs)-1] = truetype.GlyphInfo{Index: f.Index(d[1])}
+		for k := n; k < len(glyphs); k++ {
+			glyphs[k].Cluster = cluster
+		}
+	}
+	return f.SubstituteGlyphs(glyphs, o)
+}
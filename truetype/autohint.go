@@ -0,0 +1,454 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+// This file implements an automatic hinter, for fonts without TrueType
+// bytecode. It is a much simplified version of C Freetype's autofit module:
+// it finds a glyph's stems and the font's blue zones, snaps them to the pixel
+// grid, and interpolates the glyph's other points between them.
+
+import (
+	"sort"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// blueZone is a band of Y co-ordinates that many glyphs' edges are aligned
+// to, such as the baseline, x-height or cap-height. Flat edges, like the top
+// of an 'x', are at the reference position, and round edges, like the top of
+// an 'o', overshoot it slightly.
+type blueZone struct {
+	// ref and shoot are the scaled reference and overshoot positions.
+	ref, shoot fixed.Int26_6
+	// fitRef and fitShoot are those positions, fitted to the pixel grid.
+	fitRef, fitShoot fixed.Int26_6
+}
+
+// blueZoneChars are the characters whose flat and round extremes measure the
+// baseline, x-height and cap-height blue zones. top is whether the zone is at
+// the top, not the bottom, of those characters.
+var blueZoneChars = [...]struct {
+	flat, round rune
+	top         bool
+}{
+	{'H', 'O', false},
+	{'x', 'o', true},
+	{'H', 'O', true},
+}
+
+// segment is a run of consecutive points along a contour that is nearly
+// parallel to the axis that is not being hinted, such as the top of an 'x'
+// when hinting in Y.
+type segment struct {
+	// pos is the segment's position along the hinted axis, and min and max
+	// are its extent along the other axis.
+	pos, min, max fixed.Int26_6
+	// dir is the segment's direction along the other axis, +1 or -1.
+	dir int
+	// m0 and m1 are the range of the segment's point indexes in the
+	// autohinter's members.
+	m0, m1 int
+	// link is the index of the segment on the stem's other side, or -1.
+	link int
+	// edge is the index of the segment's edge.
+	edge int
+}
+
+// edge is a set of segments with almost the same position. Edges are fitted
+// to the pixel grid, and the segments' points move with them.
+type edge struct {
+	// pos is the edge's position along the hinted axis, and fit is that
+	// position after fitting.
+	pos, fit fixed.Int26_6
+	// length is the extent of the edge's longest segment.
+	length fixed.Int26_6
+	// link is the index of the edge on the stem's other side, or -1.
+	link int
+	// fitted is whether fit has been set.
+	fitted bool
+}
+
+// autohinter hints a GlyphBuf's points without the font's bytecode.
+type autohinter struct {
+	// font and scale are the font and scale that zones are for.
+	font  *Font
+	scale fixed.Int26_6
+	// unscaled are the font's blue zones in font units, and zones are those
+	// zones scaled and fitted to the pixel grid.
+	unscaled, zones []blueZone
+	// ref is a scratch GlyphBuf, for loading the characters that measure
+	// the blue zones.
+	ref *GlyphBuf
+
+	segments []segment
+	edges    []edge
+	// members are the point indexes of each segment, and pointEdge is the
+	// edge that each point is a member of, or -1.
+	members   []int
+	pointEdge []int
+}
+
+// init sets the autohinter's blue zones for the given font and scale.
+func (a *autohinter) init(f *Font, scale fixed.Int26_6) error {
+	if a.font != f {
+		a.font, a.scale = f, 0
+		if a.ref == nil {
+			a.ref = &GlyphBuf{}
+		}
+		a.unscaled = a.unscaled[:0]
+		for _, c := range blueZoneChars {
+			// Loading the glyphs at a scale of one em per font unit gives
+			// co-ordinates in font units.
+			flat, ok0, err := a.extreme(f, c.flat, c.top)
+			if err != nil {
+				return err
+			}
+			round, ok1, err := a.extreme(f, c.round, c.top)
+			if err != nil {
+				return err
+			}
+			if !ok0 {
+				continue
+			}
+			if !ok1 {
+				round = flat
+			}
+			a.unscaled = append(a.unscaled, blueZone{ref: flat, shoot: round})
+		}
+	}
+	if a.scale == scale {
+		return nil
+	}
+	a.scale = scale
+	a.zones = a.zones[:0]
+	for _, z := range a.unscaled {
+		z.ref = f.scale(scale * z.ref)
+		z.shoot = f.scale(scale * z.shoot)
+		z.fitRef = (z.ref + 32) &^ 63
+		// Overshoots of less than half a pixel are suppressed, so that, at
+		// small sizes, round and flat glyphs are the same height.
+		d := z.shoot - z.ref
+		switch {
+		case d >= 32:
+			z.fitShoot = z.fitRef + (d+32)&^63
+		case d <= -32:
+			z.fitShoot = z.fitRef - (-d+32)&^63
+		default:
+			z.fitShoot = z.fitRef
+		}
+		a.zones = append(a.zones, z)
+	}
+	return nil
+}
+
+// extreme returns the top or bottom, in font units, of the glyph for the
+// rune r. ok is false if the font has no such glyph, or it is empty.
+func (a *autohinter) extreme(f *Font, r rune, top bool) (y fixed.Int26_6, ok bool, err error) {
+	i := f.Index(r)
+	if i == 0 {
+		return 0, false, nil
+	}
+	if err := a.ref.Load(f, fixed.Int26_6(f.fUnitsPerEm), i, font.HintingNone); err != nil {
+		return 0, false, err
+	}
+	if len(a.ref.Points) == 0 {
+		return 0, false, nil
+	}
+	if top {
+		return a.ref.Bounds.Max.Y, true, nil
+	}
+	return a.ref.Bounds.Min.Y, true, nil
+}
+
+// hint hints g's unhinted points in Y, snapping edges in the blue zones to
+// them, and in X too if hintX is true.
+func (a *autohinter) hint(g *GlyphBuf, hintX bool) error {
+	if err := a.init(g.font, g.scale); err != nil {
+		return err
+	}
+	a.hintAxis(g, true)
+	if hintX {
+		a.hintAxis(g, false)
+	}
+	return nil
+}
+
+// coords returns p's co-ordinates along and across the hinted axis.
+func coords(p *Point, y bool) (u, v fixed.Int26_6) {
+	if y {
+		return p.Y, p.X
+	}
+	return p.X, p.Y
+}
+
+// hintAxis hints g's points in Y if y is true, and in X otherwise.
+func (a *autohinter) hintAxis(g *GlyphBuf, y bool) {
+	a.findSegments(g, y)
+	if len(a.segments) == 0 {
+		return
+	}
+	a.linkSegments(g.scale)
+	a.findEdges()
+	a.fitEdges(y)
+
+	// Move the segments' points with their edges, and interpolate the other
+	// points between the edges on either side.
+	for i := range g.Points {
+		p := &g.Points[i]
+		u, _ := coords(p, y)
+		if e := a.pointEdge[i]; e >= 0 {
+			u += a.edges[e].fit - a.edges[e].pos
+		} else {
+			u = a.interpolate(u)
+		}
+		if y {
+			p.Y = u
+		} else {
+			p.X = u
+		}
+	}
+}
+
+// findSegments sets a.segments to g's segments, sorted by position.
+func (a *autohinter) findSegments(g *GlyphBuf, y bool) {
+	a.segments = a.segments[:0]
+	a.members = a.members[:0]
+	a.pointEdge = a.pointEdge[:0]
+	for range g.Points {
+		a.pointEdge = append(a.pointEdge, -1)
+	}
+
+	// flat returns the direction of the line from point j to point k, if
+	// it is within about 4 degrees of the axis that is not being hinted, or
+	// 0 otherwise.
+	flat := func(j, k int) int {
+		u0, v0 := coords(&g.Points[j], y)
+		u1, v1 := coords(&g.Points[k], y)
+		du, dv := fabs(u1-u0), v1-v0
+		switch {
+		case dv > 0 && 14*du <= dv:
+			return +1
+		case dv < 0 && 14*du <= -dv:
+			return -1
+		}
+		return 0
+	}
+
+	e0 := 0
+	for _, e1 := range g.Ends {
+		n := e1 - e0
+		// Start from a line that is not flat, so that no segment wraps
+		// around the contour's start.
+		start := -1
+		for j := 0; j < n; j++ {
+			if flat(e0+j, e0+(j+1)%n) == 0 {
+				start = j
+				break
+			}
+		}
+		if n < 2 || start < 0 {
+			e0 = e1
+			continue
+		}
+		dir := 0
+		for k := 1; k <= n; k++ {
+			j := e0 + (start+k)%n
+			next := e0 + (start+k+1)%n
+			d := 0
+			if k < n {
+				d = flat(j, next)
+			}
+			if d != 0 && d == dir {
+				a.members = append(a.members, next)
+				continue
+			}
+			if dir != 0 {
+				a.closeSegment(g, y, dir)
+			}
+			dir = d
+			if d != 0 {
+				a.segments = append(a.segments, segment{m0: len(a.members), link: -1})
+				a.members = append(a.members, j, next)
+			}
+		}
+		e0 = e1
+	}
+	sort.Sort(bySegmentPos(a.segments))
+}
+
+// closeSegment sets the position and extent of the last segment, which has
+// the given direction.
+func (a *autohinter) closeSegment(g *GlyphBuf, y bool, dir int) {
+	s := &a.segments[len(a.segments)-1]
+	s.m1, s.dir = len(a.members), dir
+	umin, umax := fixed.Int26_6(0), fixed.Int26_6(0)
+	for k, j := range a.members[s.m0:s.m1] {
+		u, v := coords(&g.Points[j], y)
+		if k == 0 {
+			umin, umax, s.min, s.max = u, u, v, v
+			continue
+		}
+		if umin > u {
+			umin = u
+		} else if umax < u {
+			umax = u
+		}
+		if s.min > v {
+			s.min = v
+		} else if s.max < v {
+			s.max = v
+		}
+	}
+	s.pos = (umin + umax) / 2
+}
+
+type bySegmentPos []segment
+
+func (b bySegmentPos) Len() int           { return len(b) }
+func (b bySegmentPos) Less(i, j int) bool { return b[i].pos < b[j].pos }
+func (b bySegmentPos) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+// linkSegments links pairs of segments that are the two sides of a stem:
+// segments of opposite directions that overlap, and are each other's
+// nearest such segment, no more than a quarter of an em apart.
+func (a *autohinter) linkSegments(scale fixed.Int26_6) {
+	maxWidth := scale / 4
+	for i := range a.segments {
+		s, best, bestDist := &a.segments[i], -1, maxWidth+1
+		for j := range a.segments {
+			t := &a.segments[j]
+			if t.dir != -s.dir {
+				continue
+			}
+			lo, hi := s.min, s.max
+			if lo < t.min {
+				lo = t.min
+			}
+			if hi > t.max {
+				hi = t.max
+			}
+			if d := fabs(t.pos - s.pos); hi > lo && 0 < d && d < bestDist {
+				best, bestDist = j, d
+			}
+		}
+		s.link = best
+	}
+	for i := range a.segments {
+		if j := a.segments[i].link; j >= 0 && a.segments[j].link != i {
+			a.segments[i].link = -1
+		}
+	}
+}
+
+// findEdges groups the sorted segments into edges, each of whose segments
+// are within a quarter of a pixel of its first segment.
+func (a *autohinter) findEdges() {
+	a.edges = a.edges[:0]
+	first := fixed.Int26_6(0)
+	for i := range a.segments {
+		s := &a.segments[i]
+		if len(a.edges) == 0 || s.pos-first > 16 {
+			a.edges = append(a.edges, edge{link: -1})
+			first = s.pos
+		}
+		n := len(a.edges) - 1
+		s.edge = n
+		if e := &a.edges[n]; e.length < s.max-s.min || e.length == 0 {
+			e.pos, e.length = s.pos, s.max-s.min
+		}
+		for _, j := range a.members[s.m0:s.m1] {
+			a.pointEdge[j] = n
+		}
+	}
+	// An edge's link is the edge of the stem's other side, from its longest
+	// linked segment.
+	lengths := make([]fixed.Int26_6, len(a.edges))
+	for i := range a.segments {
+		s := &a.segments[i]
+		if s.link < 0 || s.max-s.min <= lengths[s.edge] {
+			continue
+		}
+		if l := a.segments[s.link].edge; l != s.edge {
+			a.edges[s.edge].link, lengths[s.edge] = l, s.max-s.min
+		}
+	}
+}
+
+// fitEdges fits the edges to the pixel grid: edges in the blue zones snap to
+// them, and then stems are rounded to a whole number of pixels, at least one,
+// and placed on the grid. Other edges are rounded to the nearest pixel.
+func (a *autohinter) fitEdges(y bool) {
+	if y {
+		for i := range a.edges {
+			e := &a.edges[i]
+			for _, z := range a.zones {
+				lo, hi := z.ref, z.shoot
+				if lo > hi {
+					lo, hi = hi, lo
+				}
+				if e.pos < lo-16 || hi+16 < e.pos {
+					continue
+				}
+				e.fit, e.fitted = z.fitRef, true
+				if fabs(e.pos-z.shoot) < fabs(e.pos-z.ref) {
+					e.fit = z.fitShoot
+				}
+				break
+			}
+		}
+	}
+
+	for i := range a.edges {
+		e := &a.edges[i]
+		if e.link <= i {
+			continue
+		}
+		l := &a.edges[e.link]
+		width := (l.pos - e.pos + 32) &^ 63
+		if width < 64 {
+			width = 64
+		}
+		switch {
+		case e.fitted && !l.fitted:
+			l.fit = e.fit + width
+		case !e.fitted && l.fitted:
+			e.fit = l.fit - width
+		case !e.fitted && !l.fitted:
+			center := (e.pos + l.pos) / 2
+			e.fit = (center - width/2 + 32) &^ 63
+			l.fit = e.fit + width
+		}
+		e.fitted, l.fitted = true, true
+	}
+
+	for i := range a.edges {
+		e := &a.edges[i]
+		if !e.fitted {
+			e.fit, e.fitted = (e.pos+32)&^63, true
+		}
+		// Fitting must not change the edges' order.
+		if i > 0 && e.fit < a.edges[i-1].fit {
+			e.fit = a.edges[i-1].fit
+		}
+	}
+}
+
+// interpolate returns the hinted position of a point that is not on an edge,
+// at the unhinted position u. It moves linearly between the edges on either
+// side, or with the nearest edge if it is beyond them.
+func (a *autohinter) interpolate(u fixed.Int26_6) fixed.Int26_6 {
+	i := sort.Search(len(a.edges), func(i int) bool { return a.edges[i].pos > u })
+	if i == 0 {
+		return u + a.edges[0].fit - a.edges[0].pos
+	}
+	lo := &a.edges[i-1]
+	if i == len(a.edges) || a.edges[i].pos == lo.pos {
+		return u + lo.fit - lo.pos
+	}
+	hi := &a.edges[i]
+	return lo.fit + fixed.Int26_6(mulDiv(int64(u-lo.pos), int64(hi.fit-lo.fit), int64(hi.pos-lo.pos)))
+}
@@ -0,0 +1,81 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+import (
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestAutoHinting(t *testing.T) {
+	f, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scale := fixed.I(11)
+	load := func(r rune, h font.Hinting) *GlyphBuf {
+		g := &GlyphBuf{AutoHinting: true}
+		if err := g.Load(f, scale, f.Index(r), h); err != nil {
+			t.Fatalf("%q: Load(%v): %v", r, h, err)
+		}
+		return g
+	}
+
+	// At 11 pixels per em, the x-height is 6 pixels and the cap-height is
+	// 8 pixels, and the overshoots of round glyphs are suppressed.
+	testCases := []struct {
+		r          rune
+		minY, maxY fixed.Int26_6
+	}{
+		{'x', 0, fixed.I(6)},
+		{'o', 0, fixed.I(6)},
+		{'H', 0, fixed.I(8)},
+		{'O', 0, fixed.I(8)},
+	}
+	for _, tc := range testCases {
+		g := load(tc.r, font.HintingFull)
+		minY, maxY := g.Points[0].Y, g.Points[0].Y
+		for _, p := range g.Points {
+			if minY > p.Y {
+				minY = p.Y
+			}
+			if maxY < p.Y {
+				maxY = p.Y
+			}
+		}
+		if minY != tc.minY || maxY != tc.maxY {
+			t.Errorf("%q: got Y range [%v, %v], want [%v, %v]", tc.r, minY, maxY, tc.minY, tc.maxY)
+		}
+		if g.AdvanceWidth&63 != 0 {
+			t.Errorf("%q: got advance width %v, want a whole number of pixels", tc.r, g.AdvanceWidth)
+		}
+	}
+
+	// H's stems, in both directions, are snapped to the grid.
+	for _, p := range load('H', font.HintingFull).Points {
+		if p.X&63 != 0 || p.Y&63 != 0 {
+			t.Errorf("'H': got point (%v, %v), want it on the pixel grid", p.X, p.Y)
+		}
+	}
+
+	// Vertical hinting leaves the X co-ordinates unhinted.
+	var none GlyphBuf
+	if err := none.Load(f, scale, f.Index('H'), font.HintingNone); err != nil {
+		t.Fatal(err)
+	}
+	vert := load('H', font.HintingVertical)
+	for j, p := range vert.Points {
+		if p.X != none.Points[j].X || p.Y&63 != 0 {
+			t.Errorf("'H': point %d: got (%v, %v), want X %v and Y on the pixel grid",
+				j, p.X, p.Y, none.Points[j].X)
+		}
+	}
+	if vert.AdvanceWidth != none.AdvanceWidth {
+		t.Errorf("'H': AdvanceWidth: got %v, want %v", vert.AdvanceWidth, none.AdvanceWidth)
+	}
+}
@@ -0,0 +1,535 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"sort"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// A BitmapStrike is a set of a font's embedded glyph bitmaps, drawn for one
+// size. The EBLC and EBDT tables hold monochrome and grayscale strikes, and
+// the CBLC and CBDT tables hold color strikes.
+type BitmapStrike struct {
+	// PPEMX and PPEMY are the horizontal and vertical pixels per em that the
+	// strike was drawn for.
+	PPEMX, PPEMY int
+	// BitDepth is the number of bits per pixel: 1, 2, 4 or 8 for grayscale
+	// strikes, and 32 for color strikes.
+	BitDepth int
+}
+
+// A Bitmap is an embedded glyph bitmap.
+type Bitmap struct {
+	// Image is the glyph image. It is an *image.Alpha for monochrome and
+	// grayscale bitmaps, and an *image.RGBA for color bitmaps.
+	Image image.Image
+	// Left and Top are the offsets, in pixels, from the glyph's origin to the
+	// image's left and top edges. Top is positive upwards.
+	Left, Top int
+	// Advance is the glyph's advance width, in pixels.
+	Advance int
+	// PPEM is the vertical pixels per em of the strike that holds the
+	// bitmap, which Left, Top and Advance are measured at.
+	PPEM int
+}
+
+// An SbixStrike is a set of a font's color glyph images, drawn for one size.
+// The sbix table holds them.
+type SbixStrike struct {
+	// PPEM is the pixels per em that the strike was drawn for, and PPI is
+	// the pixels per inch that it was designed to be viewed at.
+	PPEM, PPI int
+}
+
+// sbixStrike is an SbixStrike and the offset of its data in the sbix table.
+type sbixStrike struct {
+	SbixStrike
+	offset int
+}
+
+// bitmapStrike is a BitmapStrike and where to find its bitmaps.
+type bitmapStrike struct {
+	BitmapStrike
+	// loc and dat are the EBLC and EBDT, or CBLC and CBDT, tables.
+	loc, dat []byte
+	// array is the offset in loc of the strike's array of index subtables,
+	// and n is the number of index subtables.
+	array, n int
+}
+
+// maxBitmapComponentDepth is the maximum nesting of composite bitmaps.
+const maxBitmapComponentDepth = 8
+
+// parseBitmaps finds the EBLC and CBLC tables' strikes. The glyphs' outlines
+// can be drawn instead of their bitmaps, so a malformed table or strike is
+// ignored rather than failing the whole font.
+func (f *Font) parseBitmaps() {
+	f.bitmapStrikes = nil
+	for _, t := range [...]struct{ loc, dat []byte }{
+		{f.eblc, f.ebdt},
+		{f.cblc, f.cbdt},
+	} {
+		if len(t.loc) < 8 || len(t.dat) == 0 {
+			continue
+		}
+		// Version 2 is EBLC, and version 3 is CBLC. We ignore any other
+		// versions, and draw the glyphs' outlines instead.
+		if v := u16(t.loc, 0); v != 2 && v != 3 {
+			continue
+		}
+		n := int(u32(t.loc, 4))
+		if n > (len(t.loc)-8)/48 {
+			continue
+		}
+		for i := 0; i < n; i++ {
+			x := 8 + 48*i
+			s := bitmapStrike{
+				BitmapStrike: BitmapStrike{
+					PPEMX:    int(t.loc[x+44]),
+					PPEMY:    int(t.loc[x+45]),
+					BitDepth: int(t.loc[x+46]),
+				},
+				loc:   t.loc,
+				dat:   t.dat,
+				array: int(u32(t.loc, x)),
+				n:     int(u32(t.loc, x+8)),
+			}
+			if s.array > len(t.loc) || s.n > (len(t.loc)-s.array)/8 {
+				continue
+			}
+			f.bitmapStrikes = append(f.bitmapStrikes, s)
+		}
+	}
+}
+
+// BitmapStrikes returns the font's embedded bitmap strikes.
+func (f *Font) BitmapStrikes() []BitmapStrike {
+	ret := make([]BitmapStrike, len(f.bitmapStrikes))
+	for i := range f.bitmapStrikes {
+		ret[i] = f.bitmapStrikes[i].BitmapStrike
+	}
+	return ret
+}
+
+// GlyphBitmap returns the glyph's embedded bitmap from the first strike with
+// the given vertical pixels per em that has one. It returns a nil Bitmap and
+// a nil error if no such strike has a bitmap for the glyph.
+func (f *Font) GlyphBitmap(ppem int, i Index) (*Bitmap, error) {
+	for k := range f.bitmapStrikes {
+		s := &f.bitmapStrikes[k]
+		if s.PPEMY != ppem {
+			continue
+		}
+		if b, err := s.bitmap(i, 0); b != nil || err != nil {
+			return b, err
+		}
+	}
+	return nil, nil
+}
+
+// hasBitmapStrike returns whether the font has a strike with the given
+// vertical pixels per em.
+func (f *Font) hasBitmapStrike(ppem int) bool {
+	for i := range f.bitmapStrikes {
+		if f.bitmapStrikes[i].PPEMY == ppem {
+			return true
+		}
+	}
+	return false
+}
+
+// glyph returns the image format and data of the glyph's bitmap, and the
+// metrics that the index subtable holds for formats that do not hold their
+// own. It returns a zero format if the strike has no bitmap for the glyph.
+func (s *bitmapStrike) glyph(i Index) (format int, metrics, data []byte, err error) {
+	loc := s.loc
+	for j := 0; j < s.n; j++ {
+		x := s.array + 8*j
+		first, last := Index(u16(loc, x)), Index(u16(loc, x+2))
+		if i < first || last < i {
+			continue
+		}
+		y := s.array + int(u32(loc, x+4))
+		if y < s.array || len(loc) < y+8 {
+			return 0, nil, nil, FormatError("bad bitmap index subtable")
+		}
+		indexFormat := u16(loc, y)
+		format, offset := int(u16(loc, y+2)), int(u32(loc, y+4))
+		y += 8
+		// lo and hi are the offsets of the glyph's data, relative to offset.
+		var lo, hi int
+		g := int(i - first)
+		// search returns the position of i in the n glyph IDs at loc[y:],
+		// which are stride bytes apart, or -1.
+		search := func(y, n, stride int) int {
+			k := sort.Search(n, func(k int) bool { return Index(u16(loc, y+stride*k)) >= i })
+			if k == n || Index(u16(loc, y+stride*k)) != i {
+				return -1
+			}
+			return k
+		}
+		switch indexFormat {
+		case 1:
+			// Glyphs first to last have 32-bit offsets.
+			if len(loc) < y+4*g+8 {
+				return 0, nil, nil, FormatError("bad bitmap index subtable")
+			}
+			lo, hi = int(u32(loc, y+4*g)), int(u32(loc, y+4*g+4))
+		case 2:
+			// Glyphs first to last have the same size and metrics.
+			if len(loc) < y+12 {
+				return 0, nil, nil, FormatError("bad bitmap index subtable")
+			}
+			size := int(u32(loc, y))
+			metrics = loc[y+4 : y+12]
+			lo, hi = size*g, size*(g+1)
+		case 3:
+			// Glyphs first to last have 16-bit offsets.
+			if len(loc) < y+2*g+4 {
+				return 0, nil, nil, FormatError("bad bitmap index subtable")
+			}
+			lo, hi = int(u16(loc, y+2*g)), int(u16(loc, y+2*g+2))
+		case 4:
+			// Sparse glyphs have 16-bit offsets.
+			if len(loc) < y+4 {
+				return 0, nil, nil, FormatError("bad bitmap index subtable")
+			}
+			n := int(u32(loc, y))
+			y += 4
+			if n >= (len(loc)-y)/4 {
+				return 0, nil, nil, FormatError("bad bitmap index subtable")
+			}
+			k := search(y, n, 4)
+			if k < 0 {
+				return 0, nil, nil, nil
+			}
+			lo, hi = int(u16(loc, y+4*k+2)), int(u16(loc, y+4*k+6))
+		case 5:
+			// Sparse glyphs have the same size and metrics.
+			if len(loc) < y+16 {
+				return 0, nil, nil, FormatError("bad bitmap index subtable")
+			}
+			size := int(u32(loc, y))
+			metrics = loc[y+4 : y+12]
+			n := int(u32(loc, y+12))
+			y += 16
+			if n > (len(loc)-y)/2 {
+				return 0, nil, nil, FormatError("bad bitmap index subtable")
+			}
+			k := search(y, n, 2)
+			if k < 0 {
+				return 0, nil, nil, nil
+			}
+			lo, hi = size*k, size*(k+1)
+		default:
+			return 0, nil, nil, UnsupportedError(fmt.Sprintf("bitmap index format: %d", indexFormat))
+		}
+		// Equal offsets mean that the glyph has no bitmap.
+		if lo >= hi {
+			return 0, nil, nil, nil
+		}
+		if lo < 0 || offset < 0 || len(s.dat) < offset+hi {
+			return 0, nil, nil, FormatError("bitmap data too short")
+		}
+		return format, metrics, s.dat[offset+lo : offset+hi], nil
+	}
+	return 0, nil, nil, nil
+}
+
+// bitmap returns the glyph's bitmap, or nil if the strike has no bitmap for
+// the glyph. depth is the nesting of composite bitmaps.
+func (s *bitmapStrike) bitmap(i Index, depth int) (*Bitmap, error) {
+	if depth > maxBitmapComponentDepth {
+		return nil, FormatError("bitmap components nested too deeply")
+	}
+	format, metrics, data, err := s.glyph(i)
+	if err != nil || format == 0 {
+		return nil, err
+	}
+	// The small metrics, of 5 bytes, and the big metrics, of 8 bytes, both
+	// start with the height, width, horizontal bearings and advance.
+	n := 0
+	switch format {
+	case 1, 2, 17:
+		n = 5
+	case 8:
+		// The small metrics are followed by a pad byte.
+		n = 6
+	case 6, 7, 9, 18:
+		n = 8
+	case 5, 19:
+		// The index subtable holds the metrics.
+	default:
+		return nil, UnsupportedError(fmt.Sprintf("bitmap image format: %d", format))
+	}
+	if n != 0 {
+		if len(data) < n {
+			return nil, FormatError("bitmap data too short")
+		}
+		metrics, data = data[:n], data[n:]
+	}
+	if len(metrics) < 5 {
+		return nil, FormatError("bitmap metrics too short")
+	}
+	h, w := int(metrics[0]), int(metrics[1])
+	b := &Bitmap{
+		Left:    int(int8(metrics[2])),
+		Top:     int(int8(metrics[3])),
+		Advance: int(metrics[4]),
+		PPEM:    s.PPEMY,
+	}
+	switch format {
+	case 1, 6:
+		b.Image, err = decodeBitmap(data, w, h, s.BitDepth, true)
+	case 2, 5, 7:
+		b.Image, err = decodeBitmap(data, w, h, s.BitDepth, false)
+	case 8, 9:
+		b.Image, err = s.composite(data, w, h, depth)
+	case 17, 18, 19:
+		if len(data) < 4 || int(u32(data, 0)) > len(data)-4 {
+			return nil, FormatError("bitmap data too short")
+		}
+		b.Image, err = decodeImage(data[4:4+int(u32(data, 0))], "png ")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// composite returns the image of a composite bitmap, whose components are
+// other glyphs' bitmaps.
+func (s *bitmapStrike) composite(data []byte, w, h, depth int) (image.Image, error) {
+	if len(data) < 2 {
+		return nil, FormatError("bitmap data too short")
+	}
+	n := int(u16(data, 0))
+	if len(data) < 2+4*n {
+		return nil, FormatError("bitmap data too short")
+	}
+	m := image.NewAlpha(image.Rect(0, 0, w, h))
+	for j := 0; j < n; j++ {
+		x := 2 + 4*j
+		c, err := s.bitmap(Index(u16(data, x)), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if c == nil {
+			continue
+		}
+		// The offsets are of the component's top-left corner.
+		cb := c.Image.Bounds()
+		r := cb.Sub(cb.Min).Add(image.Point{int(int8(data[x+2])), int(int8(data[x+3]))})
+		draw.DrawMask(m, r, image.Opaque, image.Point{}, c.Image, cb.Min, draw.Over)
+	}
+	return m, nil
+}
+
+// decodeBitmap decodes a monochrome or grayscale bitmap of the given width,
+// height and bits per pixel. If byteAligned is true, then each row starts on
+// a byte boundary, otherwise the rows are packed together.
+func decodeBitmap(data []byte, w, h, bitDepth int, byteAligned bool) (*image.Alpha, error) {
+	switch bitDepth {
+	case 1, 2, 4, 8:
+	default:
+		return nil, UnsupportedError(fmt.Sprintf("bitmap bit depth: %d", bitDepth))
+	}
+	// stride is the number of bits per row.
+	stride := w * bitDepth
+	if byteAligned {
+		stride = (stride + 7) &^ 7
+	}
+	if len(data) < (stride*h+7)/8 {
+		return nil, FormatError("bitmap data too short")
+	}
+	m := image.NewAlpha(image.Rect(0, 0, w, h))
+	full := 1<<uint(bitDepth) - 1
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			bit := y*stride + x*bitDepth
+			v := int(data[bit/8]) >> uint(8-bitDepth-bit%8) & full
+			m.Pix[y*m.Stride+x] = uint8(v * 0xff / full)
+		}
+	}
+	return m, nil
+}
+
+// decodeImage decodes a PNG or JPEG image, as given by its sbix graphic type.
+func decodeImage(data []byte, graphicType string) (*image.RGBA, error) {
+	var (
+		src image.Image
+		err error
+	)
+	switch graphicType {
+	case "png ":
+		src, err = png.Decode(bytes.NewReader(data))
+	case "jpg ":
+		src, err = jpeg.Decode(bytes.NewReader(data))
+	default:
+		return nil, UnsupportedError("bitmap graphic type: " + graphicType)
+	}
+	if err != nil {
+		return nil, FormatError("bad bitmap image: " + err.Error())
+	}
+	sb := src.Bounds()
+	m := image.NewRGBA(image.Rect(0, 0, sb.Dx(), sb.Dy()))
+	draw.Draw(m, m.Bounds(), src, sb.Min, draw.Src)
+	return m, nil
+}
+
+// parseSbix finds the sbix table's strikes. As for parseBitmaps, a malformed
+// table or strike is ignored rather than failing the whole font.
+func (f *Font) parseSbix() {
+	f.sbixStrikes = nil
+	if len(f.sbix) < 8 {
+		return
+	}
+	n := int(u32(f.sbix, 4))
+	if n > (len(f.sbix)-8)/4 {
+		return
+	}
+	for i := 0; i < n; i++ {
+		offset := int(u32(f.sbix, 8+4*i))
+		if offset < 0 || len(f.sbix) < offset+8+4*f.nGlyph {
+			continue
+		}
+		f.sbixStrikes = append(f.sbixStrikes, sbixStrike{
+			SbixStrike: SbixStrike{
+				PPEM: int(u16(f.sbix, offset)),
+				PPI:  int(u16(f.sbix, offset+2)),
+			},
+			offset: offset,
+		})
+	}
+}
+
+// SbixStrikes returns the font's sbix strikes.
+func (f *Font) SbixStrikes() []SbixStrike {
+	ret := make([]SbixStrike, len(f.sbixStrikes))
+	for i := range f.sbixStrikes {
+		ret[i] = f.sbixStrikes[i].SbixStrike
+	}
+	return ret
+}
+
+// SbixBitmap returns the glyph's color image from the sbix strike whose
+// pixels per em are nearest to ppem, preferring the larger strike of two
+// that are equally near. The image is not scaled: the Bitmap's PPEM is that
+// of the strike. It returns a nil Bitmap and a nil error if that strike has
+// no image for the glyph.
+func (f *Font) SbixBitmap(ppem int, i Index) (*Bitmap, error) {
+	var s *sbixStrike
+	for k := range f.sbixStrikes {
+		t := &f.sbixStrikes[k]
+		if t.PPEM == 0 {
+			continue
+		}
+		if s == nil {
+			s = t
+			continue
+		}
+		d, e := abs(t.PPEM-ppem), abs(s.PPEM-ppem)
+		if d < e || d == e && t.PPEM > s.PPEM {
+			s = t
+		}
+	}
+	if s == nil {
+		return nil, nil
+	}
+	m, left, bottom, err := f.sbixImage(s, i, false)
+	if m == nil || err != nil {
+		return nil, err
+	}
+	return &Bitmap{
+		Image:   m,
+		Left:    left,
+		Top:     bottom + m.Bounds().Dy(),
+		Advance: int((f.HMetric(fixed.I(s.PPEM), i).AdvanceWidth + 32) >> 6),
+		PPEM:    s.PPEM,
+	}, nil
+}
+
+// sbixImage returns the glyph's image in the strike, and the offsets of its
+// left and bottom edges from the glyph's origin. dupe is whether the glyph
+// is the target of another glyph's "dupe" record, which may not be another
+// such record.
+func (f *Font) sbixImage(s *sbixStrike, i Index, dupe bool) (m *image.RGBA, left, bottom int, err error) {
+	if int(i) >= f.nGlyph {
+		return nil, 0, 0, nil
+	}
+	x := s.offset + 4 + 4*int(i)
+	lo, hi := s.offset+int(u32(f.sbix, x)), s.offset+int(u32(f.sbix, x+4))
+	if lo == hi {
+		return nil, 0, 0, nil
+	}
+	if hi < lo || len(f.sbix) < hi || hi-lo < 8 {
+		return nil, 0, 0, FormatError("bad sbix glyph data")
+	}
+	data := f.sbix[lo:hi]
+	switch graphicType := string(data[4:8]); graphicType {
+	case "dupe":
+		if dupe || len(data) < 10 {
+			return nil, 0, 0, FormatError("bad sbix dupe glyph")
+		}
+		return f.sbixImage(s, Index(u16(data, 8)), true)
+	default:
+		m, err = decodeImage(data[8:], graphicType)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		return m, int(int16(u16(data, 0))), int(int16(u16(data, 2))), nil
+	}
+}
+
+// scaleImage returns src resampled to w by h pixels. Each destination
+// pixel is the average of the source pixels, or parts of pixels, that it
+// covers.
+func scaleImage(src *image.RGBA, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	sb := src.Bounds()
+	sx := float64(sb.Dx()) / float64(w)
+	sy := float64(sb.Dy()) / float64(h)
+	for y := 0; y < h; y++ {
+		y0, y1 := float64(y)*sy, float64(y+1)*sy
+		for x := 0; x < w; x++ {
+			x0, x1 := float64(x)*sx, float64(x+1)*sx
+			// The RGBA pixels are alpha-premultiplied, so averaging each
+			// channel separately is correct.
+			var c [4]float64
+			for j := int(y0); float64(j) < y1 && j < sb.Dy(); j++ {
+				wy := math.Min(y1, float64(j+1)) - math.Max(y0, float64(j))
+				for i := int(x0); float64(i) < x1 && i < sb.Dx(); i++ {
+					wx := math.Min(x1, float64(i+1)) - math.Max(x0, float64(i))
+					p := src.PixOffset(sb.Min.X+i, sb.Min.Y+j)
+					for k := range c {
+						c[k] += wx * wy * float64(src.Pix[p+k])
+					}
+				}
+			}
+			d := dst.PixOffset(x, y)
+			for k := range c {
+				dst.Pix[d+k] = uint8(math.Min(0xff, c[k]/(sx*sy)+0.5))
+			}
+		}
+	}
+	return dst
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,309 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// bitmapSize returns an EBLC or CBLC BitmapSize record.
+func bitmapSize(array, n int, first, last Index, ppem, bitDepth int) []byte {
+	b := be16(array>>16, array, 0, 0, n>>16, n, 0, 0)
+	b = append(b, make([]byte, 24)...)
+	b = append(b, be16(int(first), int(last))...)
+	return append(b, byte(ppem), byte(ppem), byte(bitDepth), 1)
+}
+
+// makeTestBitmapFont returns luxisr.ttf plus a 12 ppem monochrome strike, in
+// which glyphs a and b have the same 3x2 bitmap, and a 20 ppem color strike,
+// in which glyph a has a 2x2 red PNG bitmap.
+func makeTestBitmapFont(t *testing.T, a, b Index) *Font {
+	// Glyph a uses index format 1 and image format 1, which is byte-aligned
+	// with small metrics. Glyph b uses index format 5, with big metrics, and
+	// image format 5, which is bit-aligned.
+	eblc := cat(
+		be16(2, 0, 0, 1),
+		bitmapSize(56, 2, a, b, 12, 1),
+		be16(int(a), int(a), 0, 16),
+		be16(int(b), int(b), 0, 32),
+		be16(1, 1, 0, 4), be16(0, 0, 0, 7),
+		be16(5, 5, 0, 11, 0, 1), []byte{2, 3, 1, 2, 5, 0, 0, 0}, be16(0, 1, int(b)),
+	)
+	ebdt := cat(
+		be16(2, 0),
+		[]byte{2, 3, 1, 2, 5, 0xa0, 0x40},
+		[]byte{0xa8},
+	)
+
+	red := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	for i := range red.Pix {
+		red.Pix[i] = []byte{0xff, 0x00, 0x00, 0xff}[i%4]
+	}
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, red); err != nil {
+		t.Fatal(err)
+	}
+	cblc := cat(
+		be16(3, 0, 0, 1),
+		bitmapSize(56, 1, a, a, 20, 32),
+		be16(int(a), int(a), 0, 8),
+		be16(1, 17, 0, 4), be16(0, 0, 0, 9+buf.Len()),
+	)
+	cbdt := cat(
+		be16(3, 0),
+		[]byte{2, 2, 0, 2, 2},
+		be16(buf.Len()>>16, buf.Len()),
+		buf.Bytes(),
+	)
+
+	return makeTestFont(t, map[string][]byte{
+		"CBDT": cbdt, "CBLC": cblc, "EBDT": ebdt, "EBLC": eblc,
+	})
+}
+
+func TestBitmap(t *testing.T) {
+	g, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	A, B, C := g.Index('A'), g.Index('B'), g.Index('C')
+	if A >= B {
+		t.Fatalf("glyph indexes: A=%d, B=%d are not increasing", A, B)
+	}
+	f := makeTestBitmapFont(t, A, B)
+
+	want := []BitmapStrike{{12, 12, 1}, {20, 20, 32}}
+	if got := f.BitmapStrikes(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("BitmapStrikes: got %v, want %v", got, want)
+	}
+
+	for _, i := range []Index{A, B} {
+		b, err := f.GlyphBitmap(12, i)
+		if err != nil || b == nil {
+			t.Errorf("GlyphBitmap(12, %d): got %v, %v", i, b, err)
+			continue
+		}
+		if b.Left != 1 || b.Top != 2 || b.Advance != 5 {
+			t.Errorf("GlyphBitmap(12, %d): got metrics %d, %d, %d, want 1, 2, 5", i, b.Left, b.Top, b.Advance)
+		}
+		m, ok := b.Image.(*image.Alpha)
+		if !ok {
+			t.Errorf("GlyphBitmap(12, %d): got image %T, want *image.Alpha", i, b.Image)
+			continue
+		}
+		if want := []uint8{0xff, 0, 0xff, 0, 0xff, 0}; !bytes.Equal(m.Pix, want) {
+			t.Errorf("GlyphBitmap(12, %d): got pixels %v, want %v", i, m.Pix, want)
+		}
+	}
+	if b, err := f.GlyphBitmap(12, C); b != nil || err != nil {
+		t.Errorf("GlyphBitmap(12, C): got %v, %v, want nil, nil", b, err)
+	}
+	if b, err := f.GlyphBitmap(13, A); b != nil || err != nil {
+		t.Errorf("GlyphBitmap(13, A): got %v, %v, want nil, nil", b, err)
+	}
+
+	b, err := f.GlyphBitmap(20, A)
+	if err != nil || b == nil {
+		t.Fatalf("GlyphBitmap(20, A): got %v, %v", b, err)
+	}
+	m, ok := b.Image.(*image.RGBA)
+	if !ok {
+		t.Fatalf("GlyphBitmap(20, A): got image %T, want *image.RGBA", b.Image)
+	}
+	if got, want := m.At(1, 1), (color.RGBA{0xff, 0, 0, 0xff}); got != want {
+		t.Errorf("GlyphBitmap(20, A): got color %v, want %v", got, want)
+	}
+
+	// A face draws the bitmaps of the strike that matches its size.
+	face := NewFace(f, &Options{Size: 12})
+	dot := fixed.P(10, 20)
+	dr, mask, maskp, advance, ok := face.Glyph(dot, 'A')
+	if !ok {
+		t.Fatal("Glyph: not ok")
+	}
+	if want := image.Rect(11, 18, 14, 20); dr != want {
+		t.Errorf("Glyph: got dr %v, want %v", dr, want)
+	}
+	if a := mask.(*image.Alpha).AlphaAt(maskp.X+2, maskp.Y); a.A != 0xff {
+		t.Errorf("Glyph: got mask alpha %d, want 0xff", a.A)
+	}
+	if advance != fixed.I(5) {
+		t.Errorf("Glyph: got advance %v, want %v", advance, fixed.I(5))
+	}
+	bounds, advance, ok := face.GlyphBounds('B')
+	if want := fixed.R(1, -2, 4, 0); !ok || bounds != want || advance != fixed.I(5) {
+		t.Errorf("GlyphBounds: got %v, %v, %t, want %v, %v, true", bounds, advance, ok, want, fixed.I(5))
+	}
+	// Glyphs without bitmaps are drawn from their outlines.
+	if advance, _ := face.GlyphAdvance('C'); advance != g.HMetric(fixed.I(12), C).AdvanceWidth {
+		t.Errorf("GlyphAdvance('C'): got %v, want the outline's advance", advance)
+	}
+
+	// Other sizes draw outlines.
+	face = NewFace(f, &Options{Size: 13})
+	if advance, _ := face.GlyphAdvance('A'); advance == fixed.I(5) {
+		t.Errorf("size 13: got the bitmap's advance")
+	}
+
+	// A malformed strike or table is ignored, leaving the other strikes.
+	eblc := append([]byte(nil), f.eblc...)
+	copy(eblc[8:], be16(0xffff, 0xffff))
+	for _, loc := range [][]byte{eblc, f.eblc[:6], f.eblc[:50]} {
+		h := makeTestFont(t, map[string][]byte{
+			"CBDT": f.cbdt, "CBLC": f.cblc, "EBDT": f.ebdt, "EBLC": loc,
+		})
+		if got, want := h.BitmapStrikes(), []BitmapStrike{{20, 20, 32}}; fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("malformed EBLC: BitmapStrikes: got %v, want %v", got, want)
+		}
+	}
+}
+
+// encodePNG returns a PNG image of the given size and color.
+func encodePNG(w, h int, c color.NRGBA) ([]byte, error) {
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			m.SetNRGBA(x, y, c)
+		}
+	}
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, m); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// sbixStrikeData returns an sbix strike with the given glyph data records.
+func sbixStrikeData(ppem, nGlyph int, glyphs map[Index][]byte) []byte {
+	b := be16(ppem, 72)
+	var data []byte
+	for i := 0; i <= nGlyph; i++ {
+		offset := 4 + 4*(nGlyph+1) + len(data)
+		b = append(b, be16(offset>>16, offset)...)
+		data = append(data, glyphs[Index(i)]...)
+	}
+	return append(b, data...)
+}
+
+func TestSbix(t *testing.T) {
+	g, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	A, B, C := g.Index('A'), g.Index('B'), g.Index('C')
+	red, err := encodePNG(2, 2, color.NRGBA{0xff, 0, 0, 0xff})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blue, err := encodePNG(4, 4, color.NRGBA{0, 0, 0xff, 0xff})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Glyph A has images in both strikes, with its origin one pixel above
+	// and to the left of the image's bottom-left corner. Glyph B has none,
+	// and glyph C duplicates glyph A.
+	strike20 := sbixStrikeData(20, g.nGlyph, map[Index][]byte{
+		A: cat(be16(1, 0xffff), []byte("png "), red),
+		C: cat(be16(0, 0), []byte("dupe"), be16(int(A))),
+	})
+	strike40 := sbixStrikeData(40, g.nGlyph, map[Index][]byte{
+		A: cat(be16(2, 0xfffe), []byte("png "), blue),
+	})
+	sbix := cat(be16(1, 1, 0, 2, 0, 16, 0, 16+len(strike20)), strike20, strike40)
+	f := makeTestFont(t, map[string][]byte{"sbix": sbix})
+
+	want := []SbixStrike{{20, 72}, {40, 72}}
+	if got := f.SbixStrikes(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("SbixStrikes: got %v, want %v", got, want)
+	}
+
+	// A strike with a bad offset is skipped.
+	bad := cat(be16(1, 1, 0, 2, 0, 16, 0xffff, 0xffff), strike20)
+	if got, want := makeTestFont(t, map[string][]byte{"sbix": bad}).SbixStrikes(), want[:1]; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("bad strike offset: SbixStrikes: got %v, want %v", got, want)
+	}
+	testCases := []struct {
+		ppem      int
+		i         Index
+		wantPPEM  int
+		left, top int
+	}{
+		{12, A, 20, 1, 1},
+		{30, A, 40, 2, 2},
+		{36, A, 40, 2, 2},
+		{20, C, 20, 1, 1},
+		{20, B, 0, 0, 0},
+	}
+	for _, tc := range testCases {
+		b, err := f.SbixBitmap(tc.ppem, tc.i)
+		if err != nil {
+			t.Errorf("SbixBitmap(%d, %d): %v", tc.ppem, tc.i, err)
+			continue
+		}
+		if tc.wantPPEM == 0 {
+			if b != nil {
+				t.Errorf("SbixBitmap(%d, %d): got %v, want nil", tc.ppem, tc.i, b)
+			}
+			continue
+		}
+		if b == nil {
+			t.Errorf("SbixBitmap(%d, %d): got nil", tc.ppem, tc.i)
+			continue
+		}
+		if b.PPEM != tc.wantPPEM || b.Left != tc.left || b.Top != tc.top {
+			t.Errorf("SbixBitmap(%d, %d): got PPEM %d, Left %d, Top %d, want %d, %d, %d",
+				tc.ppem, tc.i, b.PPEM, b.Left, b.Top, tc.wantPPEM, tc.left, tc.top)
+		}
+	}
+
+	// A 10 pixel face scales the 20 ppem strike's 2x2 image to 1x1.
+	face := NewFace(f, &Options{Size: 10})
+	dr, mask, maskp, advance, ok := face.Glyph(fixed.P(10, 20), 'A')
+	if !ok {
+		t.Fatal("Glyph: not ok")
+	}
+	if want := image.Rect(11, 19, 12, 20); dr != want {
+		t.Errorf("Glyph: got dr %v, want %v", dr, want)
+	}
+	m, ok := mask.(*image.RGBA)
+	if !ok {
+		t.Fatalf("Glyph: got mask %T, want *image.RGBA", mask)
+	}
+	if got, want := m.RGBAAt(maskp.X, maskp.Y), (color.RGBA{0xff, 0, 0, 0xff}); got != want {
+		t.Errorf("Glyph: got color %v, want %v", got, want)
+	}
+	if want := (g.HMetric(fixed.I(10), A).AdvanceWidth + 32) &^ 63; advance != want {
+		t.Errorf("Glyph: got advance %v, want %v", advance, want)
+	}
+}
+
+func TestScaleImage(t *testing.T) {
+	// Halving a checkerboard of opaque and transparent pixels averages them.
+	src := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	src.SetRGBA(0, 0, color.RGBA{0xff, 0xff, 0xff, 0xff})
+	src.SetRGBA(1, 1, color.RGBA{0xff, 0xff, 0xff, 0xff})
+	if got, want := scaleImage(src, 1, 1).RGBAAt(0, 0), (color.RGBA{0x80, 0x80, 0x80, 0x80}); got != want {
+		t.Errorf("halved: got %v, want %v", got, want)
+	}
+	// Doubling copies each pixel.
+	dst := scaleImage(src, 4, 4)
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			want := src.RGBAAt(x/2, y/2)
+			if got := dst.RGBAAt(x, y); got != want {
+				t.Errorf("doubled (%d, %d): got %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
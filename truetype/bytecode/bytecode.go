@@ -0,0 +1,362 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+// Package bytecode disassembles and assembles TrueType hinting programs, such
+// as a font's fpgm and prep tables and its glyphs' instructions.
+//
+// The text form has one instruction per line, written as its mnemonic, such
+// as "MDRP[01101]", with any flag bits in brackets. Push instructions are
+// followed by the values that they push, such as "PUSHB[001] 3 17". Undefined
+// opcodes are written in hexadecimal, such as "0x91". The bodies of FDEF,
+// IDEF and IF instructions are indented, and text after a ';' is a comment.
+// The opcodes are described at
+// https://developer.apple.com/fonts/TTRefMan/RM05/Chap5.html
+package bytecode // import "github.com/golang/freetype/truetype/bytecode"
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	opELSE   = 0x1b
+	opFDEF   = 0x2c
+	opENDF   = 0x2d
+	opNPUSHB = 0x40
+	opNPUSHW = 0x41
+	opIF     = 0x58
+	opEIF    = 0x59
+	opIDEF   = 0x89
+	opPUSHB  = 0xb0 // PUSHB[000], which pushes one byte.
+	opPUSHW  = 0xb8 // PUSHW[000], which pushes one word.
+)
+
+// names are the opcodes' mnemonics. Undefined opcodes have no name.
+var names = [256]string{
+	// 0x00 - 0x0f
+	"SVTCA[0]", "SVTCA[1]", "SPVTCA[0]", "SPVTCA[1]", "SFVTCA[0]", "SFVTCA[1]", "SPVTL[0]", "SPVTL[1]",
+	"SFVTL[0]", "SFVTL[1]", "SPVFS", "SFVFS", "GPV", "GFV", "SFVTPV", "ISECT",
+	// 0x10 - 0x1f
+	"SRP0", "SRP1", "SRP2", "SZP0", "SZP1", "SZP2", "SZPS", "SLOOP",
+	"RTG", "RTHG", "SMD", "ELSE", "JMPR", "SCVTCI", "SSWCI", "SSW",
+	// 0x20 - 0x2f
+	"DUP", "POP", "CLEAR", "SWAP", "DEPTH", "CINDEX", "MINDEX", "ALIGNPTS",
+	"", "UTP", "LOOPCALL", "CALL", "FDEF", "ENDF", "MDAP[0]", "MDAP[1]",
+	// 0x30 - 0x3f
+	"IUP[0]", "IUP[1]", "SHP[0]", "SHP[1]", "SHC[0]", "SHC[1]", "SHZ[0]", "SHZ[1]",
+	"SHPIX", "IP", "MSIRP[0]", "MSIRP[1]", "ALIGNRP", "RTDG", "MIAP[0]", "MIAP[1]",
+	// 0x40 - 0x4f
+	"NPUSHB", "NPUSHW", "WS", "RS", "WCVTP", "RCVT", "GC[0]", "GC[1]",
+	"SCFS", "MD[0]", "MD[1]", "MPPEM", "MPS", "FLIPON", "FLIPOFF", "DEBUG",
+	// 0x50 - 0x5f
+	"LT", "LTEQ", "GT", "GTEQ", "EQ", "NEQ", "ODD", "EVEN",
+	"IF", "EIF", "AND", "OR", "NOT", "DELTAP1", "SDB", "SDS",
+	// 0x60 - 0x6f
+	"ADD", "SUB", "DIV", "MUL", "ABS", "NEG", "FLOOR", "CEILING",
+	"ROUND[00]", "ROUND[01]", "ROUND[10]", "ROUND[11]", "NROUND[00]", "NROUND[01]", "NROUND[10]", "NROUND[11]",
+	// 0x70 - 0x7f
+	"WCVTF", "DELTAP2", "DELTAP3", "DELTAC1", "DELTAC2", "DELTAC3", "SROUND", "S45ROUND",
+	"JROT", "JROF", "ROFF", "", "RUTG", "RDTG", "SANGW", "AA",
+	// 0x80 - 0x8f
+	"FLIPPT", "FLIPRGON", "FLIPRGOFF", "", "", "SCANCTRL", "SDPVTL[0]", "SDPVTL[1]",
+	"GETINFO", "IDEF", "ROLL", "MAX", "MIN", "SCANTYPE", "INSTCTRL", "",
+	// 0x90 - 0x9f
+	"", "", "", "", "", "", "", "",
+	"", "", "", "", "", "", "", "",
+	// 0xa0 - 0xaf
+	"", "", "", "", "", "", "", "",
+	"", "", "", "", "", "", "", "",
+	// 0xb0 - 0xbf
+	"PUSHB[000]", "PUSHB[001]", "PUSHB[010]", "PUSHB[011]", "PUSHB[100]", "PUSHB[101]", "PUSHB[110]", "PUSHB[111]",
+	"PUSHW[000]", "PUSHW[001]", "PUSHW[010]", "PUSHW[011]", "PUSHW[100]", "PUSHW[101]", "PUSHW[110]", "PUSHW[111]",
+	// 0xc0 - 0xcf
+	"MDRP[00000]", "MDRP[00001]", "MDRP[00010]", "MDRP[00011]", "MDRP[00100]", "MDRP[00101]", "MDRP[00110]", "MDRP[00111]",
+	"MDRP[01000]", "MDRP[01001]", "MDRP[01010]", "MDRP[01011]", "MDRP[01100]", "MDRP[01101]", "MDRP[01110]", "MDRP[01111]",
+	// 0xd0 - 0xdf
+	"MDRP[10000]", "MDRP[10001]", "MDRP[10010]", "MDRP[10011]", "MDRP[10100]", "MDRP[10101]", "MDRP[10110]", "MDRP[10111]",
+	"MDRP[11000]", "MDRP[11001]", "MDRP[11010]", "MDRP[11011]", "MDRP[11100]", "MDRP[11101]", "MDRP[11110]", "MDRP[11111]",
+	// 0xe0 - 0xef
+	"MIRP[00000]", "MIRP[00001]", "MIRP[00010]", "MIRP[00011]", "MIRP[00100]", "MIRP[00101]", "MIRP[00110]", "MIRP[00111]",
+	"MIRP[01000]", "MIRP[01001]", "MIRP[01010]", "MIRP[01011]", "MIRP[01100]", "MIRP[01101]", "MIRP[01110]", "MIRP[01111]",
+	// 0xf0 - 0xff
+	"MIRP[10000]", "MIRP[10001]", "MIRP[10010]", "MIRP[10011]", "MIRP[10100]", "MIRP[10101]", "MIRP[10110]", "MIRP[10111]",
+	"MIRP[11000]", "MIRP[11001]", "MIRP[11010]", "MIRP[11011]", "MIRP[11100]", "MIRP[11101]", "MIRP[11110]", "MIRP[11111]",
+}
+
+// opcodes maps mnemonics to opcodes.
+var opcodes = map[string]byte{}
+
+func init() {
+	for i, name := range names {
+		if name != "" {
+			opcodes[name] = byte(i)
+		}
+	}
+}
+
+// Name returns the mnemonic of the opcode op, such as "MDRP[01101]", or op in
+// hexadecimal, such as "0x91", if it is undefined.
+func Name(op byte) string {
+	if names[op] != "" {
+		return names[op]
+	}
+	return fmt.Sprintf("0x%02x", op)
+}
+
+// pushCount returns the number of values that the push instruction op pushes,
+// the width of each value in bytes, and whether that number follows op in
+// the program. n is 0 and ok is false if op is not a push instruction.
+func pushCount(op byte) (n, width int, follows, ok bool) {
+	switch {
+	case op == opNPUSHB:
+		return 0, 1, true, true
+	case op == opNPUSHW:
+		return 0, 2, true, true
+	case opPUSHB <= op && op < opPUSHB+8:
+		return int(op-opPUSHB) + 1, 1, false, true
+	case opPUSHW <= op && op < opPUSHW+8:
+		return int(op-opPUSHW) + 1, 2, false, true
+	}
+	return 0, 0, false, false
+}
+
+// Instruction is a decoded instruction.
+type Instruction struct {
+	// Offset is the instruction's offset in its program.
+	Offset int
+	// Opcode is the instruction's opcode.
+	Opcode byte
+	// Args are the values that a push instruction pushes, in the order that
+	// they are pushed. They are nil for other instructions.
+	Args []int32
+}
+
+// String returns the instruction's text form, without indentation.
+func (i Instruction) String() string {
+	if len(i.Args) == 0 {
+		return Name(i.Opcode)
+	}
+	s := make([]string, 1+len(i.Args))
+	s[0] = Name(i.Opcode)
+	for j, a := range i.Args {
+		s[1+j] = strconv.Itoa(int(a))
+	}
+	return strings.Join(s, " ")
+}
+
+// Decode decodes the program's instructions. If the program ends in the
+// middle of a push instruction's values, it returns the instructions before
+// that one and an error.
+func Decode(program []byte) ([]Instruction, error) {
+	var ret []Instruction
+	for pc := 0; pc < len(program); {
+		i := Instruction{Offset: pc, Opcode: program[pc]}
+		pc++
+		n, width, follows, ok := pushCount(i.Opcode)
+		if ok {
+			if follows {
+				if pc >= len(program) {
+					return ret, fmt.Errorf("bytecode: offset %d: %s: insufficient data", i.Offset, Name(i.Opcode))
+				}
+				n = int(program[pc])
+				pc++
+			}
+			if pc+n*width > len(program) {
+				return ret, fmt.Errorf("bytecode: offset %d: %s: insufficient data", i.Offset, Name(i.Opcode))
+			}
+			i.Args = make([]int32, n)
+			for j := range i.Args {
+				if width == 1 {
+					i.Args[j] = int32(program[pc])
+				} else {
+					i.Args[j] = int32(int16(uint16(program[pc])<<8 | uint16(program[pc+1])))
+				}
+				pc += width
+			}
+		}
+		ret = append(ret, i)
+	}
+	return ret, nil
+}
+
+// Disassemble returns the program's text form. Unbalanced FDEF, IDEF, IF,
+// ELSE, ENDF and EIF instructions are not an error, so that broken programs
+// can be inspected, but a truncated push instruction is.
+func Disassemble(program []byte) (string, error) {
+	instructions, err := Decode(program)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	depth := 0
+	for _, i := range instructions {
+		d := depth
+		switch i.Opcode {
+		case opFDEF, opIDEF, opIF:
+			depth++
+		case opELSE:
+			d--
+		case opENDF, opEIF:
+			depth--
+			d = depth
+		}
+		if d < 0 {
+			d = 0
+		}
+		if depth < 0 {
+			depth = 0
+		}
+		b.WriteString(strings.Repeat("  ", d))
+		b.WriteString(i.String())
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+// Assemble returns the program whose text form is text. Mnemonics are not
+// case sensitive, and indentation is ignored.
+//
+// As well as the instructions' mnemonics, the text form may use PUSH, which
+// is assembled to the shortest sequence of PUSHB, PUSHW, NPUSHB and NPUSHW
+// instructions that push its values.
+func Assemble(text string) ([]byte, error) {
+	var program []byte
+	for n, line := range strings.Split(text, "\n") {
+		if i := strings.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		mnemonic := strings.ToUpper(fields[0])
+		args := make([]int32, len(fields)-1)
+		for i, f := range fields[1:] {
+			a, err := strconv.ParseInt(f, 0, 32)
+			if err != nil {
+				return nil, fmt.Errorf("bytecode: line %d: invalid value %q", n+1, f)
+			}
+			args[i] = int32(a)
+		}
+
+		if mnemonic == "PUSH" {
+			var err error
+			if program, err = appendPush(program, args); err != nil {
+				return nil, fmt.Errorf("bytecode: line %d: %v", n+1, err)
+			}
+			continue
+		}
+		op, ok := opcodes[mnemonic]
+		if !ok {
+			x, err := strconv.ParseUint(mnemonic, 0, 8)
+			if err != nil || !strings.HasPrefix(mnemonic, "0X") {
+				return nil, fmt.Errorf("bytecode: line %d: unknown instruction %q", n+1, fields[0])
+			}
+			op = byte(x)
+		}
+		want, width, follows, push := pushCount(op)
+		if !push {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("bytecode: line %d: %s takes no values", n+1, Name(op))
+			}
+			program = append(program, op)
+			continue
+		}
+		if follows {
+			want = len(args)
+			if want > 255 {
+				return nil, fmt.Errorf("bytecode: line %d: %s pushes at most 255 values", n+1, Name(op))
+			}
+		}
+		if len(args) != want {
+			return nil, fmt.Errorf("bytecode: line %d: %s pushes %d values, not %d", n+1, Name(op), want, len(args))
+		}
+		program = append(program, op)
+		if follows {
+			program = append(program, byte(want))
+		}
+		var err error
+		if program, err = appendValues(program, args, width); err != nil {
+			return nil, fmt.Errorf("bytecode: line %d: %s: %v", n+1, Name(op), err)
+		}
+	}
+	return program, nil
+}
+
+// appendValues appends the values to the program, as bytes or words.
+func appendValues(program []byte, values []int32, width int) ([]byte, error) {
+	for _, v := range values {
+		if width == 1 {
+			if v < 0 || 0xff < v {
+				return nil, fmt.Errorf("value %d is out of range for a byte", v)
+			}
+			program = append(program, byte(v))
+		} else {
+			if v < -0x8000 || 0x7fff < v {
+				return nil, fmt.Errorf("value %d is out of range for a word", v)
+			}
+			program = append(program, byte(v>>8), byte(v))
+		}
+	}
+	return program, nil
+}
+
+// appendPush appends the shortest sequence of push instructions for the
+// values to the program. Of the sequences with the fewest bytes, it picks the
+// one with the fewest instructions.
+func appendPush(program []byte, values []int32) ([]byte, error) {
+	// size[j] and count[j] are the number of bytes and of instructions that
+	// push values[:j], whose last instruction pushes values[start[j]:j], as
+	// bytes or as words depending on width[j].
+	n := len(values)
+	size := make([]int, n+1)
+	count := make([]int, n+1)
+	start := make([]int, n+1)
+	width := make([]int, n+1)
+	for j := 1; j <= n; j++ {
+		size[j] = -1
+		fits := true
+		for i := j - 1; i >= 0 && j-i <= 255; i-- {
+			fits = fits && 0 <= values[i] && values[i] <= 0xff
+			w := 2
+			if fits {
+				w = 1
+			}
+			s := size[i] + 1 + w*(j-i)
+			if j-i > 8 {
+				// NPUSHB and NPUSHW have a count byte.
+				s++
+			}
+			if size[j] < 0 || s < size[j] || s == size[j] && count[i]+1 < count[j] {
+				size[j], count[j], start[j], width[j] = s, count[i]+1, i, w
+			}
+		}
+	}
+
+	var ends []int
+	for j := n; j > 0; j = start[j] {
+		ends = append(ends, j)
+	}
+	for k := len(ends) - 1; k >= 0; k-- {
+		j := ends[k]
+		i, w := start[j], width[j]
+		switch m := j - i; {
+		case m <= 8 && w == 1:
+			program = append(program, opPUSHB+byte(m-1))
+		case m <= 8:
+			program = append(program, opPUSHW+byte(m-1))
+		case w == 1:
+			program = append(program, opNPUSHB, byte(m))
+		default:
+			program = append(program, opNPUSHW, byte(m))
+		}
+		var err error
+		if program, err = appendValues(program, values[i:j], w); err != nil {
+			return nil, err
+		}
+	}
+	return program, nil
+}
@@ -0,0 +1,221 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package bytecode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestNames(t *testing.T) {
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		if got := opcodes[name]; got != byte(i) {
+			t.Errorf("opcode %#02x: %q is also the name of %#02x", i, name, got)
+		}
+	}
+	if got, want := Name(0x91), "0x91"; got != want {
+		t.Errorf("Name(0x91): got %q, want %q", got, want)
+	}
+}
+
+func TestAllOpcodes(t *testing.T) {
+	for i := 0; i < 256; i++ {
+		program := []byte{byte(i)}
+		if n, width, follows, ok := pushCount(byte(i)); ok {
+			if follows {
+				n = 2
+				program = append(program, byte(n))
+			}
+			program = append(program, make([]byte, n*width)...)
+		}
+		text, err := Disassemble(program)
+		if err != nil {
+			t.Errorf("opcode %#02x: Disassemble: %v", i, err)
+			continue
+		}
+		got, err := Assemble(text)
+		if err != nil {
+			t.Errorf("opcode %#02x: Assemble(%q): %v", i, text, err)
+			continue
+		}
+		if !bytes.Equal(got, program) {
+			t.Errorf("opcode %#02x: got % x, want % x", i, got, program)
+		}
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	program := []byte{
+		0xb1, 0x00, 0x07, // PUSHB[001] 0 7
+		0x2c,       // FDEF
+		0x58,       // IF
+		0x41, 0x02, // NPUSHW 2
+		0xff, 0xfe, 0x01, 0x00, // -2 256
+		0x1b,       // ELSE
+		0x40, 0x01, // NPUSHB 1
+		0x2a, // 42
+		0x59, // EIF
+		0x2d, // ENDF
+		0x91, // an undefined opcode
+		0x2d, // an unbalanced ENDF
+		0xc1, // MDRP[00001]
+	}
+	want := strings.Join([]string{
+		"PUSHB[001] 0 7",
+		"FDEF",
+		"  IF",
+		"    NPUSHW -2 256",
+		"  ELSE",
+		"    NPUSHB 42",
+		"  EIF",
+		"ENDF",
+		"0x91",
+		"ENDF",
+		"MDRP[00001]",
+	}, "\n") + "\n"
+	got, err := Disassemble(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Fatalf("Disassemble:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+	reassembled, err := Assemble(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reassembled, program) {
+		t.Fatalf("Assemble:\ngot  % x\nwant % x", reassembled, program)
+	}
+
+	for _, truncated := range [][]byte{
+		{0x40},
+		{0x40, 0x02, 0x00},
+		{0xb8, 0x00},
+	} {
+		if _, err := Disassemble(truncated); err == nil {
+			t.Errorf("Disassemble(% x): got no error", truncated)
+		}
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	testCases := []struct {
+		text string
+		want []byte
+	}{
+		{"svtca[1] ; set both vectors to the x-axis\n\n  Mdap[1]", []byte{0x01, 0x2f}},
+		{"PUSH 1 2 3", []byte{0xb2, 1, 2, 3}},
+		{"PUSH 1 -1", []byte{0xb9, 0x00, 0x01, 0xff, 0xff}},
+		{"PUSH 0 1 2 3 4 5 6 7 8", []byte{0x40, 9, 0, 1, 2, 3, 4, 5, 6, 7, 8}},
+		{"PUSH 0x100 1 2 3 4 5 6 7 8", []byte{0xb8, 0x01, 0x00, 0xb7, 1, 2, 3, 4, 5, 6, 7, 8}},
+		{"PUSH 1 2 3 -1 4 5 6", []byte{0xb2, 1, 2, 3, 0xb8, 0xff, 0xff, 0xb2, 4, 5, 6}},
+		{"PUSH 1 0x100 2", []byte{0xba, 0x00, 0x01, 0x01, 0x00, 0x00, 0x02}},
+		{"PUSH", nil},
+		{"0x91\n0xA0", []byte{0x91, 0xa0}},
+	}
+	for _, tc := range testCases {
+		got, err := Assemble(tc.text)
+		if err != nil {
+			t.Errorf("Assemble(%q): %v", tc.text, err)
+			continue
+		}
+		if !bytes.Equal(got, tc.want) {
+			t.Errorf("Assemble(%q): got % x, want % x", tc.text, got, tc.want)
+		}
+	}
+
+	badCases := []string{
+		"FOO",
+		"SVTCA[2]",
+		"SVTCA[0] 1",
+		"PUSHB[001] 1",
+		"PUSHB[000] 256",
+		"PUSHW[000] 32768",
+		"NPUSHB -1",
+		"PUSHB[000] x",
+		"0x100",
+		"PUSH 1 65536",
+	}
+	for _, text := range badCases {
+		if _, err := Assemble(text); err == nil {
+			t.Errorf("Assemble(%q): got no error", text)
+		}
+	}
+	if _, err := Assemble("SVTCA[0]\nNPUSHB " + strings.Repeat("1 ", 256)); err == nil {
+		t.Errorf("Assemble: NPUSHB with 256 values: got no error")
+	} else if !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Assemble: NPUSHB with 256 values: got %q, want the line number", err)
+	}
+
+	// PUSH splits more than 255 values into several instructions.
+	values := strings.Repeat("1 ", 300)
+	got, err := Assemble("PUSH " + values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2+255+2+45 || got[0] != 0x40 || got[1] != 255 || got[257] != 0x40 || got[258] != 45 {
+		t.Errorf("PUSH with 300 values: got % x", got[:4])
+	}
+}
+
+// testdataTables returns the named tables of a font in the testdata
+// directory.
+func testdataTables(t *testing.T, filename string, tags ...string) map[string][]byte {
+	b, err := ioutil.ReadFile("../../testdata/" + filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) < 12 {
+		t.Fatalf("%s: too short", filename)
+	}
+	ret := map[string][]byte{}
+	n := int(binary.BigEndian.Uint16(b[4:]))
+	for i := 0; i < n; i++ {
+		x := 12 + 16*i
+		if x+16 > len(b) {
+			t.Fatalf("%s: bad table directory", filename)
+		}
+		tag := string(b[x : x+4])
+		offset := int(binary.BigEndian.Uint32(b[x+8:]))
+		length := int(binary.BigEndian.Uint32(b[x+12:]))
+		if offset+length > len(b) {
+			t.Fatalf("%s: bad table %q", filename, tag)
+		}
+		ret[tag] = b[offset : offset+length]
+	}
+	for _, tag := range tags {
+		if ret[tag] == nil {
+			t.Fatalf("%s: no %q table", filename, tag)
+		}
+	}
+	return ret
+}
+
+func TestRoundTripFont(t *testing.T) {
+	tables := testdataTables(t, "luxisr.ttf", "fpgm", "prep")
+	for _, tag := range []string{"fpgm", "prep"} {
+		text, err := Disassemble(tables[tag])
+		if err != nil {
+			t.Errorf("%s: Disassemble: %v", tag, err)
+			continue
+		}
+		got, err := Assemble(text)
+		if err != nil {
+			t.Errorf("%s: Assemble: %v", tag, err)
+			continue
+		}
+		if !bytes.Equal(got, tables[tag]) {
+			t.Errorf("%s: round trip: got %d bytes, want %d", tag, len(got), len(tables[tag]))
+		}
+	}
+}
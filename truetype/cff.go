@@ -0,0 +1,1021 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+// This file implements a parser for the Compact Font Format tables, "CFF " and
+// "CFF2", which hold the cubic outlines of OpenType (.otf) fonts, and a
+// decoder for the Type 2 charstrings that those outlines are encoded as.
+// The formats are described at
+// https://adobe-type-tools.github.io/font-tech-notes/pdfs/5176.CFF.pdf,
+// https://adobe-type-tools.github.io/font-tech-notes/pdfs/5177.Type2.pdf and
+// https://www.microsoft.com/typography/otspec/cff2.htm
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// FlagCubic is the bit of a Point's Flags that marks an 'off' Point as a
+// cubic, not quadratic, Bézier control point. Such points come in pairs, and
+// only occur in CFF outlines.
+const FlagCubic = 1 << 8
+
+// DICT operators. Two-byte operators, whose first byte is 12, are represented
+// as 0x0c00 plus their second byte.
+const (
+	cffOpCharStrings    = 17
+	cffOpPrivate        = 18
+	cffOpSubrs          = 19
+	cffOpVSIndex        = 22
+	cffOpBlend          = 23
+	cffOpVStore         = 24
+	cffOpCharstringType = 0x0c06
+	cffOpROS            = 0x0c1e
+	cffOpFDArray        = 0x0c24
+	cffOpFDSelect       = 0x0c25
+)
+
+// cffIndex is a CFF INDEX, an array of variable length objects. The objects
+// are sliced lazily, as CJK fonts can have tens of thousands of glyphs.
+type cffIndex struct {
+	count, offSize int
+	offsets, data  []byte
+}
+
+// parseCFFIndex parses the INDEX at b[offset:], returning it and the offset
+// just past its end. CFF2 INDEXes have a 32-bit count instead of a 16-bit one.
+func parseCFFIndex(b []byte, offset int, cff2 bool) (x cffIndex, end int, err error) {
+	countSize := 2
+	if cff2 {
+		countSize = 4
+	}
+	if offset < 0 || len(b)-offset < countSize {
+		return cffIndex{}, 0, FormatError("CFF INDEX too short")
+	}
+	if cff2 {
+		x.count = int(u32(b, offset))
+	} else {
+		x.count = int(u16(b, offset))
+	}
+	offset += countSize
+	if x.count == 0 {
+		return x, offset, nil
+	}
+	if x.count < 0 || offset >= len(b) {
+		return cffIndex{}, 0, FormatError("CFF INDEX too short")
+	}
+	x.offSize = int(b[offset])
+	offset++
+	if x.offSize < 1 || 4 < x.offSize {
+		return cffIndex{}, 0, FormatError(fmt.Sprintf("bad CFF INDEX offSize: %d", x.offSize))
+	}
+	n := (x.count + 1) * x.offSize
+	if n/x.offSize != x.count+1 || len(b)-offset < n {
+		return cffIndex{}, 0, FormatError("CFF INDEX too short")
+	}
+	x.offsets = b[offset : offset+n]
+	offset += n
+	dataLen := x.offset(x.count) - 1
+	if dataLen < 0 || len(b)-offset < dataLen {
+		return cffIndex{}, 0, FormatError("CFF INDEX too short")
+	}
+	x.data = b[offset : offset+dataLen]
+	return x, offset + dataLen, nil
+}
+
+// offset returns the i'th element of the INDEX's offset array. Offsets are
+// 1-based, relative to the byte preceding the object data.
+func (x *cffIndex) offset(i int) int {
+	v, b := 0, x.offsets[i*x.offSize:(i+1)*x.offSize]
+	for _, c := range b {
+		v = v<<8 | int(c)
+	}
+	return v
+}
+
+// get returns the i'th object in the INDEX.
+func (x *cffIndex) get(i int) ([]byte, error) {
+	if i < 0 || x.count <= i {
+		return nil, FormatError(fmt.Sprintf("CFF INDEX element out of range: %d", i))
+	}
+	o0, o1 := x.offset(i), x.offset(i+1)
+	if o0 < 1 || o1 < o0 || len(x.data)+1 < o1 {
+		return nil, FormatError("bad CFF INDEX offset")
+	}
+	return x.data[o0-1 : o1-1], nil
+}
+
+// cffDict maps a DICT's operators to their operands.
+type cffDict map[int][]float64
+
+// int returns the i'th operand of op, or dflt if op is absent.
+func (d cffDict) int(op, i, dflt int) int {
+	if v := d[op]; i < len(v) {
+		return int(v[i])
+	}
+	return dflt
+}
+
+// parseCFFDict parses the DICT data in b. regionCounts is the number of
+// variation regions for each vsindex, and is used to discard the deltas of a
+// CFF2 blend operator, leaving the default instance's values.
+func parseCFFDict(b []byte, regionCounts []int) (cffDict, error) {
+	d, operands, vsindex := cffDict{}, []float64(nil), 0
+	for i := 0; i < len(b); {
+		b0 := int(b[i])
+		switch {
+		case b0 <= 27:
+			op := b0
+			i++
+			if b0 == 12 {
+				if i >= len(b) {
+					return nil, FormatError("bad CFF DICT operator")
+				}
+				op = 0x0c00 | int(b[i])
+				i++
+			}
+			switch op {
+			case cffOpVSIndex:
+				if len(operands) != 1 {
+					return nil, FormatError("bad CFF DICT vsindex")
+				}
+				vsindex = int(operands[0])
+			case cffOpBlend:
+				if len(operands) == 0 || vsindex < 0 || len(regionCounts) <= vsindex {
+					return nil, FormatError("bad CFF DICT blend")
+				}
+				n := int(operands[len(operands)-1])
+				k := regionCounts[vsindex]
+				if n < 0 || len(operands) < n*(k+1)+1 {
+					return nil, FormatError("bad CFF DICT blend")
+				}
+				operands = operands[:len(operands)-1-n*k]
+				// The blended values are operands for the next operator.
+				continue
+			}
+			d[op] = operands
+			operands = nil
+		case b0 == 28:
+			if len(b)-i < 3 {
+				return nil, FormatError("bad CFF DICT operand")
+			}
+			operands = append(operands, float64(int16(u16(b, i+1))))
+			i += 3
+		case b0 == 29:
+			if len(b)-i < 5 {
+				return nil, FormatError("bad CFF DICT operand")
+			}
+			operands = append(operands, float64(int32(u32(b, i+1))))
+			i += 5
+		case b0 == 30:
+			v, n, err := parseCFFReal(b[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			operands = append(operands, v)
+			i += 1 + n
+		case 32 <= b0 && b0 <= 246:
+			operands = append(operands, float64(b0-139))
+			i++
+		case 247 <= b0 && b0 <= 254:
+			if len(b)-i < 2 {
+				return nil, FormatError("bad CFF DICT operand")
+			}
+			if b0 <= 250 {
+				operands = append(operands, float64(+(b0-247)*256+int(b[i+1])+108))
+			} else {
+				operands = append(operands, float64(-(b0-251)*256-int(b[i+1])-108))
+			}
+			i += 2
+		default:
+			return nil, FormatError(fmt.Sprintf("bad CFF DICT byte: %d", b0))
+		}
+	}
+	return d, nil
+}
+
+// parseCFFReal parses a DICT real number operand, encoded as nibbles. It
+// returns the value and the number of bytes consumed.
+func parseCFFReal(b []byte) (v float64, n int, err error) {
+	s := make([]byte, 0, 16)
+	for n < len(b) {
+		c := b[n]
+		n++
+		for _, nib := range [2]byte{c >> 4, c & 0x0f} {
+			switch {
+			case nib <= 9:
+				s = append(s, '0'+nib)
+			case nib == 0xa:
+				s = append(s, '.')
+			case nib == 0xb:
+				s = append(s, 'E')
+			case nib == 0xc:
+				s = append(s, 'E', '-')
+			case nib == 0xe:
+				s = append(s, '-')
+			case nib == 0xf:
+				v, err := strconv.ParseFloat(string(s), 64)
+				if err != nil {
+					return 0, 0, FormatError("bad CFF DICT real")
+				}
+				return v, n, nil
+			default:
+				return 0, 0, FormatError("bad CFF DICT real")
+			}
+		}
+	}
+	return 0, 0, FormatError("bad CFF DICT real")
+}
+
+// cffFont holds the parsed "CFF " or "CFF2" table of an OpenType font.
+type cffFont struct {
+	cff2        bool
+	charStrings cffIndex
+	globalSubrs cffIndex
+	// localSubrs and vsindexes hold each Font DICT's local subroutines and
+	// default vsindex. A non-CID-keyed CFF font has exactly one Font DICT.
+	localSubrs []cffIndex
+	vsindexes  []int
+	// fdSelect is the FDSelect data, which maps glyph indexes to Font DICTs.
+	// It is nil if there is only one Font DICT.
+	fdSelect []byte
+	// regionCounts is the number of variation regions in each of a CFF2
+	// table's ItemVariationData subtables, indexed by vsindex.
+	regionCounts []int
+	// vstore is a CFF2 table's VariationStore. It is nil if there is none.
+	vstore *itemVariationStore
+}
+
+// parseCFF parses a "CFF " or "CFF2" table.
+func parseCFF(b []byte, cff2 bool) (*cffFont, error) {
+	if len(b) < 4 {
+		return nil, FormatError("CFF data too short")
+	}
+	c := &cffFont{cff2: cff2}
+	var (
+		top    cffDict
+		offset int
+		err    error
+	)
+	if cff2 {
+		if b[0] != 2 {
+			return nil, UnsupportedError(fmt.Sprintf("CFF2 major version: %d", b[0]))
+		}
+		if len(b) < 5 {
+			return nil, FormatError("CFF2 data too short")
+		}
+		hdrSize, topLen := int(b[2]), int(u16(b, 3))
+		if len(b) < hdrSize+topLen {
+			return nil, FormatError("CFF2 data too short")
+		}
+		// The Top DICT may refer to the VariationStore, so find that first.
+		top, err = parseCFFDict(b[hdrSize:hdrSize+topLen], nil)
+		if err != nil {
+			return nil, err
+		}
+		if o := top.int(cffOpVStore, 0, 0); o != 0 {
+			if c.vstore, err = parseCFF2VStore(b, o); err != nil {
+				return nil, err
+			}
+			c.regionCounts = make([]int, len(c.vstore.data))
+			for i, d := range c.vstore.data {
+				c.regionCounts[i] = len(d.regionIndexes)
+			}
+		}
+		offset = hdrSize + topLen
+	} else {
+		if b[0] != 1 {
+			return nil, UnsupportedError(fmt.Sprintf("CFF major version: %d", b[0]))
+		}
+		// Skip the Name INDEX.
+		_, offset, err = parseCFFIndex(b, int(b[2]), false)
+		if err != nil {
+			return nil, err
+		}
+		var topDicts cffIndex
+		topDicts, offset, err = parseCFFIndex(b, offset, false)
+		if err != nil {
+			return nil, err
+		}
+		if topDicts.count != 1 {
+			return nil, UnsupportedError(fmt.Sprintf("CFF fonts: %d", topDicts.count))
+		}
+		data, err := topDicts.get(0)
+		if err != nil {
+			return nil, err
+		}
+		if top, err = parseCFFDict(data, nil); err != nil {
+			return nil, err
+		}
+		if t := top.int(cffOpCharstringType, 0, 2); t != 2 {
+			return nil, UnsupportedError(fmt.Sprintf("CFF charstring type: %d", t))
+		}
+		// Skip the String INDEX.
+		_, offset, err = parseCFFIndex(b, offset, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.globalSubrs, _, err = parseCFFIndex(b, offset, cff2)
+	if err != nil {
+		return nil, err
+	}
+	o := top.int(cffOpCharStrings, 0, 0)
+	if o == 0 {
+		return nil, FormatError("CFF has no CharStrings")
+	}
+	if c.charStrings, _, err = parseCFFIndex(b, o, cff2); err != nil {
+		return nil, err
+	}
+
+	// CFF2 fonts, and CID-keyed CFF fonts, have an array of Font DICTs, each
+	// with its own Private DICT. Other CFF fonts have a single Private DICT,
+	// referred to by the Top DICT.
+	var privates []cffDict
+	if _, cid := top[cffOpROS]; cff2 || cid {
+		o := top.int(cffOpFDArray, 0, 0)
+		if o == 0 {
+			return nil, FormatError("CFF has no FDArray")
+		}
+		fdArray, _, err := parseCFFIndex(b, o, cff2)
+		if err != nil {
+			return nil, err
+		}
+		if fdArray.count == 0 {
+			return nil, FormatError("CFF has an empty FDArray")
+		}
+		for i := 0; i < fdArray.count; i++ {
+			data, err := fdArray.get(i)
+			if err != nil {
+				return nil, err
+			}
+			fd, err := parseCFFDict(data, c.regionCounts)
+			if err != nil {
+				return nil, err
+			}
+			privates = append(privates, fd)
+		}
+		if fdArray.count > 1 {
+			o := top.int(cffOpFDSelect, 0, 0)
+			if o <= 0 || len(b) <= o {
+				return nil, FormatError("CFF has no FDSelect")
+			}
+			c.fdSelect = b[o:]
+		}
+	} else {
+		privates = append(privates, top)
+	}
+	for _, d := range privates {
+		size, o := d.int(cffOpPrivate, 0, 0), d.int(cffOpPrivate, 1, 0)
+		if size < 0 || o < 0 || len(b) < o+size {
+			return nil, FormatError("bad CFF Private DICT")
+		}
+		p, err := parseCFFDict(b[o:o+size], c.regionCounts)
+		if err != nil {
+			return nil, err
+		}
+		var subrs cffIndex
+		if so := p.int(cffOpSubrs, 0, 0); so != 0 {
+			// The Subrs offset is relative to the start of the Private DICT.
+			if subrs, _, err = parseCFFIndex(b, o+so, cff2); err != nil {
+				return nil, err
+			}
+		}
+		c.localSubrs = append(c.localSubrs, subrs)
+		c.vsindexes = append(c.vsindexes, p.int(cffOpVSIndex, 0, 0))
+	}
+	return c, nil
+}
+
+// parseCFF2VStore parses the VariationStore at b[offset:].
+func parseCFF2VStore(b []byte, offset int) (*itemVariationStore, error) {
+	// The VariationStore starts with a 16-bit length, which we ignore.
+	offset += 2
+	if offset < 0 || len(b)-offset < 8 {
+		return nil, FormatError("CFF2 VariationStore too short")
+	}
+	return parseItemVariationStore(b[offset:])
+}
+
+// fd returns the index of the Font DICT for the glyph with the given index.
+func (c *cffFont) fd(i Index) (int, error) {
+	if c.fdSelect == nil {
+		return 0, nil
+	}
+	b, fd := c.fdSelect, -1
+	switch b[0] {
+	case 0:
+		if int(i)+1 < len(b) {
+			fd = int(b[1+int(i)])
+		}
+	case 3:
+		if len(b) < 3 {
+			break
+		}
+		n := int(u16(b, 1))
+		if len(b) < 3+3*n+2 {
+			break
+		}
+		for j := 0; j < n; j++ {
+			first, next := Index(u16(b, 3+3*j)), Index(u16(b, 3+3*j+3))
+			if first <= i && i < next {
+				fd = int(b[3+3*j+2])
+				break
+			}
+		}
+	case 4:
+		if len(b) < 5 {
+			break
+		}
+		n := int(u32(b, 1))
+		if n < 0 || (len(b)-9)/6 < n {
+			break
+		}
+		for j := 0; j < n; j++ {
+			first, next := u32(b, 5+6*j), u32(b, 5+6*j+6)
+			if first <= uint32(i) && uint32(i) < next {
+				fd = int(u16(b, 5+6*j+4))
+				break
+			}
+		}
+	default:
+		return 0, UnsupportedError(fmt.Sprintf("CFF FDSelect format: %d", b[0]))
+	}
+	if fd < 0 || len(c.localSubrs) <= fd {
+		return 0, FormatError(fmt.Sprintf("bad CFF FDSelect for glyph %d", i))
+	}
+	return fd, nil
+}
+
+// subrBias returns the bias that is added to a subroutine number, as per
+// section 4.7 of the Type 2 Charstring Format specification.
+func subrBias(x *cffIndex) int32 {
+	switch {
+	case x.count < 1240:
+		return 107
+	case x.count < 33900:
+		return 1131
+	}
+	return 32768
+}
+
+const (
+	// cffMaxStack is the maximum argument stack depth. It is 48 for CFF and
+	// 513 for CFF2.
+	cffMaxStack = 513
+	// cffMaxCallDepth is the maximum subroutine nesting depth.
+	cffMaxCallDepth = 10
+)
+
+// cffDecoder decodes a Type 2 charstring into a GlyphBuf's Points and Ends.
+// Values on the argument stack, and the current point, are 16.16 fixed point
+// numbers of FUnits.
+type cffDecoder struct {
+	c          *cffFont
+	g          *GlyphBuf
+	localSubrs *cffIndex
+	stack      [cffMaxStack]int32
+	top        int
+	transient  [32]int32
+	x, y       int32
+	// nStems is the number of stem hints declared so far, which determines
+	// the length of a hintmask's mask.
+	nStems int
+	// seenWidth is whether the optional width argument, which precedes the
+	// first stack-clearing operator of a CFF (but not CFF2) charstring, has
+	// been consumed. We ignore it and use the hmtx advance width instead.
+	seenWidth bool
+	// np0 is the index in g.Points of the current contour's first point, or
+	// -1 if there is no current contour.
+	np0     int
+	vsindex int
+	// coords are the normalized variation co-ordinates for CFF2 blends. They
+	// are nil for the default instance.
+	coords []float64
+	ended  bool
+}
+
+// load appends the outline of the glyph with the given index to g, in
+// FUnits.
+func (c *cffFont) load(g *GlyphBuf, i Index) error {
+	cs, err := c.charStrings.get(int(i))
+	if err != nil {
+		return err
+	}
+	fd, err := c.fd(i)
+	if err != nil {
+		return err
+	}
+	d := &cffDecoder{
+		c:          c,
+		g:          g,
+		localSubrs: &c.localSubrs[fd],
+		np0:        -1,
+		vsindex:    c.vsindexes[fd],
+	}
+	if g.font != nil {
+		d.coords = g.font.coords
+	}
+	if err := d.run(cs, 0); err != nil {
+		return err
+	}
+	d.closePath()
+	return nil
+}
+
+// cffWidthArgs holds the number of arguments that each stack-clearing
+// operator takes, for detecting a leading width argument. A negative value
+// means that the operator takes an even number of arguments.
+var cffWidthArgs = map[int]int{
+	1:  -1, // hstem
+	3:  -1, // vstem
+	4:  1,  // vmoveto
+	14: -1, // endchar
+	18: -1, // hstemhm
+	19: -1, // hintmask
+	20: -1, // cntrmask
+	21: 2,  // rmoveto
+	22: 1,  // hmoveto
+	23: -1, // vstemhm
+}
+
+func (d *cffDecoder) run(program []byte, depth int) error {
+	if depth > cffMaxCallDepth {
+		return FormatError("CFF subroutine nesting too deep")
+	}
+	for pc := 0; pc < len(program) && !d.ended; {
+		b0 := program[pc]
+		pc++
+
+		// Decode a number.
+		if b0 == 28 || b0 >= 32 {
+			if d.top >= len(d.stack) {
+				return FormatError("CFF stack overflow")
+			}
+			var v int32
+			switch {
+			case b0 == 28:
+				if len(program)-pc < 2 {
+					return FormatError("CFF charstring too short")
+				}
+				v = int32(int16(u16(program, pc))) << 16
+				pc += 2
+			case b0 <= 246:
+				v = (int32(b0) - 139) << 16
+			case b0 <= 250:
+				if pc >= len(program) {
+					return FormatError("CFF charstring too short")
+				}
+				v = (+(int32(b0)-247)*256 + int32(program[pc]) + 108) << 16
+				pc++
+			case b0 <= 254:
+				if pc >= len(program) {
+					return FormatError("CFF charstring too short")
+				}
+				v = (-(int32(b0)-251)*256 - int32(program[pc]) - 108) << 16
+				pc++
+			default:
+				if len(program)-pc < 4 {
+					return FormatError("CFF charstring too short")
+				}
+				v = int32(u32(program, pc))
+				pc += 4
+			}
+			d.stack[d.top] = v
+			d.top++
+			continue
+		}
+
+		op := int(b0)
+		if op == 12 {
+			if pc >= len(program) {
+				return FormatError("CFF charstring too short")
+			}
+			op = 0x0c00 | int(program[pc])
+			pc++
+		}
+		if n, ok := cffWidthArgs[op]; ok && !d.c.cff2 && !d.seenWidth {
+			d.seenWidth = true
+			if (n < 0 && d.top%2 == 1) || (n >= 0 && d.top > n) {
+				d.shift(1)
+			}
+		}
+
+		args := d.stack[:d.top]
+		switch op {
+		case 1, 3, 18, 23: // hstem, vstem, hstemhm, vstemhm.
+			d.nStems += len(args) / 2
+			d.top = 0
+
+		case 19, 20: // hintmask, cntrmask.
+			// Any arguments are implicit vstem hints.
+			d.nStems += len(args) / 2
+			d.top = 0
+			pc += (d.nStems + 7) / 8
+			if pc > len(program) {
+				return FormatError("CFF charstring too short")
+			}
+
+		case 21: // rmoveto.
+			if len(args) < 2 {
+				return FormatError("CFF stack underflow")
+			}
+			d.moveTo(args[0], args[1])
+		case 22: // hmoveto.
+			if len(args) < 1 {
+				return FormatError("CFF stack underflow")
+			}
+			d.moveTo(args[0], 0)
+		case 4: // vmoveto.
+			if len(args) < 1 {
+				return FormatError("CFF stack underflow")
+			}
+			d.moveTo(0, args[0])
+
+		case 5: // rlineto.
+			for ; len(args) >= 2; args = args[2:] {
+				d.lineTo(args[0], args[1])
+			}
+		case 6, 7: // hlineto, vlineto.
+			horizontal := op == 6
+			for ; len(args) >= 1; args = args[1:] {
+				if horizontal {
+					d.lineTo(args[0], 0)
+				} else {
+					d.lineTo(0, args[0])
+				}
+				horizontal = !horizontal
+			}
+
+		case 8: // rrcurveto.
+			for ; len(args) >= 6; args = args[6:] {
+				d.curveTo(args[0], args[1], args[2], args[3], args[4], args[5])
+			}
+		case 24: // rcurveline.
+			for ; len(args) >= 8; args = args[6:] {
+				d.curveTo(args[0], args[1], args[2], args[3], args[4], args[5])
+			}
+			if len(args) >= 2 {
+				d.lineTo(args[0], args[1])
+			}
+		case 25: // rlinecurve.
+			for ; len(args) >= 8; args = args[2:] {
+				d.lineTo(args[0], args[1])
+			}
+			if len(args) >= 6 {
+				d.curveTo(args[0], args[1], args[2], args[3], args[4], args[5])
+			}
+		case 26: // vvcurveto.
+			dx1 := int32(0)
+			if len(args)%2 == 1 {
+				dx1, args = args[0], args[1:]
+			}
+			for ; len(args) >= 4; args = args[4:] {
+				d.curveTo(dx1, args[0], args[1], args[2], 0, args[3])
+				dx1 = 0
+			}
+		case 27: // hhcurveto.
+			dy1 := int32(0)
+			if len(args)%2 == 1 {
+				dy1, args = args[0], args[1:]
+			}
+			for ; len(args) >= 4; args = args[4:] {
+				d.curveTo(args[0], dy1, args[1], args[2], args[3], 0)
+				dy1 = 0
+			}
+		case 30, 31: // vhcurveto, hvcurveto.
+			horizontal := op == 31
+			for ; len(args) >= 4; args = args[4:] {
+				last := int32(0)
+				if len(args) == 5 {
+					last = args[4]
+				}
+				if horizontal {
+					d.curveTo(args[0], 0, args[1], args[2], last, args[3])
+				} else {
+					d.curveTo(0, args[0], args[1], args[2], args[3], last)
+				}
+				horizontal = !horizontal
+			}
+
+		case 0x0c23: // flex.
+			if len(args) < 13 {
+				return FormatError("CFF stack underflow")
+			}
+			d.curveTo(args[0], args[1], args[2], args[3], args[4], args[5])
+			d.curveTo(args[6], args[7], args[8], args[9], args[10], args[11])
+		case 0x0c22: // hflex.
+			if len(args) < 7 {
+				return FormatError("CFF stack underflow")
+			}
+			d.curveTo(args[0], 0, args[1], args[2], args[3], 0)
+			d.curveTo(args[4], 0, args[5], -args[2], args[6], 0)
+		case 0x0c24: // hflex1.
+			if len(args) < 9 {
+				return FormatError("CFF stack underflow")
+			}
+			d.curveTo(args[0], args[1], args[2], args[3], args[4], 0)
+			d.curveTo(args[5], 0, args[6], args[7], args[8], -(args[1] + args[3] + args[7]))
+		case 0x0c25: // flex1.
+			if len(args) < 11 {
+				return FormatError("CFF stack underflow")
+			}
+			dx, dy := int32(0), int32(0)
+			for j := 0; j < 10; j += 2 {
+				dx += args[j]
+				dy += args[j+1]
+			}
+			dx6, dy6 := args[10], args[10]
+			if abs32(dx) > abs32(dy) {
+				dy6 = -dy
+			} else {
+				dx6 = -dx
+			}
+			d.curveTo(args[0], args[1], args[2], args[3], args[4], args[5])
+			d.curveTo(args[6], args[7], args[8], args[9], dx6, dy6)
+
+		case 10, 29: // callsubr, callgsubr.
+			if d.top < 1 {
+				return FormatError("CFF stack underflow")
+			}
+			d.top--
+			subrs := d.localSubrs
+			if op == 29 {
+				subrs = &d.c.globalSubrs
+			}
+			subr, err := subrs.get(int(d.stack[d.top]>>16 + subrBias(subrs)))
+			if err != nil {
+				return err
+			}
+			if err := d.run(subr, depth+1); err != nil {
+				return err
+			}
+			continue
+		case 11: // return.
+			if d.c.cff2 {
+				return FormatError("CFF2 charstring uses return")
+			}
+			return nil
+		case 14: // endchar.
+			if d.c.cff2 {
+				return FormatError("CFF2 charstring uses endchar")
+			}
+			if d.top >= 4 {
+				// The deprecated seac-like accented character form.
+				return UnsupportedError("CFF endchar with accent")
+			}
+			d.ended = true
+
+		case 15: // vsindex.
+			if !d.c.cff2 || d.top < 1 {
+				return FormatError("bad CFF vsindex")
+			}
+			d.top--
+			d.vsindex = int(d.stack[d.top] >> 16)
+			continue
+		case 16: // blend.
+			if err := d.blend(); err != nil {
+				return err
+			}
+			continue
+
+		case 0x0c00: // dotsection.
+			// No-op. This deprecated operator is a hint.
+
+		default:
+			if err := d.arithmetic(op); err != nil {
+				return err
+			}
+			continue
+		}
+		d.top = 0
+	}
+	return nil
+}
+
+// shift removes the bottom n elements of the argument stack.
+func (d *cffDecoder) shift(n int) {
+	copy(d.stack[:], d.stack[n:d.top])
+	d.top -= n
+}
+
+// blend implements the CFF2 blend operator. Each blended value is the default
+// instance's value plus its region deltas, weighted by the region scalars at
+// the decoder's variation co-ordinates.
+func (d *cffDecoder) blend() error {
+	if !d.c.cff2 || d.top < 1 {
+		return FormatError("bad CFF blend")
+	}
+	if d.vsindex < 0 || len(d.c.regionCounts) <= d.vsindex {
+		return FormatError("bad CFF vsindex")
+	}
+	n := int(d.stack[d.top-1] >> 16)
+	k := d.c.regionCounts[d.vsindex]
+	if n < 0 || d.top < n*(k+1)+1 {
+		return FormatError("CFF stack underflow")
+	}
+	base := d.top - 1 - n*(k+1)
+	if len(d.coords) != 0 && d.c.vstore != nil {
+		scalars := d.c.vstore.scalars(d.vsindex, d.coords)
+		for i := 0; i < n; i++ {
+			sum := 0.0
+			for j, s := range scalars {
+				sum += s * float64(d.stack[base+n+i*k+j])
+			}
+			d.stack[base+i] += int32(math.Floor(sum + 0.5))
+		}
+	}
+	d.top = base + n
+	return nil
+}
+
+// arithmetic implements the Type 2 arithmetic, storage and conditional
+// operators, which leave their results on the argument stack.
+func (d *cffDecoder) arithmetic(op int) error {
+	// pops is the number of arguments that each operator takes.
+	pops := 0
+	switch op {
+	case 0x0c05, 0x0c09, 0x0c0e, 0x0c12, 0x0c15, 0x0c1a, 0x0c1b: // not, abs, neg, drop, get, sqrt, dup.
+		pops = 1
+	case 0x0c03, 0x0c04, 0x0c0a, 0x0c0b, 0x0c0c, 0x0c0f, 0x0c14, 0x0c18, 0x0c1c, 0x0c1e: // and, or, add, sub, div, eq, put, mul, exch, roll.
+		pops = 2
+	case 0x0c16: // ifelse.
+		pops = 4
+	case 0x0c1d: // index.
+		pops = 1
+	default:
+		return UnsupportedError(fmt.Sprintf("CFF charstring operator: %d", op))
+	}
+	if d.top < pops {
+		return FormatError("CFF stack underflow")
+	}
+	s := d.stack[:d.top]
+	a := &s[len(s)-pops]
+	switch op {
+	case 0x0c03: // and.
+		*a = bool2int32(s[len(s)-2] != 0 && s[len(s)-1] != 0) << 16
+	case 0x0c04: // or.
+		*a = bool2int32(s[len(s)-2] != 0 || s[len(s)-1] != 0) << 16
+	case 0x0c05: // not.
+		*a = bool2int32(*a == 0) << 16
+	case 0x0c09: // abs.
+		*a = abs32(*a)
+	case 0x0c0a: // add.
+		*a = s[len(s)-2] + s[len(s)-1]
+	case 0x0c0b: // sub.
+		*a = s[len(s)-2] - s[len(s)-1]
+	case 0x0c0c: // div.
+		if s[len(s)-1] == 0 {
+			return FormatError("CFF division by zero")
+		}
+		*a = int32((int64(s[len(s)-2]) << 16) / int64(s[len(s)-1]))
+	case 0x0c0e: // neg.
+		*a = -*a
+	case 0x0c0f: // eq.
+		*a = bool2int32(s[len(s)-2] == s[len(s)-1]) << 16
+	case 0x0c12: // drop.
+		d.top--
+		return nil
+	case 0x0c14: // put.
+		i := int(s[len(s)-1] >> 16)
+		if i < 0 || len(d.transient) <= i {
+			return FormatError("CFF transient array index out of range")
+		}
+		d.transient[i] = s[len(s)-2]
+		d.top -= 2
+		return nil
+	case 0x0c15: // get.
+		i := int(*a >> 16)
+		if i < 0 || len(d.transient) <= i {
+			return FormatError("CFF transient array index out of range")
+		}
+		*a = d.transient[i]
+	case 0x0c16: // ifelse.
+		if s[len(s)-2] > s[len(s)-1] {
+			*a = s[len(s)-3]
+		}
+	case 0x0c18: // mul.
+		*a = int32((int64(s[len(s)-2]) * int64(s[len(s)-1])) >> 16)
+	case 0x0c1a: // sqrt.
+		if *a < 0 {
+			return FormatError("CFF square root of a negative number")
+		}
+		*a = int32(math.Sqrt(float64(*a)/65536) * 65536)
+	case 0x0c1b: // dup.
+		if d.top >= len(d.stack) {
+			return FormatError("CFF stack overflow")
+		}
+		d.stack[d.top] = *a
+		d.top++
+		return nil
+	case 0x0c1c: // exch.
+		s[len(s)-2], s[len(s)-1] = s[len(s)-1], s[len(s)-2]
+		return nil
+	case 0x0c1d: // index.
+		i := int(*a >> 16)
+		if i < 0 {
+			i = 0
+		}
+		if i >= len(s)-1 {
+			return FormatError("CFF stack underflow")
+		}
+		*a = s[len(s)-2-i]
+		return nil
+	case 0x0c1e: // roll.
+		n, j := int(s[len(s)-2]>>16), int(s[len(s)-1]>>16)
+		d.top -= 2
+		if n < 0 || d.top < n {
+			return FormatError("CFF stack underflow")
+		}
+		if n > 0 {
+			r := d.stack[d.top-n : d.top]
+			j = ((j % n) + n) % n
+			tmp := append([]int32(nil), r...)
+			for k := range r {
+				r[(k+j)%n] = tmp[k]
+			}
+		}
+		return nil
+	}
+	d.top -= pops - 1
+	return nil
+}
+
+func abs32(x int32) int32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// cffPoint returns a Point at the given 16.16 fixed point co-ordinates,
+// rounded to the nearest FUnit.
+func cffPoint(x, y int32, flags uint32) Point {
+	return Point{
+		X:     fixed.Int26_6((x + 0x8000) >> 16),
+		Y:     fixed.Int26_6((y + 0x8000) >> 16),
+		Flags: flags,
+	}
+}
+
+func (d *cffDecoder) moveTo(dx, dy int32) {
+	d.closePath()
+	d.x += dx
+	d.y += dy
+	d.np0 = len(d.g.Points)
+	d.g.Points = append(d.g.Points, cffPoint(d.x, d.y, flagOnCurve))
+}
+
+func (d *cffDecoder) lineTo(dx, dy int32) {
+	if d.np0 < 0 {
+		// A path must start with a moveto, but be lenient, like C Freetype.
+		d.moveTo(0, 0)
+	}
+	d.x += dx
+	d.y += dy
+	d.g.Points = append(d.g.Points, cffPoint(d.x, d.y, flagOnCurve))
+}
+
+func (d *cffDecoder) curveTo(dxa, dya, dxb, dyb, dxc, dyc int32) {
+	if d.np0 < 0 {
+		d.moveTo(0, 0)
+	}
+	xa, ya := d.x+dxa, d.y+dya
+	xb, yb := xa+dxb, ya+dyb
+	d.x, d.y = xb+dxc, yb+dyc
+	d.g.Points = append(d.g.Points,
+		cffPoint(xa, ya, FlagCubic),
+		cffPoint(xb, yb, FlagCubic),
+		cffPoint(d.x, d.y, flagOnCurve),
+	)
+}
+
+// closePath ends the current contour, if any. TrueType contours are
+// implicitly closed, so a final on-curve point that coincides with the
+// contour's first point is dropped, as is a contour consisting only of its
+// first point.
+func (d *cffDecoder) closePath() {
+	if d.np0 < 0 {
+		return
+	}
+	ps := d.g.Points
+	if n := len(ps) - d.np0; n > 1 {
+		first, last := ps[d.np0], ps[len(ps)-1]
+		if last.X == first.X && last.Y == first.Y {
+			ps = ps[:len(ps)-1]
+		}
+	}
+	if len(ps)-d.np0 <= 1 {
+		ps = ps[:d.np0]
+	} else {
+		d.g.Ends = append(d.g.Ends, len(ps))
+	}
+	d.g.Points = ps
+	d.np0 = -1
+}
@@ -0,0 +1,268 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+import (
+	"fmt"
+	"image"
+	"io/ioutil"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// cffTestIndex returns a CFF INDEX holding the given objects.
+func cffTestIndex(objects ...[]byte) []byte {
+	b := []byte{byte(len(objects) >> 8), byte(len(objects))}
+	if len(objects) == 0 {
+		return b
+	}
+	b = append(b, 4)
+	o := 1
+	b = append(b, byte(o>>24), byte(o>>16), byte(o>>8), byte(o))
+	for _, obj := range objects {
+		o += len(obj)
+		b = append(b, byte(o>>24), byte(o>>16), byte(o>>8), byte(o))
+	}
+	for _, obj := range objects {
+		b = append(b, obj...)
+	}
+	return b
+}
+
+// cffTestInt returns the 5-byte DICT encoding of v.
+func cffTestInt(v int) []byte {
+	return []byte{29, byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+}
+
+// cffTestCharstring returns a Type 2 charstring. Each int element is encoded
+// as a number and each byte element is an operator.
+func cffTestCharstring(elems ...interface{}) []byte {
+	var b []byte
+	for _, e := range elems {
+		switch e := e.(type) {
+		case int:
+			b = append(b, 28, byte(e>>8), byte(e))
+		case byte:
+			b = append(b, e)
+		case []byte:
+			b = append(b, e...)
+		}
+	}
+	return b
+}
+
+const (
+	t2rmoveto   = byte(21)
+	t2rlineto   = byte(5)
+	t2rrcurveto = byte(8)
+	t2hvcurveto = byte(31)
+	t2callsubr  = byte(10)
+	t2callgsubr = byte(29)
+	t2return    = byte(11)
+	t2endchar   = byte(14)
+	t2hstemhm   = byte(18)
+	t2hintmask  = byte(19)
+	t2blend     = byte(16)
+)
+
+// makeTestCFF returns a CFF table with nGlyph charstrings. Glyphs not in
+// glyphs are empty.
+func makeTestCFF(nGlyph int, glyphs map[int][]byte, gsubrs, subrs [][]byte) []byte {
+	charStrings := make([][]byte, nGlyph)
+	for i := range charStrings {
+		charStrings[i] = []byte{t2endchar}
+		if g, ok := glyphs[i]; ok {
+			charStrings[i] = g
+		}
+	}
+	csIndex := cffTestIndex(charStrings...)
+	private := append(cffTestInt(6), cffOpSubrs)
+
+	// The Top DICT has a fixed size, as every operand takes 5 bytes.
+	const topDictLen = 17
+	header := []byte{1, 0, 4, 4}
+	nameIndex := cffTestIndex([]byte("Test"))
+	stringIndex := cffTestIndex()
+	gsubrIndex := cffTestIndex(gsubrs...)
+	csOffset := len(header) + len(nameIndex) + len(cffTestIndex(make([]byte, topDictLen))) +
+		len(stringIndex) + len(gsubrIndex)
+	privateOffset := csOffset + len(csIndex)
+
+	topDict := cffTestInt(csOffset)
+	topDict = append(topDict, cffOpCharStrings)
+	topDict = append(topDict, cffTestInt(len(private))...)
+	topDict = append(topDict, cffTestInt(privateOffset)...)
+	topDict = append(topDict, cffOpPrivate)
+	if len(topDict) != topDictLen {
+		panic("bad Top DICT length")
+	}
+
+	var b []byte
+	b = append(b, header...)
+	b = append(b, nameIndex...)
+	b = append(b, cffTestIndex(topDict)...)
+	b = append(b, stringIndex...)
+	b = append(b, gsubrIndex...)
+	b = append(b, csIndex...)
+	b = append(b, private...)
+	b = append(b, cffTestIndex(subrs...)...)
+	return b
+}
+
+// makeTestOTF returns an OpenType font with CFF outlines, whose metrics and
+// cmap are those of luxisr.ttf.
+func makeTestOTF(glyphs map[int][]byte, gsubrs, subrs [][]byte) ([]byte, error) {
+	ttf, err := ioutil.ReadFile("../testdata/luxisr.ttf")
+	if err != nil {
+		return nil, err
+	}
+	f, err := Parse(ttf)
+	if err != nil {
+		return nil, err
+	}
+	maxp := []byte{0x00, 0x00, 0x50, 0x00, byte(f.nGlyph >> 8), byte(f.nGlyph)}
+	return makeSFNT(0x4f54544f, map[string][]byte{
+		"CFF ": makeTestCFF(f.nGlyph, glyphs, gsubrs, subrs),
+		"cmap": f.cmap,
+		"head": f.head,
+		"hhea": f.hhea,
+		"hmtx": f.hmtx,
+		"maxp": maxp,
+		"name": f.name,
+	}), nil
+}
+
+func TestCFF(t *testing.T) {
+	const (
+		indexA = 36
+		indexV = 57
+	)
+	glyphs := map[int][]byte{
+		// A triangle, with a leading width argument.
+		indexA: cffTestCharstring(
+			500, 100, 100, t2rmoveto,
+			200, 0, t2rlineto,
+			-100, 200, t2rlineto,
+			t2endchar,
+		),
+		// Cubic curves, with hints and subroutine calls.
+		indexV: cffTestCharstring(
+			0, 10, 50, 10, t2hstemhm,
+			t2hintmask, byte(0xc0),
+			-107, t2callsubr,
+			-107, t2callgsubr,
+			50, 50, -50, -150, t2hvcurveto,
+			t2endchar,
+		),
+	}
+	gsubrs := [][]byte{
+		cffTestCharstring(0, 100, 100, 100, 100, 0, t2rrcurveto, t2return),
+	}
+	subrs := [][]byte{
+		cffTestCharstring(100, 0, t2rmoveto, t2return),
+	}
+	otf, err := makeTestOTF(glyphs, gsubrs, subrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := Parse(otf)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if f.cffFont == nil {
+		t.Fatal("Parse: no CFF outlines")
+	}
+
+	testCases := []struct {
+		r      rune
+		points []Point
+		ends   []int
+	}{
+		{'A', []Point{
+			{X: 100, Y: 100, Flags: flagOnCurve},
+			{X: 300, Y: 100, Flags: flagOnCurve},
+			{X: 200, Y: 300, Flags: flagOnCurve},
+		}, []int{3}},
+		{'V', []Point{
+			{X: 100, Y: 0, Flags: flagOnCurve},
+			{X: 100, Y: 100, Flags: FlagCubic},
+			{X: 200, Y: 200, Flags: FlagCubic},
+			{X: 300, Y: 200, Flags: flagOnCurve},
+			{X: 350, Y: 200, Flags: FlagCubic},
+			{X: 400, Y: 150, Flags: FlagCubic},
+			{X: 400, Y: 0, Flags: flagOnCurve},
+		}, []int{7}},
+		{' ', nil, nil},
+	}
+	fupe := fixed.Int26_6(f.FUnitsPerEm())
+	g := &GlyphBuf{}
+	for _, tc := range testCases {
+		i := f.Index(tc.r)
+		if err := g.Load(f, fupe, i, font.HintingNone); err != nil {
+			t.Errorf("%q: Load: %v", tc.r, err)
+			continue
+		}
+		// Load shifts the points so that the left side bearing is that of
+		// the hmtx table.
+		dx := fixed.Int26_6(0)
+		if len(tc.points) != 0 {
+			dx = tc.points[0].X - f.HMetric(fupe, i).LeftSideBearing
+		}
+		want := make([]Point, len(tc.points))
+		for j, p := range tc.points {
+			want[j] = Point{X: p.X - dx, Y: p.Y, Flags: p.Flags}
+		}
+		if got, want := fmt.Sprint(g.Points), fmt.Sprint(want); got != want {
+			t.Errorf("%q: Points:\ngot  %v\nwant %v", tc.r, got, want)
+		}
+		if got, want := fmt.Sprint(g.Ends), fmt.Sprint(tc.ends); got != want {
+			t.Errorf("%q: Ends: got %v, want %v", tc.r, got, want)
+		}
+		if got, want := g.AdvanceWidth, f.HMetric(fupe, i).AdvanceWidth; got != want {
+			t.Errorf("%q: AdvanceWidth: got %v, want %v", tc.r, got, want)
+		}
+	}
+
+	// Check that the cubic outline rasterizes.
+	face := NewFace(f, &Options{Size: 64})
+	dr, mask, maskp, _, ok := face.Glyph(fixed.P(0, 64), 'V')
+	if !ok {
+		t.Fatal("Glyph: not ok")
+	}
+	painted := false
+	for y := 0; y < dr.Dy() && !painted; y++ {
+		for x := 0; x < dr.Dx(); x++ {
+			if _, _, _, a := mask.At(maskp.X+x, maskp.Y+y).RGBA(); a != 0 {
+				painted = true
+				break
+			}
+		}
+	}
+	if dr == (image.Rectangle{}) || !painted {
+		t.Errorf("Glyph: no pixels painted in %v", dr)
+	}
+}
+
+func TestCFF2Blend(t *testing.T) {
+	c := &cffFont{
+		cff2:         true,
+		localSubrs:   []cffIndex{{}},
+		vsindexes:    []int{0},
+		regionCounts: []int{2},
+	}
+	d := &cffDecoder{c: c, g: &GlyphBuf{}, localSubrs: &c.localSubrs[0], np0: -1}
+	// Two default values, then two deltas for each of them, then n = 2.
+	cs := cffTestCharstring(100, 200, 10, 20, 30, 40, 2, t2blend, t2rmoveto)
+	if err := d.run(cs, 0); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	want := []Point{{X: 100, Y: 200, Flags: flagOnCurve}}
+	if got := d.g.Points; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
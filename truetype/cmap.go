@@ -0,0 +1,294 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+import (
+	"sort"
+)
+
+// How runes map to the codes of a cmap subtable.
+const (
+	cmapEncodingUnicode = iota
+	// cmapEncodingMacRoman subtables are indexed by Mac OS Roman codes.
+	cmapEncodingMacRoman
+	// cmapEncodingSymbol subtables are indexed by Unicode, but map a
+	// symbol font's glyphs from U+F020 to U+F0FF.
+	cmapEncodingSymbol
+)
+
+// A CmapSubtable describes one of a font's cmap subtables, which each map
+// the character codes of an encoding to glyphs. The encodings are listed at
+// https://www.microsoft.com/typography/otspec/name.htm
+type CmapSubtable struct {
+	// PlatformID and EncodingID identify the encoding, such as 3 and 1
+	// for Microsoft Unicode BMP, or 1 and 0 for Mac OS Roman.
+	PlatformID, EncodingID uint16
+	// Language is the Macintosh language code plus one, or 0 if the
+	// subtable is language independent.
+	Language uint32
+	// Format is the subtable format, such as 4 or 12.
+	Format uint16
+}
+
+// CmapSubtables returns the font's cmap subtables, in the order that they
+// appear in the cmap table.
+func (f *Font) CmapSubtables() []CmapSubtable {
+	if len(f.cmap) < 4 {
+		return nil
+	}
+	n := int(u16(f.cmap, 2))
+	ret := make([]CmapSubtable, 0, n)
+	for i := 0; i < n && len(f.cmap) >= 12+8*i; i++ {
+		x := 4 + 8*i
+		s := CmapSubtable{
+			PlatformID: u16(f.cmap, x),
+			EncodingID: u16(f.cmap, x+2),
+		}
+		if o := int(u32(f.cmap, x+4)); o > 0 && len(f.cmap) >= o+12 {
+			s.Format = u16(f.cmap, o)
+			switch s.Format {
+			case 0, 2, 4, 6:
+				s.Language = uint32(u16(f.cmap, o+4))
+			case 8, 10, 12, 13:
+				s.Language = u32(f.cmap, o+8)
+			}
+		}
+		ret = append(ret, s)
+	}
+	return ret
+}
+
+// SelectCmap returns a copy of the font that maps runes to glyphs by its
+// i'th cmap subtable, as listed by CmapSubtables, instead of by the
+// subtable that Parse selected. Runes are converted to the subtable's
+// encoding: Mac OS Roman subtables map the Mac OS Roman repertoire, and
+// Microsoft Symbol subtables also map the runes U+0020 to U+00FF to their
+// glyphs at U+F020 to U+F0FF.
+//
+// The other encodings that are supported are the Unicode platform and the
+// Microsoft Unicode BMP and full repertoire encodings.
+func (f *Font) SelectCmap(i int) (*Font, error) {
+	if len(f.cmap) < 4 || i < 0 || int(u16(f.cmap, 2)) <= i || len(f.cmap) < 12+8*i {
+		return nil, FormatError("bad cmap subtable index")
+	}
+	g := *f
+	if err := g.loadCmap(4 + 8*i); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// findCmap returns the offset of the first cmap encoding record with the
+// given platform and encoding IDs, or -1.
+func (f *Font) findCmap(pidPsid uint32) int {
+	if len(f.cmap) < 4 {
+		return -1
+	}
+	n := int(u16(f.cmap, 2))
+	for i := 0; i < n && len(f.cmap) >= 12+8*i; i++ {
+		if u32(f.cmap, 4+8*i) == pidPsid {
+			return 4 + 8*i
+		}
+	}
+	return -1
+}
+
+// macRoman maps the Mac OS Roman codes from 0x80 to 0xff to runes.
+var macRoman = [128]rune{
+	0x00c4, 0x00c5, 0x00c7, 0x00c9, 0x00d1, 0x00d6, 0x00dc, 0x00e1,
+	0x00e0, 0x00e2, 0x00e4, 0x00e3, 0x00e5, 0x00e7, 0x00e9, 0x00e8,
+	0x00ea, 0x00eb, 0x00ed, 0x00ec, 0x00ee, 0x00ef, 0x00f1, 0x00f3,
+	0x00f2, 0x00f4, 0x00f6, 0x00f5, 0x00fa, 0x00f9, 0x00fb, 0x00fc,
+	0x2020, 0x00b0, 0x00a2, 0x00a3, 0x00a7, 0x2022, 0x00b6, 0x00df,
+	0x00ae, 0x00a9, 0x2122, 0x00b4, 0x00a8, 0x2260, 0x00c6, 0x00d8,
+	0x221e, 0x00b1, 0x2264, 0x2265, 0x00a5, 0x00b5, 0x2202, 0x2211,
+	0x220f, 0x03c0, 0x222b, 0x00aa, 0x00ba, 0x03a9, 0x00e6, 0x00f8,
+	0x00bf, 0x00a1, 0x00ac, 0x221a, 0x0192, 0x2248, 0x2206, 0x00ab,
+	0x00bb, 0x2026, 0x00a0, 0x00c0, 0x00c3, 0x00d5, 0x0152, 0x0153,
+	0x2013, 0x2014, 0x201c, 0x201d, 0x2018, 0x2019, 0x00f7, 0x25ca,
+	0x00ff, 0x0178, 0x2044, 0x20ac, 0x2039, 0x203a, 0xfb01, 0xfb02,
+	0x2021, 0x00b7, 0x201a, 0x201e, 0x2030, 0x00c2, 0x00ca, 0x00c1,
+	0x00cb, 0x00c8, 0x00cd, 0x00ce, 0x00cf, 0x00cc, 0x00d3, 0x00d4,
+	0xf8ff, 0x00d2, 0x00da, 0x00db, 0x00d9, 0x0131, 0x02c6, 0x02dc,
+	0x00af, 0x02d8, 0x02d9, 0x02da, 0x00b8, 0x02dd, 0x02db, 0x02c7,
+}
+
+// macRomanCodes is the inverse of macRoman.
+var macRomanCodes = func() map[rune]uint32 {
+	m := make(map[rune]uint32, len(macRoman))
+	for i, r := range macRoman {
+		m[r] = 0x80 + uint32(i)
+	}
+	return m
+}()
+
+// macRomanCode returns the Mac OS Roman code of the rune.
+func macRomanCode(r rune) (uint32, bool) {
+	if 0 <= r && r < 0x80 {
+		return uint32(r), true
+	}
+	c, ok := macRomanCodes[r]
+	return c, ok
+}
+
+// A RuneRange is an inclusive range of runes, from Lo to Hi.
+type RuneRange struct {
+	Lo, Hi rune
+}
+
+// Coverage returns the runes that the font maps to a glyph, other than the
+// missing glyph 0, as sorted, non-overlapping and non-adjacent ranges.
+func (f *Font) Coverage() []RuneRange {
+	var ret []RuneRange
+	for h := range f.cm {
+		cm := &f.cm[h]
+		for c := cm.start; c <= cm.end; {
+			// Find the next run of codes that map to non-zero glyphs.
+			for c <= cm.end && f.cmIndex(h, c) == 0 {
+				c = f.nextCode(h, c)
+			}
+			if c > cm.end || c < cm.start {
+				break
+			}
+			lo := c
+			for c <= cm.end && c >= lo && f.cmIndex(h, c) != 0 {
+				c = f.nextCode(h, c)
+			}
+			ret = f.appendCodeRange(ret, lo, c-1)
+			if c < lo {
+				break
+			}
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Lo < ret[j].Lo })
+	// Merge overlapping and adjacent ranges.
+	n := 0
+	for _, r := range ret {
+		if n > 0 && r.Lo <= ret[n-1].Hi+1 {
+			if r.Hi > ret[n-1].Hi {
+				ret[n-1].Hi = r.Hi
+			}
+			continue
+		}
+		ret[n] = r
+		n++
+	}
+	return ret[:n]
+}
+
+// nextCode returns the code after c that might map to a different glyph
+// than c does, in the h'th cm entry, or end+1. Within a constant entry, or a
+// delta entry away from the code that maps to glyph 0, every code maps
+// alike, so Coverage need not visit each code of a large range.
+func (f *Font) nextCode(h int, c uint32) uint32 {
+	cm := &f.cm[h]
+	switch {
+	case cm.constant:
+		return cm.end + 1
+	case cm.offset == 0:
+		// The code that maps to glyph 0 is the next code z that satisfies
+		// uint16(z + delta) == 0, which is either c itself or past it.
+		if Index(c+cm.delta) == 0 {
+			return c + 1
+		}
+		z := c + (0x10000 - (c+cm.delta)&0xffff)
+		if z < c || z > cm.end {
+			return cm.end + 1
+		}
+		return z
+	}
+	return c + 1
+}
+
+// appendCodeRange appends the runes of the cmap codes from lo to hi.
+func (f *Font) appendCodeRange(ret []RuneRange, lo, hi uint32) []RuneRange {
+	switch f.cmapEncoding {
+	case cmapEncodingMacRoman:
+		for c := lo; c <= hi && c < 0x100; c++ {
+			r := macRomanRune(c)
+			ret = append(ret, RuneRange{r, r})
+		}
+		return ret
+	case cmapEncodingSymbol:
+		// The codes U+F020 to U+F0FF also map the runes U+0020 to U+00FF.
+		if sLo, sHi := max32(lo, 0xf020), min32(hi, 0xf0ff); sLo <= sHi {
+			ret = append(ret, RuneRange{rune(sLo - 0xf000), rune(sHi - 0xf000)})
+		}
+	}
+	return append(ret, RuneRange{rune(lo), rune(hi)})
+}
+
+// Runes returns the runes that the font maps to the glyph, in increasing
+// order. It returns nil for the missing glyph 0.
+func (f *Font) Runes(i Index) []rune {
+	if i == 0 {
+		return nil
+	}
+	var ret []rune
+	add := func(c uint32) {
+		switch f.cmapEncoding {
+		case cmapEncodingMacRoman:
+			if c < 0x100 {
+				ret = append(ret, macRomanRune(c))
+			}
+			return
+		case cmapEncodingSymbol:
+			if 0xf020 <= c && c <= 0xf0ff && f.lookup(c-0xf000) == 0 {
+				ret = append(ret, rune(c-0xf000))
+			}
+		}
+		ret = append(ret, rune(c))
+	}
+	for h := range f.cm {
+		cm := &f.cm[h]
+		switch {
+		case cm.constant:
+			if Index(cm.delta) == i {
+				for c := cm.start; c <= cm.end && c >= cm.start; c++ {
+					add(c)
+				}
+			}
+		case cm.offset == 0:
+			// The codes that map to i are those congruent to i - delta,
+			// modulo 0x10000.
+			c := cm.start + (uint32(i)-cm.delta-cm.start)&0xffff
+			for ; c <= cm.end && c >= cm.start; c += 0x10000 {
+				add(c)
+			}
+		default:
+			for c := cm.start; c <= cm.end && c >= cm.start; c++ {
+				if f.cmIndex(h, c) == i {
+					add(c)
+				}
+			}
+		}
+	}
+	sort.Slice(ret, func(a, b int) bool { return ret[a] < ret[b] })
+	return ret
+}
+
+// macRomanRune returns the rune of the Mac OS Roman code c, which is less
+// than 0x100.
+func macRomanRune(c uint32) rune {
+	if c < 0x80 {
+		return rune(c)
+	}
+	return macRoman[c-0x80]
+}
+
+func min32(a, b uint32) uint32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b uint32) uint32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,1014 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/golang/freetype/raster"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// colrTable holds the offsets, in the COLR table, of its glyph and layer
+// lists. The version 0 lists are of layers, and the version 1 lists are of
+// paint graphs, which take precedence.
+type colrTable struct {
+	// baseGlyphs and layers are the offsets of the version 0 base glyph and
+	// layer records, and nBaseGlyphs and nLayers are their numbers.
+	baseGlyphs, nBaseGlyphs, layers, nLayers int
+	// baseGlyphList, layerList and clipList are the offsets of the version
+	// 1 lists, or 0 if there are none.
+	baseGlyphList, layerList, clipList int
+}
+
+// maxPaintDepth is the maximum nesting of a COLR paint graph, and maxPaints
+// is the maximum number of paint tables drawn for one glyph. A paint table
+// can be reached along many paths, so bounding the depth alone does not
+// bound the work.
+const (
+	maxPaintDepth = 64
+	maxPaints     = 2048
+)
+
+// paintSizes are the sizes, in bytes, of the non-variable paint formats.
+var paintSizes = [...]int{
+	1: 6, 2: 5, 4: 16, 6: 16, 8: 12, 10: 6, 11: 3, 12: 7, 14: 8, 16: 8,
+	18: 12, 20: 6, 22: 10, 24: 6, 26: 10, 28: 8, 30: 12, 32: 8,
+}
+
+// parseColor parses the COLR and CPAL tables. The glyphs' outlines can be
+// drawn instead of their color layers, so if either table is malformed then
+// both are ignored rather than failing the whole font.
+func (f *Font) parseColor() {
+	err := f.parseColr()
+	if err == nil {
+		err = f.parseCpal()
+	}
+	if err != nil {
+		f.colrTable, f.cpalPalettes = nil, nil
+	}
+}
+
+func (f *Font) parseColr() error {
+	f.colrTable = nil
+	if len(f.colr) == 0 {
+		return nil
+	}
+	if len(f.colr) < 14 {
+		return FormatError("COLR data too short")
+	}
+	version := u16(f.colr, 0)
+	if version > 1 {
+		return UnsupportedError(fmt.Sprintf("COLR version: %d", version))
+	}
+	t := &colrTable{
+		nBaseGlyphs: int(u16(f.colr, 2)),
+		baseGlyphs:  int(u32(f.colr, 4)),
+		layers:      int(u32(f.colr, 8)),
+		nLayers:     int(u16(f.colr, 12)),
+	}
+	if t.nBaseGlyphs != 0 && len(f.colr) < t.baseGlyphs+6*t.nBaseGlyphs {
+		return FormatError("bad COLR base glyph records")
+	}
+	if t.nLayers != 0 && len(f.colr) < t.layers+4*t.nLayers {
+		return FormatError("bad COLR layer records")
+	}
+	if version == 1 {
+		if len(f.colr) < 34 {
+			return FormatError("COLR data too short")
+		}
+		t.baseGlyphList = int(u32(f.colr, 14))
+		t.layerList = int(u32(f.colr, 18))
+		t.clipList = int(u32(f.colr, 22))
+		for _, l := range [...]struct{ offset, size int }{
+			{t.baseGlyphList, 6},
+			{t.layerList, 4},
+		} {
+			if l.offset == 0 {
+				continue
+			}
+			if len(f.colr) < l.offset+4 {
+				return FormatError("bad COLR list offset")
+			}
+			if n := int(u32(f.colr, l.offset)); n > (len(f.colr)-l.offset-4)/l.size {
+				return FormatError("bad COLR list length")
+			}
+		}
+		if t.clipList != 0 {
+			if len(f.colr) < t.clipList+5 {
+				return FormatError("bad COLR clip list offset")
+			}
+			if n := int(u32(f.colr, t.clipList+1)); n > (len(f.colr)-t.clipList-5)/7 {
+				return FormatError("bad COLR clip list length")
+			}
+		}
+	}
+	f.colrTable = t
+	return nil
+}
+
+func (f *Font) parseCpal() error {
+	f.cpalPalettes = nil
+	if len(f.cpal) == 0 {
+		return nil
+	}
+	if len(f.cpal) < 12 {
+		return FormatError("CPAL data too short")
+	}
+	nEntries, nPalettes := int(u16(f.cpal, 2)), int(u16(f.cpal, 4))
+	nRecords, records := int(u16(f.cpal, 6)), int(u32(f.cpal, 8))
+	if len(f.cpal) < 12+2*nPalettes || len(f.cpal) < records+4*nRecords {
+		return FormatError("CPAL data too short")
+	}
+	f.cpalPalettes = make([][]color.NRGBA, nPalettes)
+	for i := range f.cpalPalettes {
+		first := int(u16(f.cpal, 12+2*i))
+		if nRecords < first+nEntries {
+			return FormatError("bad CPAL color record index")
+		}
+		p := make([]color.NRGBA, nEntries)
+		for j := range p {
+			// The records are in BGRA order.
+			x := records + 4*(first+j)
+			p[j] = color.NRGBA{f.cpal[x+2], f.cpal[x+1], f.cpal[x], f.cpal[x+3]}
+		}
+		f.cpalPalettes[i] = p
+	}
+	return nil
+}
+
+// Palettes returns the colors of the font's CPAL palettes, which the COLR
+// table's color glyphs are drawn with.
+func (f *Font) Palettes() [][]color.NRGBA {
+	ret := make([][]color.NRGBA, len(f.cpalPalettes))
+	for i, p := range f.cpalPalettes {
+		ret[i] = append([]color.NRGBA(nil), p...)
+	}
+	return ret
+}
+
+// HasColorGlyph returns whether the COLR table has layers, or a paint graph,
+// for the glyph.
+func (f *Font) HasColorGlyph(i Index) bool {
+	if f.colrTable == nil {
+		return false
+	}
+	if _, ok := f.colrPaint(i); ok {
+		return true
+	}
+	_, _, ok := f.colrLayers(i)
+	return ok
+}
+
+// ColorGlyph draws the glyph's COLR layers or paint graph, at the given scale
+// in 26.6 fixed point units per em, with the colors of the given CPAL
+// palette. Palette 0 is used if the font has no such palette. Colors that the
+// font leaves to the text, such as those of icons that should match the
+// text around them, are the foreground color, or opaque black if it is nil.
+//
+// The Bitmap's Image is an *image.RGBA. It returns a nil Bitmap and a nil
+// error if the font has no color layers for the glyph.
+//
+// COLR version 1 paint graphs of variable fonts are drawn without their
+// variation deltas.
+func (f *Font) ColorGlyph(scale fixed.Int26_6, i Index, palette int, foreground color.Color) (*Bitmap, error) {
+	if f.colrTable == nil {
+		return nil, nil
+	}
+	paint, isPaint := f.colrPaint(i)
+	first, n, isLayers := f.colrLayers(i)
+	if !isPaint && !isLayers {
+		return nil, nil
+	}
+
+	c := &colorRenderer{f: f, path: map[int]bool{}}
+	if palette < 0 || len(f.cpalPalettes) <= palette {
+		palette = 0
+	}
+	if palette < len(f.cpalPalettes) {
+		c.palette = f.cpalPalettes[palette]
+	}
+	if foreground == nil {
+		foreground = color.Black
+	}
+	r, g, b, a := foreground.RGBA()
+	c.foreground = [4]float32{float32(r) / 0xffff, float32(g) / 0xffff, float32(b) / 0xffff, float32(a) / 0xffff}
+
+	// Find the glyph's bounds, in FUnits. A version 1 glyph's clip box
+	// bounds it. Otherwise, a version 0 glyph is bounded by its layers'
+	// outlines, and a version 1 glyph by the font's bounding box.
+	var xMin, yMin, xMax, yMax float64
+	if box, ok := f.colrClipBox(i); isPaint && ok {
+		xMin, yMin, xMax, yMax = box[0], box[1], box[2], box[3]
+	} else if isPaint {
+		xMin, yMin = float64(f.bounds.Min.X), float64(f.bounds.Min.Y)
+		xMax, yMax = float64(f.bounds.Max.X), float64(f.bounds.Max.Y)
+	} else {
+		xMin, yMin, xMax, yMax = math.Inf(+1), math.Inf(+1), math.Inf(-1), math.Inf(-1)
+		for j := 0; j < n; j++ {
+			x := f.colrTable.layers + 4*(first+j)
+			if err := c.g.Load(f, fixed.Int26_6(f.fUnitsPerEm), Index(u16(f.colr, x)), font.HintingNone); err != nil {
+				return nil, err
+			}
+			if len(c.g.Points) == 0 {
+				continue
+			}
+			xMin = math.Min(xMin, float64(c.g.Bounds.Min.X))
+			yMin = math.Min(yMin, float64(c.g.Bounds.Min.Y))
+			xMax = math.Max(xMax, float64(c.g.Bounds.Max.X))
+			yMax = math.Max(yMax, float64(c.g.Bounds.Max.Y))
+		}
+	}
+	bm := &Bitmap{
+		Advance: int((f.HMetric(scale, i).AdvanceWidth + 32) >> 6),
+		PPEM:    int((scale + 32) >> 6),
+	}
+	if xMin >= xMax || yMin >= yMax {
+		bm.Image = image.NewRGBA(image.Rectangle{})
+		return bm, nil
+	}
+
+	// The transform from FUnits to the image's pixels, whose Y axis points
+	// down, puts the glyph's bounds at the image's edges.
+	s := float64(scale) / 64 / float64(f.fUnitsPerEm)
+	left, right := math.Floor(xMin*s), math.Ceil(xMax*s)
+	bottom, top := math.Floor(yMin*s), math.Ceil(yMax*s)
+	c.w, c.h = int(right-left), int(top-bottom)
+	c.r = raster.NewRasterizer(c.w, c.h)
+	m := affine{xx: s, yy: -s, dx: -left, dy: top}
+	bm.Left, bm.Top = int(left), int(top)
+
+	var (
+		l   layer
+		err error
+	)
+	if isPaint {
+		l, err = c.paint(paint, m)
+	} else {
+		l, err = c.layers(first, n, m)
+	}
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, c.w, c.h))
+	for j, v := range l {
+		img.Pix[j] = uint8(math.Min(1, math.Max(0, float64(v)))*0xff + 0.5)
+	}
+	bm.Image = img
+	return bm, nil
+}
+
+// colrPaint returns the offset of the glyph's version 1 paint graph.
+func (f *Font) colrPaint(i Index) (int, bool) {
+	t := f.colrTable
+	if t.baseGlyphList == 0 {
+		return 0, false
+	}
+	n := int(u32(f.colr, t.baseGlyphList))
+	x := t.baseGlyphList + 4
+	k := sort.Search(n, func(k int) bool { return Index(u16(f.colr, x+6*k)) >= i })
+	if k == n || Index(u16(f.colr, x+6*k)) != i {
+		return 0, false
+	}
+	return t.baseGlyphList + int(u32(f.colr, x+6*k+2)), true
+}
+
+// colrLayers returns the index of the glyph's first version 0 layer record
+// and the number of its layers.
+func (f *Font) colrLayers(i Index) (first, n int, ok bool) {
+	t := f.colrTable
+	x := t.baseGlyphs
+	k := sort.Search(t.nBaseGlyphs, func(k int) bool { return Index(u16(f.colr, x+6*k)) >= i })
+	if k == t.nBaseGlyphs || Index(u16(f.colr, x+6*k)) != i {
+		return 0, 0, false
+	}
+	first, n = int(u16(f.colr, x+6*k+2)), int(u16(f.colr, x+6*k+4))
+	if t.nLayers < first+n {
+		return 0, 0, false
+	}
+	return first, n, true
+}
+
+// colrClipBox returns the glyph's version 1 clip box, of its minimum and
+// maximum X and Y in FUnits.
+func (f *Font) colrClipBox(i Index) (box [4]float64, ok bool) {
+	t := f.colrTable
+	if t.clipList == 0 {
+		return box, false
+	}
+	n := int(u32(f.colr, t.clipList+1))
+	for k := 0; k < n; k++ {
+		x := t.clipList + 5 + 7*k
+		if i < Index(u16(f.colr, x)) || Index(u16(f.colr, x+2)) < i {
+			continue
+		}
+		y := t.clipList + int(u24(f.colr, x+4))
+		if len(f.colr) < y+9 {
+			return box, false
+		}
+		for j := range box {
+			box[j] = float64(int16(u16(f.colr, y+1+2*j)))
+		}
+		return box, true
+	}
+	return box, false
+}
+
+// affine is the transform from (x, y) to (xx*x + xy*y + dx, yx*x + yy*y + dy).
+type affine struct {
+	xx, yx, xy, yy, dx, dy float64
+}
+
+// mul returns the transform that applies b and then a.
+func (a affine) mul(b affine) affine {
+	return affine{
+		xx: a.xx*b.xx + a.xy*b.yx,
+		yx: a.yx*b.xx + a.yy*b.yx,
+		xy: a.xx*b.xy + a.xy*b.yy,
+		yy: a.yx*b.xy + a.yy*b.yy,
+		dx: a.xx*b.dx + a.xy*b.dy + a.dx,
+		dy: a.yx*b.dx + a.yy*b.dy + a.dy,
+	}
+}
+
+func (a affine) apply(x, y float64) (float64, float64) {
+	return a.xx*x + a.xy*y + a.dx, a.yx*x + a.yy*y + a.dy
+}
+
+// invert returns the inverse transform, or false if a is singular.
+func (a affine) invert() (affine, bool) {
+	det := a.xx*a.yy - a.xy*a.yx
+	if det == 0 {
+		return affine{}, false
+	}
+	b := affine{
+		xx: +a.yy / det,
+		yx: -a.yx / det,
+		xy: -a.xy / det,
+		yy: +a.xx / det,
+	}
+	b.dx = -(b.xx*a.dx + b.xy*a.dy)
+	b.dy = -(b.yx*a.dx + b.yy*a.dy)
+	return b, true
+}
+
+// around returns the transform that applies a about the center (cx, cy).
+func (a affine) around(cx, cy float64) affine {
+	return affine{xx: 1, yy: 1, dx: cx, dy: cy}.mul(a).mul(affine{xx: 1, yy: 1, dx: -cx, dy: -cy})
+}
+
+// A layer is an image of premultiplied colors, with four channels of red,
+// green, blue and alpha per pixel, each in the range [0, 1].
+type layer []float32
+
+// colorRenderer draws a COLR glyph into layers of w by h pixels.
+type colorRenderer struct {
+	f          *Font
+	palette    []color.NRGBA
+	foreground [4]float32
+	w, h       int
+	r          *raster.Rasterizer
+	g          GlyphBuf
+	// path holds the offsets of the paint tables being drawn, from the
+	// glyph's root paint down to the current one, and nPaints is the number
+	// of paint tables drawn so far.
+	path    map[int]bool
+	nPaints int
+}
+
+// colorStop is a color stop of a gradient's color line.
+type colorStop struct {
+	offset float64
+	c      [4]float32
+}
+
+func (c *colorRenderer) newLayer() layer {
+	return make(layer, 4*c.w*c.h)
+}
+
+// need returns an error unless the COLR table has n bytes at offset x.
+func (c *colorRenderer) need(x, n int) error {
+	if x < 0 || len(c.f.colr) < x+n {
+		return FormatError("bad COLR paint offset")
+	}
+	return nil
+}
+
+// layers draws the glyph's version 0 layers, each of which fills a glyph
+// outline with a palette color.
+func (c *colorRenderer) layers(first, n int, m affine) (layer, error) {
+	dst := c.newLayer()
+	for j := 0; j < n; j++ {
+		x := c.f.colrTable.layers + 4*(first+j)
+		col, err := c.color(u16(c.f.colr, x+2), 1)
+		if err != nil {
+			return nil, err
+		}
+		src := c.newLayer()
+		src.fill(col)
+		if err := c.clip(src, Index(u16(c.f.colr, x)), m); err != nil {
+			return nil, err
+		}
+		composite(src, dst, compositeSrcOver)
+	}
+	return dst, nil
+}
+
+// paint draws the version 1 paint table at offset x of the COLR table,
+// whose FUnit co-ordinates are transformed by m.
+func (c *colorRenderer) paint(x int, m affine) (layer, error) {
+	if len(c.path) >= maxPaintDepth {
+		return nil, FormatError("COLR paint graph nested too deeply")
+	}
+	if c.path[x] {
+		return nil, FormatError("COLR paint graph has a cycle")
+	}
+	if c.nPaints++; c.nPaints > maxPaints {
+		return nil, FormatError("COLR paint graph too large")
+	}
+	c.path[x] = true
+	defer delete(c.path, x)
+	if err := c.need(x, 1); err != nil {
+		return nil, err
+	}
+	b := c.f.colr
+	format := int(b[x])
+	// The variable formats are laid out like the formats before them, with
+	// the indexes of their deltas appended, so we draw them alike.
+	vary := format >= 3 && format <= 31 && format%2 == 1 && format != 11
+	if vary {
+		format--
+	}
+	// child draws the paint at the 24-bit offset at x+o, transformed by t.
+	child := func(o int, t affine) (layer, error) {
+		return c.paint(x+int(u24(b, x+o)), m.mul(t))
+	}
+	i16 := func(o int) float64 { return float64(int16(u16(b, x+o))) }
+	f2dot14 := func(o int) float64 { return float64(int16(u16(b, x+o))) / (1 << 14) }
+
+	if format >= len(paintSizes) || paintSizes[format] == 0 {
+		return nil, UnsupportedError(fmt.Sprintf("COLR paint format: %d", b[x]))
+	}
+	if err := c.need(x, paintSizes[format]); err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case 1: // PaintColrLayers.
+		n, first := int(b[x+1]), int(u32(b, x+2))
+		ll := c.f.colrTable.layerList
+		if ll == 0 || int(u32(b, ll)) < first+n {
+			return nil, FormatError("bad COLR layer index")
+		}
+		dst := c.newLayer()
+		for j := first; j < first+n; j++ {
+			src, err := c.paint(ll+int(u32(b, ll+4+4*j)), m)
+			if err != nil {
+				return nil, err
+			}
+			composite(src, dst, compositeSrcOver)
+		}
+		return dst, nil
+
+	case 2: // PaintSolid.
+		col, err := c.color(u16(b, x+1), f2dot14(3))
+		if err != nil {
+			return nil, err
+		}
+		dst := c.newLayer()
+		dst.fill(col)
+		return dst, nil
+
+	case 4: // PaintLinearGradient.
+		extend, stops, err := c.colorLine(x+int(u24(b, x+1)), vary)
+		if err != nil {
+			return nil, err
+		}
+		x0, y0, x1, y1, x2, y2 := i16(4), i16(6), i16(8), i16(10), i16(12), i16(14)
+		// The gradient's direction is from p0 to p1, projected onto the
+		// line through p0 that is perpendicular to p0 to p2.
+		nx, ny := -(y2 - y0), x2-x0
+		if d := nx*nx + ny*ny; d != 0 {
+			k := ((x1-x0)*nx + (y1-y0)*ny) / d
+			x1, y1 = x0+k*nx, y0+k*ny
+		}
+		dx, dy := x1-x0, y1-y0
+		d := dx*dx + dy*dy
+		if d == 0 {
+			return c.newLayer(), nil
+		}
+		return c.shade(m, func(u, v float64) ([4]float32, bool) {
+			return colorAt(stops, extend, ((u-x0)*dx+(v-y0)*dy)/d), true
+		}), nil
+
+	case 6: // PaintRadialGradient.
+		extend, stops, err := c.colorLine(x+int(u24(b, x+1)), vary)
+		if err != nil {
+			return nil, err
+		}
+		cx0, cy0, r0 := i16(4), i16(6), float64(u16(b, x+8))
+		cx1, cy1, r1 := i16(10), i16(12), float64(u16(b, x+14))
+		return c.shade(m, func(u, v float64) ([4]float32, bool) {
+			t, ok := radialT(u-cx0, v-cy0, cx1-cx0, cy1-cy0, r0, r1-r0)
+			if !ok {
+				return [4]float32{}, false
+			}
+			return colorAt(stops, extend, t), true
+		}), nil
+
+	case 8: // PaintSweepGradient.
+		extend, stops, err := c.colorLine(x+int(u24(b, x+1)), vary)
+		if err != nil {
+			return nil, err
+		}
+		// The angles are counter-clockwise from the positive X axis, in
+		// half turns.
+		cx, cy := i16(4), i16(6)
+		start, end := f2dot14(8)*180, f2dot14(10)*180
+		if start == end {
+			return c.newLayer(), nil
+		}
+		return c.shade(m, func(u, v float64) ([4]float32, bool) {
+			a := math.Atan2(v-cy, u-cx) * 180 / math.Pi
+			if a < 0 {
+				a += 360
+			}
+			return colorAt(stops, extend, (a-start)/(end-start)), true
+		}), nil
+
+	case 10: // PaintGlyph.
+		dst, err := child(1, affine{xx: 1, yy: 1})
+		if err != nil {
+			return nil, err
+		}
+		return dst, c.clip(dst, Index(u16(b, x+4)), m)
+
+	case 11: // PaintColrGlyph.
+		p, ok := c.f.colrPaint(Index(u16(b, x+1)))
+		if !ok {
+			return c.newLayer(), nil
+		}
+		return c.paint(p, m)
+
+	case 12: // PaintTransform.
+		y := x + int(u24(b, x+4))
+		if err := c.need(y, 24); err != nil {
+			return nil, err
+		}
+		fixed16 := func(o int) float64 { return float64(int32(u32(b, y+o))) / (1 << 16) }
+		return child(1, affine{fixed16(0), fixed16(4), fixed16(8), fixed16(12), fixed16(16), fixed16(20)})
+
+	case 14: // PaintTranslate.
+		return child(1, affine{xx: 1, yy: 1, dx: i16(4), dy: i16(6)})
+
+	case 16: // PaintScale.
+		return child(1, affine{xx: f2dot14(4), yy: f2dot14(6)})
+
+	case 18: // PaintScaleAroundCenter.
+		return child(1, affine{xx: f2dot14(4), yy: f2dot14(6)}.around(i16(8), i16(10)))
+
+	case 20: // PaintScaleUniform.
+		return child(1, affine{xx: f2dot14(4), yy: f2dot14(4)})
+
+	case 22: // PaintScaleUniformAroundCenter.
+		return child(1, affine{xx: f2dot14(4), yy: f2dot14(4)}.around(i16(6), i16(8)))
+
+	case 24, 26: // PaintRotate and PaintRotateAroundCenter.
+		// The angle is counter-clockwise, in half turns.
+		sin, cos := math.Sincos(f2dot14(4) * math.Pi)
+		t := affine{xx: cos, yx: sin, xy: -sin, yy: cos}
+		if format == 26 {
+			t = t.around(i16(6), i16(8))
+		}
+		return child(1, t)
+
+	case 28, 30: // PaintSkew and PaintSkewAroundCenter.
+		// The angles are counter-clockwise, in half turns.
+		t := affine{xx: 1, yx: math.Tan(f2dot14(6) * math.Pi), xy: -math.Tan(f2dot14(4) * math.Pi), yy: 1}
+		if format == 30 {
+			t = t.around(i16(8), i16(10))
+		}
+		return child(1, t)
+
+	case 32: // PaintComposite.
+		src, err := child(1, affine{xx: 1, yy: 1})
+		if err != nil {
+			return nil, err
+		}
+		dst, err := child(5, affine{xx: 1, yy: 1})
+		if err != nil {
+			return nil, err
+		}
+		if b[x+4] > compositeLuminosity {
+			return nil, UnsupportedError(fmt.Sprintf("COLR composite mode: %d", b[x+4]))
+		}
+		composite(src, dst, b[x+4])
+		return dst, nil
+	}
+	panic("unreachable")
+}
+
+// color returns the premultiplied palette color, with its alpha multiplied
+// by alpha. Index 0xffff is the foreground color.
+func (c *colorRenderer) color(i uint16, alpha float64) ([4]float32, error) {
+	var col [4]float32
+	switch {
+	case i == 0xffff:
+		col = c.foreground
+	case int(i) < len(c.palette):
+		p := c.palette[i]
+		a := float32(p.A) / 0xff
+		col = [4]float32{float32(p.R) / 0xff * a, float32(p.G) / 0xff * a, float32(p.B) / 0xff * a, a}
+	default:
+		return col, FormatError("bad CPAL palette entry")
+	}
+	for k := range col {
+		col[k] *= float32(alpha)
+	}
+	return col, nil
+}
+
+// colorLine returns the extend mode and the color stops, sorted by offset,
+// of the color line at offset x of the COLR table.
+func (c *colorRenderer) colorLine(x int, vary bool) (extend uint8, stops []colorStop, err error) {
+	if err := c.need(x, 3); err != nil {
+		return 0, nil, err
+	}
+	size := 6
+	if vary {
+		size = 10
+	}
+	n := int(u16(c.f.colr, x+1))
+	if err := c.need(x+3, size*n); err != nil {
+		return 0, nil, err
+	}
+	stops = make([]colorStop, n)
+	for j := range stops {
+		y := x + 3 + size*j
+		stops[j].offset = float64(int16(u16(c.f.colr, y))) / (1 << 14)
+		alpha := float64(int16(u16(c.f.colr, y+4))) / (1 << 14)
+		if stops[j].c, err = c.color(u16(c.f.colr, y+2), alpha); err != nil {
+			return 0, nil, err
+		}
+	}
+	sort.SliceStable(stops, func(i, j int) bool { return stops[i].offset < stops[j].offset })
+	return c.f.colr[x], stops, nil
+}
+
+// clip multiplies the layer by the coverage of the glyph's outline,
+// transformed by m.
+func (c *colorRenderer) clip(l layer, i Index, m affine) error {
+	if err := c.g.Load(c.f, fixed.Int26_6(c.f.fUnitsPerEm), i, font.HintingNone); err != nil {
+		return err
+	}
+	c.r.Clear()
+	e0 := 0
+	for _, e1 := range c.g.Ends {
+		drawContour(c.r, c.g.Points[e0:e1], func(p Point) fixed.Point26_6 {
+			x, y := m.apply(float64(p.X), float64(p.Y))
+			return fixed.Point26_6{X: fixed.Int26_6(x * 64), Y: fixed.Int26_6(y * 64)}
+		})
+		e0 = e1
+	}
+	mask := image.NewAlpha(image.Rect(0, 0, c.w, c.h))
+	c.r.Rasterize(raster.NewAlphaSrcPainter(mask))
+	for j, a := range mask.Pix {
+		k := float32(a) / 0xff
+		for ch := 0; ch < 4; ch++ {
+			l[4*j+ch] *= k
+		}
+	}
+	return nil
+}
+
+// shade returns a layer whose pixels are given by the function of their
+// centers' FUnit co-ordinates, before they are transformed by m. The
+// function returns false for transparent pixels.
+func (c *colorRenderer) shade(m affine, f func(u, v float64) ([4]float32, bool)) layer {
+	dst := c.newLayer()
+	inv, ok := m.invert()
+	if !ok {
+		return dst
+	}
+	for y := 0; y < c.h; y++ {
+		for x := 0; x < c.w; x++ {
+			u, v := inv.apply(float64(x)+0.5, float64(y)+0.5)
+			if col, ok := f(u, v); ok {
+				copy(dst[4*(y*c.w+x):], col[:])
+			}
+		}
+	}
+	return dst
+}
+
+// fill sets every pixel of the layer to the color.
+func (l layer) fill(col [4]float32) {
+	for j := 0; j < len(l); j += 4 {
+		copy(l[j:j+4], col[:])
+	}
+}
+
+// Extend modes of a color line, which give the colors outside its stops.
+const (
+	extendPad     = 0
+	extendRepeat  = 1
+	extendReflect = 2
+)
+
+// colorAt returns the color at t on the color line.
+func colorAt(stops []colorStop, extend uint8, t float64) [4]float32 {
+	if len(stops) == 0 {
+		return [4]float32{}
+	}
+	first, last := stops[0].offset, stops[len(stops)-1].offset
+	if r := last - first; r > 0 {
+		switch extend {
+		case extendRepeat:
+			u := math.Mod(t-first, r)
+			if u < 0 {
+				u += r
+			}
+			t = first + u
+		case extendReflect:
+			u := math.Mod(t-first, 2*r)
+			if u < 0 {
+				u += 2 * r
+			}
+			if u > r {
+				u = 2*r - u
+			}
+			t = first + u
+		}
+	}
+	if t <= first {
+		return stops[0].c
+	}
+	for j := 1; j < len(stops); j++ {
+		s0, s1 := stops[j-1], stops[j]
+		if t > s1.offset {
+			continue
+		}
+		if s1.offset == s0.offset {
+			return s1.c
+		}
+		k := float32((t - s0.offset) / (s1.offset - s0.offset))
+		var col [4]float32
+		for ch := range col {
+			col[ch] = s0.c[ch] + k*(s1.c[ch]-s0.c[ch])
+		}
+		return col
+	}
+	return stops[len(stops)-1].c
+}
+
+// radialT returns the largest t for which the point, relative to the start
+// circle's center, is on the circle whose center is t of the way along
+// (cdx, cdy) and whose radius, r0 + t*dr, is non-negative.
+func radialT(px, py, cdx, cdy, r0, dr float64) (float64, bool) {
+	a := cdx*cdx + cdy*cdy - dr*dr
+	b := px*cdx + py*cdy + r0*dr
+	c := px*px + py*py - r0*r0
+	if a == 0 {
+		if b == 0 {
+			return 0, false
+		}
+		t := c / (2 * b)
+		return t, r0+t*dr >= 0
+	}
+	disc := b*b - a*c
+	if disc < 0 {
+		return 0, false
+	}
+	sq := math.Sqrt(disc)
+	t0, t1 := (b+sq)/a, (b-sq)/a
+	if t0 < t1 {
+		t0, t1 = t1, t0
+	}
+	if r0+t0*dr >= 0 {
+		return t0, true
+	}
+	if r0+t1*dr >= 0 {
+		return t1, true
+	}
+	return 0, false
+}
+
+// Composite modes of PaintComposite. The first are the Porter-Duff
+// operators, and the rest are the W3C Compositing and Blending blend modes.
+const (
+	compositeClear = iota
+	compositeSrc
+	compositeDest
+	compositeSrcOver
+	compositeDestOver
+	compositeSrcIn
+	compositeDestIn
+	compositeSrcOut
+	compositeDestOut
+	compositeSrcAtop
+	compositeDestAtop
+	compositeXor
+	compositePlus
+	compositeScreen
+	compositeOverlay
+	compositeDarken
+	compositeLighten
+	compositeColorDodge
+	compositeColorBurn
+	compositeHardLight
+	compositeSoftLight
+	compositeDifference
+	compositeExclusion
+	compositeMultiply
+	compositeHue
+	compositeSaturation
+	compositeColor
+	compositeLuminosity
+)
+
+// composite composites src onto dst, in place, with the composite mode.
+func composite(src, dst layer, mode uint8) {
+	for j := 0; j < len(dst); j += 4 {
+		s, d := src[j:j+4], dst[j:j+4]
+		sa, da := s[3], d[3]
+		if mode <= compositePlus {
+			// The result is s*fa + d*fb.
+			var fa, fb float32
+			switch mode {
+			case compositeSrc:
+				fa = 1
+			case compositeDest:
+				fb = 1
+			case compositeSrcOver:
+				fa, fb = 1, 1-sa
+			case compositeDestOver:
+				fa, fb = 1-da, 1
+			case compositeSrcIn:
+				fa = da
+			case compositeDestIn:
+				fb = sa
+			case compositeSrcOut:
+				fa = 1 - da
+			case compositeDestOut:
+				fb = 1 - sa
+			case compositeSrcAtop:
+				fa, fb = da, 1-sa
+			case compositeDestAtop:
+				fa, fb = 1-da, sa
+			case compositeXor:
+				fa, fb = 1-da, 1-sa
+			case compositePlus:
+				fa, fb = 1, 1
+			}
+			for ch := range d {
+				d[ch] = float32(math.Min(1, float64(s[ch]*fa+d[ch]*fb)))
+			}
+			continue
+		}
+
+		// The blend modes mix the unpremultiplied colors, cs and cb, where
+		// the source and backdrop overlap.
+		var cs, cb [3]float64
+		for ch := range cs {
+			if sa != 0 {
+				cs[ch] = float64(s[ch] / sa)
+			}
+			if da != 0 {
+				cb[ch] = float64(d[ch] / da)
+			}
+		}
+		var mixed [3]float64
+		switch mode {
+		case compositeHue:
+			mixed = setLum(setSat(cs, sat(cb)), lum(cb))
+		case compositeSaturation:
+			mixed = setLum(setSat(cb, sat(cs)), lum(cb))
+		case compositeColor:
+			mixed = setLum(cs, lum(cb))
+		case compositeLuminosity:
+			mixed = setLum(cb, lum(cs))
+		default:
+			for ch := range mixed {
+				mixed[ch] = blend(mode, cb[ch], cs[ch])
+			}
+		}
+		for ch := range mixed {
+			d[ch] = (1-da)*s[ch] + (1-sa)*d[ch] + sa*da*float32(mixed[ch])
+		}
+		d[3] = sa + da*(1-sa)
+	}
+}
+
+// blend returns the separable blend mode's mix of a backdrop and source
+// color channel.
+func blend(mode uint8, cb, cs float64) float64 {
+	switch mode {
+	case compositeScreen:
+		return cb + cs - cb*cs
+	case compositeOverlay:
+		return blend(compositeHardLight, cs, cb)
+	case compositeDarken:
+		return math.Min(cb, cs)
+	case compositeLighten:
+		return math.Max(cb, cs)
+	case compositeColorDodge:
+		if cb == 0 {
+			return 0
+		}
+		if cs >= 1 {
+			return 1
+		}
+		return math.Min(1, cb/(1-cs))
+	case compositeColorBurn:
+		if cb >= 1 {
+			return 1
+		}
+		if cs <= 0 {
+			return 0
+		}
+		return 1 - math.Min(1, (1-cb)/cs)
+	case compositeHardLight:
+		if cs <= 0.5 {
+			return cb * 2 * cs
+		}
+		return blend(compositeScreen, cb, 2*cs-1)
+	case compositeSoftLight:
+		if cs <= 0.5 {
+			return cb - (1-2*cs)*cb*(1-cb)
+		}
+		d := math.Sqrt(cb)
+		if cb <= 0.25 {
+			d = ((16*cb-12)*cb + 4) * cb
+		}
+		return cb + (2*cs-1)*(d-cb)
+	case compositeDifference:
+		return math.Abs(cb - cs)
+	case compositeExclusion:
+		return cb + cs - 2*cb*cs
+	case compositeMultiply:
+		return cb * cs
+	}
+	return cs
+}
+
+// lum, clipColor, setLum, sat and setSat are the helpers of the
+// non-separable blend modes.
+
+func lum(c [3]float64) float64 {
+	return 0.3*c[0] + 0.59*c[1] + 0.11*c[2]
+}
+
+func clipColor(c [3]float64) [3]float64 {
+	l := lum(c)
+	n := math.Min(c[0], math.Min(c[1], c[2]))
+	x := math.Max(c[0], math.Max(c[1], c[2]))
+	for ch := range c {
+		if n < 0 {
+			c[ch] = l + (c[ch]-l)*l/(l-n)
+		}
+		if x > 1 {
+			c[ch] = l + (c[ch]-l)*(1-l)/(x-l)
+		}
+	}
+	return c
+}
+
+func setLum(c [3]float64, l float64) [3]float64 {
+	d := l - lum(c)
+	for ch := range c {
+		c[ch] += d
+	}
+	return clipColor(c)
+}
+
+func sat(c [3]float64) float64 {
+	return math.Max(c[0], math.Max(c[1], c[2])) - math.Min(c[0], math.Min(c[1], c[2]))
+}
+
+func setSat(c [3]float64, s float64) [3]float64 {
+	// Find the indexes of the minimum, middle and maximum channels.
+	lo, mid, hi := 0, 1, 2
+	if c[lo] > c[mid] {
+		lo, mid = mid, lo
+	}
+	if c[mid] > c[hi] {
+		mid, hi = hi, mid
+	}
+	if c[lo] > c[mid] {
+		lo, mid = mid, lo
+	}
+	var ret [3]float64
+	if c[hi] > c[lo] {
+		ret[mid] = (c[mid] - c[lo]) * s / (c[hi] - c[lo])
+		ret[hi] = s
+	}
+	return ret
+}
@@ -0,0 +1,273 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// makeTestColorFont returns luxisr.ttf plus a COLR table, in which glyph a
+// has a version 0 layer that fills its outline with palette entry 0, glyph
+// b has a version 1 paint graph that fills its outline with palette entry 1,
+// and glyph c has a layer that fills its outline with the foreground color,
+// and a CPAL table of two palettes.
+func makeTestColorFont(t *testing.T, a, b, c Index) *Font {
+	colr := cat(
+		// The version 1 header.
+		be16(1, 2, 0, 34, 0, 46, 2, 0, 54, 0, 0, 0, 0, 0, 0, 0, 0),
+		// The base glyph and layer records.
+		be16(int(a), 0, 1, int(c), 1, 1),
+		be16(int(a), 0, int(c), 0xffff),
+		// The base glyph list, a PaintGlyph and a PaintSolid.
+		be16(0, 1, int(b), 0, 10),
+		[]byte{10, 0, 0, 6}, be16(int(b)),
+		[]byte{2}, be16(1, 0x2000),
+	)
+	cpal := cat(
+		be16(0, 2, 2, 4, 0, 16, 0, 2),
+		// The color records, in BGRA order: red and green, then blue and
+		// white.
+		[]byte{0, 0, 0xff, 0xff, 0, 0xff, 0, 0xff},
+		[]byte{0xff, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff},
+	)
+	return makeTestFont(t, map[string][]byte{"COLR": colr, "CPAL": cpal})
+}
+
+// opaqueColors returns the distinct colors of the image's opaque pixels.
+func opaqueColors(m *image.RGBA) map[color.RGBA]bool {
+	ret := map[color.RGBA]bool{}
+	for y := m.Rect.Min.Y; y < m.Rect.Max.Y; y++ {
+		for x := m.Rect.Min.X; x < m.Rect.Max.X; x++ {
+			if c := m.RGBAAt(x, y); c.A == 0xff {
+				ret[c] = true
+			}
+		}
+	}
+	return ret
+}
+
+func TestColorGlyph(t *testing.T) {
+	g, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	A, B, C, D := g.Index('A'), g.Index('B'), g.Index('C'), g.Index('D')
+	f := makeTestColorFont(t, A, B, C)
+
+	wantPalettes := [][]color.NRGBA{
+		{{0xff, 0, 0, 0xff}, {0, 0xff, 0, 0xff}},
+		{{0, 0, 0xff, 0xff}, {0xff, 0xff, 0xff, 0xff}},
+	}
+	if got := f.Palettes(); fmt.Sprint(got) != fmt.Sprint(wantPalettes) {
+		t.Errorf("Palettes: got %v, want %v", got, wantPalettes)
+	}
+	for _, i := range []Index{A, B, C} {
+		if !f.HasColorGlyph(i) {
+			t.Errorf("HasColorGlyph(%d): got false, want true", i)
+		}
+	}
+	if f.HasColorGlyph(D) {
+		t.Errorf("HasColorGlyph(D): got true, want false")
+	}
+	if b, err := f.ColorGlyph(fixed.I(40), D, 0, nil); b != nil || err != nil {
+		t.Errorf("ColorGlyph(D): got %v, %v, want nil, nil", b, err)
+	}
+
+	testCases := []struct {
+		i          Index
+		palette    int
+		foreground color.Color
+		want       color.RGBA
+	}{
+		{A, 0, nil, color.RGBA{0xff, 0, 0, 0xff}},
+		{A, 1, nil, color.RGBA{0, 0, 0xff, 0xff}},
+		// A missing palette is the first palette.
+		{A, 2, nil, color.RGBA{0xff, 0, 0, 0xff}},
+		// Glyph B's paint graph halves its palette color's alpha.
+		{B, 0, nil, color.RGBA{0, 0x80, 0, 0x80}},
+		{B, 1, nil, color.RGBA{0x80, 0x80, 0x80, 0x80}},
+		{C, 0, nil, color.RGBA{0, 0, 0, 0xff}},
+		{C, 0, color.RGBA{0, 0xff, 0xff, 0xff}, color.RGBA{0, 0xff, 0xff, 0xff}},
+	}
+	for _, tc := range testCases {
+		b, err := f.ColorGlyph(fixed.I(40), tc.i, tc.palette, tc.foreground)
+		if err != nil || b == nil {
+			t.Errorf("ColorGlyph(%d, %d): got %v, %v", tc.i, tc.palette, b, err)
+			continue
+		}
+		m, ok := b.Image.(*image.RGBA)
+		if !ok {
+			t.Errorf("ColorGlyph(%d, %d): got image %T, want *image.RGBA", tc.i, tc.palette, b.Image)
+			continue
+		}
+		// The glyph's interior is painted with the wanted color, and its
+		// edges are partly transparent.
+		found := false
+		for y := m.Rect.Min.Y; y < m.Rect.Max.Y; y++ {
+			for x := m.Rect.Min.X; x < m.Rect.Max.X; x++ {
+				c := m.RGBAAt(x, y)
+				if c == tc.want {
+					found = true
+				} else if c.A >= tc.want.A {
+					t.Errorf("ColorGlyph(%d, %d): got color %v at (%d, %d), want %v",
+						tc.i, tc.palette, c, x, y, tc.want)
+					break
+				}
+			}
+		}
+		if !found {
+			t.Errorf("ColorGlyph(%d, %d): no pixel has color %v", tc.i, tc.palette, tc.want)
+		}
+	}
+
+	// A face draws color glyphs with its options' palette.
+	face := NewFace(f, &Options{Size: 40, Palette: 1})
+	_, mask, _, advance, ok := face.Glyph(fixed.P(10, 50), 'A')
+	if !ok {
+		t.Fatal("Glyph: not ok")
+	}
+	m, ok := mask.(*image.RGBA)
+	if !ok {
+		t.Fatalf("Glyph: got mask %T, want *image.RGBA", mask)
+	}
+	if got, want := opaqueColors(m), map[color.RGBA]bool{{0, 0, 0xff, 0xff}: true}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Glyph: got opaque colors %v, want %v", got, want)
+	}
+	if want := (g.HMetric(fixed.I(40), A).AdvanceWidth + 32) &^ 63; advance != want {
+		t.Errorf("Glyph: got advance %v, want %v", advance, want)
+	}
+	// Glyphs without color layers are drawn from their outlines.
+	if _, mask, _, _, ok := face.Glyph(fixed.P(10, 50), 'D'); !ok {
+		t.Error("Glyph('D'): not ok")
+	} else if _, ok := mask.(*image.Alpha); !ok {
+		t.Errorf("Glyph('D'): got mask %T, want *image.Alpha", mask)
+	}
+}
+
+func TestColorBadTables(t *testing.T) {
+	g, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	A := g.Index('A')
+	good := makeTestColorFont(t, A, g.Index('B'), g.Index('C'))
+	testCases := []struct {
+		desc       string
+		colr, cpal []byte
+	}{
+		{"short COLR", be16(1, 2, 0, 34), good.cpal},
+		{"COLR version", be16(2, 0, 0, 0, 0, 0, 0), good.cpal},
+		{"short CPAL", good.colr, be16(0, 2, 2, 4)},
+		{"CPAL color record index", good.colr, cat(be16(0, 2, 1, 2, 0, 14, 1), []byte{0, 0, 0xff, 0xff, 0, 0xff, 0, 0xff})},
+	}
+	for _, tc := range testCases {
+		// If either table is malformed, both are ignored, and the glyphs are
+		// drawn from their outlines.
+		f := makeTestFont(t, map[string][]byte{"COLR": tc.colr, "CPAL": tc.cpal})
+		if f.HasColorGlyph(A) {
+			t.Errorf("%s: HasColorGlyph(A): got true, want false", tc.desc)
+		}
+		if got := f.Palettes(); len(got) != 0 {
+			t.Errorf("%s: Palettes: got %v, want none", tc.desc, got)
+		}
+	}
+}
+
+func TestColorPaintLimits(t *testing.T) {
+	g, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	A := g.Index('A')
+	// colr returns a version 1 COLR table whose only base glyph is A, with
+	// the given paint tables.
+	colr := func(paints ...[]byte) []byte {
+		return cat(
+			be16(1, 0, 0, 0, 0, 0, 0, 0, 34, 0, 0, 0, 0, 0, 0, 0, 0),
+			be16(0, 1, int(A), 0, 10),
+			cat(paints...),
+		)
+	}
+	// A PaintColrGlyph that draws A again.
+	cycle := colr([]byte{11}, be16(int(A)))
+	// A chain of 30 PaintComposites, each of whose source and backdrop are
+	// the next one, ending in a PaintSolid. It is not nested too deeply, but
+	// it reaches the PaintSolid along 1<<30 paths.
+	var chain [][]byte
+	for j := 0; j < 30; j++ {
+		chain = append(chain, []byte{32, 0, 0, 8, compositeSrcOver, 0, 0, 8})
+	}
+	chain = append(chain, []byte{2}, be16(0xffff, 0x4000))
+	testCases := []struct {
+		desc string
+		colr []byte
+		want string
+	}{
+		{"cycle", cycle, "COLR paint graph has a cycle"},
+		{"chain", colr(chain...), "COLR paint graph too large"},
+	}
+	for _, tc := range testCases {
+		f := makeTestFont(t, map[string][]byte{"COLR": tc.colr})
+		_, err := f.ColorGlyph(fixed.I(4), A, 0, nil)
+		if want := FormatError(tc.want); err != want {
+			t.Errorf("%s: got %v, want %v", tc.desc, err, want)
+		}
+	}
+}
+
+func TestColorAt(t *testing.T) {
+	black, white := [4]float32{0, 0, 0, 1}, [4]float32{1, 1, 1, 1}
+	stops := []colorStop{{0.25, black}, {0.75, white}}
+	testCases := []struct {
+		extend uint8
+		t      float64
+		want   float32
+	}{
+		{extendPad, 0, 0},
+		{extendPad, 0.5, 0.5},
+		{extendPad, 1, 1},
+		{extendRepeat, 1, 0.5},
+		{extendRepeat, 0, 0.5},
+		{extendReflect, 1, 0.5},
+		{extendReflect, 1.25, 0},
+		{extendReflect, -0.25, 1},
+	}
+	for _, tc := range testCases {
+		if got := colorAt(stops, tc.extend, tc.t); got != [4]float32{tc.want, tc.want, tc.want, 1} {
+			t.Errorf("extend %d, t=%v: got %v, want gray %v", tc.extend, tc.t, got, tc.want)
+		}
+	}
+}
+
+func TestComposite(t *testing.T) {
+	// The source is half transparent red, and the backdrop is opaque blue.
+	src := layer{0.5, 0, 0, 0.5}
+	testCases := []struct {
+		mode uint8
+		want layer
+	}{
+		{compositeClear, layer{0, 0, 0, 0}},
+		{compositeSrc, layer{0.5, 0, 0, 0.5}},
+		{compositeDest, layer{0, 0, 1, 1}},
+		{compositeSrcOver, layer{0.5, 0, 0.5, 1}},
+		{compositeSrcIn, layer{0.5, 0, 0, 0.5}},
+		{compositeDestOut, layer{0, 0, 0.5, 0.5}},
+		{compositeMultiply, layer{0, 0, 0.5, 1}},
+		{compositeScreen, layer{0.5, 0, 1, 1}},
+	}
+	for _, tc := range testCases {
+		dst := layer{0, 0, 1, 1}
+		composite(src, dst, tc.mode)
+		if fmt.Sprint(dst) != fmt.Sprint(tc.want) {
+			t.Errorf("mode %d: got %v, want %v", tc.mode, dst, tc.want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+// These are the bits of an OS2's FSType field, as described at
+// https://www.microsoft.com/typography/otspec/os2.htm#fst
+const (
+	fsTypeRestricted      = 0x0002
+	fsTypePreviewAndPrint = 0x0004
+	fsTypeEditable        = 0x0008
+	fsTypeNoSubsetting    = 0x0100
+	fsTypeBitmapOnly      = 0x0200
+)
+
+// An EmbeddingLevel is how a font's license lets its data be embedded in
+// documents.
+type EmbeddingLevel int
+
+const (
+	// EmbeddingInstallable fonts may be embedded, and installed permanently
+	// on the systems that receive the documents.
+	EmbeddingInstallable EmbeddingLevel = iota
+	// EmbeddingEditable fonts may be embedded, and used to edit the
+	// documents, but not installed.
+	EmbeddingEditable
+	// EmbeddingPreviewAndPrint fonts may be embedded to view and print the
+	// documents, which must be read-only.
+	EmbeddingPreviewAndPrint
+	// EmbeddingRestricted fonts must not be embedded.
+	EmbeddingRestricted
+)
+
+func (l EmbeddingLevel) String() string {
+	switch l {
+	case EmbeddingInstallable:
+		return "installable"
+	case EmbeddingEditable:
+		return "editable"
+	case EmbeddingPreviewAndPrint:
+		return "preview and print"
+	case EmbeddingRestricted:
+		return "restricted"
+	}
+	return "unknown"
+}
+
+// Permissions are the embedding permissions of a font's OS/2 fsType field,
+// and the licensing names of its name table.
+type Permissions struct {
+	// Level is the embedding level. If the fsType field sets more than one
+	// level, the least restrictive takes precedence.
+	Level EmbeddingLevel
+	// NoSubsetting is whether the font must be embedded whole, rather than
+	// as a subset of its glyphs.
+	NoSubsetting bool
+	// BitmapOnly is whether only the font's embedded bitmaps, and not its
+	// outlines, may be embedded.
+	BitmapOnly bool
+	// Copyright, License and LicenseURL are the font's NameIDCopyright,
+	// NameIDFontLicense and NameIDFontLicenseURL names, in English if there
+	// is a choice, or "" if it has none.
+	Copyright, License, LicenseURL string
+}
+
+// Permissions returns the font's embedding permissions and licensing names. A
+// font without an OS/2 table is installable.
+func (f *Font) Permissions() Permissions {
+	p := Permissions{
+		Copyright:  f.LocalizedName(NameIDCopyright, "en"),
+		License:    f.LocalizedName(NameIDFontLicense, "en"),
+		LicenseURL: f.LocalizedName(NameIDFontLicenseURL, "en"),
+	}
+	if f.os2Table == nil {
+		return p
+	}
+	fsType := f.os2Table.FSType
+	switch {
+	case fsType&fsTypeEditable != 0:
+		p.Level = EmbeddingEditable
+	case fsType&fsTypePreviewAndPrint != 0:
+		p.Level = EmbeddingPreviewAndPrint
+	case fsType&fsTypeRestricted != 0:
+		p.Level = EmbeddingRestricted
+	}
+	p.NoSubsetting = fsType&fsTypeNoSubsetting != 0
+	p.BitmapOnly = fsType&fsTypeBitmapOnly != 0
+	return p
+}
+
+// An EmbeddingUse is a combination of the ways in which a program uses a
+// font's data when embedding it in a document. The zero value is embedding
+// the whole font's outlines, to view and print a read-only document.
+type EmbeddingUse uint32
+
+const (
+	// UseEdit is embedding the font in a document that can be edited.
+	UseEdit EmbeddingUse = 1 << iota
+	// UseInstall is embedding the font so that it can be installed
+	// permanently on the systems that receive the document.
+	UseInstall
+	// UseSubset is embedding a subset of the font's glyphs.
+	UseSubset
+	// UseBitmapsOnly is embedding only the font's embedded bitmaps, and not
+	// its outlines.
+	UseBitmapsOnly
+)
+
+// An EmbeddingError reports that a font's permissions forbid a use of its
+// data.
+type EmbeddingError string
+
+func (e EmbeddingError) Error() string {
+	return "freetype: font embedding not permitted: " + string(e)
+}
+
+// Check returns nil if the permissions allow the use, or an EmbeddingError
+// if they forbid it.
+func (p Permissions) Check(use EmbeddingUse) error {
+	switch {
+	case p.Level == EmbeddingRestricted:
+		return EmbeddingError("the font is restricted")
+	case use&UseInstall != 0 && p.Level != EmbeddingInstallable:
+		return EmbeddingError("the font is " + p.Level.String() + ", not installable")
+	case use&UseEdit != 0 && p.Level == EmbeddingPreviewAndPrint:
+		return EmbeddingError("the font is preview and print, not editable")
+	case use&UseSubset != 0 && p.NoSubsetting:
+		return EmbeddingError("the font must not be subset")
+	case use&UseBitmapsOnly == 0 && p.BitmapOnly:
+		return EmbeddingError("only the font's bitmaps may be embedded")
+	}
+	return nil
+}
+
+// An EmbeddingPolicy decides whether a program may use a font's data in the
+// given way, returning nil if it may. APIs that subset or embed fonts take an
+// EmbeddingPolicy, and refuse the operations that it forbids, so that their
+// callers can choose to enforce the fonts' permissions or their own rules.
+type EmbeddingPolicy func(f *Font, use EmbeddingUse) error
+
+// FontEmbeddingPolicy is the EmbeddingPolicy that enforces the permissions of
+// the font's OS/2 fsType field.
+func FontEmbeddingPolicy(f *Font, use EmbeddingUse) error {
+	return f.Permissions().Check(use)
+}
@@ -0,0 +1,77 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+import (
+	"testing"
+)
+
+func TestPermissions(t *testing.T) {
+	f, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Permissions{
+		Level:     EmbeddingInstallable,
+		Copyright: "Copyright (c) 2001 by Bigelow & Holmes Inc. Instructions copyright (c) 2001 by URW++.",
+	}
+	if got := f.Permissions(); got != want {
+		t.Errorf("luxisr:\ngot  %+v\nwant %+v", got, want)
+	}
+
+	name := makeNameTable([]NameRecord{
+		{PlatformID: 3, EncodingID: 1, LanguageID: 0x0409, NameID: NameIDFontLicense, Value: "SIL Open Font License, Version 1.1"},
+		{PlatformID: 3, EncodingID: 1, LanguageID: 0x0409, NameID: NameIDFontLicenseURL, Value: "https://openfontlicense.org"},
+	})
+	all := UseEdit | UseInstall | UseSubset
+	testCases := []struct {
+		fsType       int
+		level        EmbeddingLevel
+		noSubsetting bool
+		bitmapOnly   bool
+		allowed      []EmbeddingUse
+		forbidden    []EmbeddingUse
+	}{
+		{0x0000, EmbeddingInstallable, false, false, []EmbeddingUse{0, all, all | UseBitmapsOnly}, nil},
+		{0x0002, EmbeddingRestricted, false, false, nil, []EmbeddingUse{0, UseSubset}},
+		{0x0004, EmbeddingPreviewAndPrint, false, false,
+			[]EmbeddingUse{0, UseSubset}, []EmbeddingUse{UseEdit, UseInstall}},
+		{0x0008, EmbeddingEditable, false, false,
+			[]EmbeddingUse{UseEdit | UseSubset}, []EmbeddingUse{UseInstall}},
+		// Version 0 to 2 tables may set more than one level, and the least
+		// restrictive wins.
+		{0x000e, EmbeddingEditable, false, false, []EmbeddingUse{UseEdit}, []EmbeddingUse{UseInstall}},
+		{0x0108, EmbeddingEditable, true, false, []EmbeddingUse{UseEdit}, []EmbeddingUse{UseSubset}},
+		{0x0200, EmbeddingInstallable, false, true,
+			[]EmbeddingUse{UseBitmapsOnly, UseInstall | UseSubset | UseBitmapsOnly}, []EmbeddingUse{0, UseSubset}},
+	}
+	for _, tc := range testCases {
+		os2 := append([]byte(nil), f.os2...)
+		copy(os2[8:], be16(tc.fsType))
+		g := makeTestFont(t, map[string][]byte{"name": name, "OS/2": os2})
+		p := g.Permissions()
+		want := Permissions{
+			Level:        tc.level,
+			NoSubsetting: tc.noSubsetting,
+			BitmapOnly:   tc.bitmapOnly,
+			License:      "SIL Open Font License, Version 1.1",
+			LicenseURL:   "https://openfontlicense.org",
+		}
+		if p != want {
+			t.Errorf("fsType %#04x:\ngot  %+v\nwant %+v", tc.fsType, p, want)
+		}
+		for _, use := range tc.allowed {
+			if err := FontEmbeddingPolicy(g, use); err != nil {
+				t.Errorf("fsType %#04x: use %#x: got %v, want nil", tc.fsType, use, err)
+			}
+		}
+		for _, use := range tc.forbidden {
+			if _, ok := FontEmbeddingPolicy(g, use).(EmbeddingError); !ok {
+				t.Errorf("fsType %#04x: use %#x: got no EmbeddingError", tc.fsType, use)
+			}
+		}
+	}
+}
@@ -7,6 +7,7 @@ package truetype
 
 import (
 	"image"
+	"image/color"
 	"math"
 
 	"github.com/golang/freetype/raster"
@@ -66,6 +67,45 @@ type Options struct {
 	//
 	// A zero value means to use 1 sub-pixel location.
 	SubPixelsY int
+
+	// Variations are the user co-ordinates, keyed by axis tag, of the
+	// instance of a variable font to use. For example, {"wght": 700} selects
+	// a bold weight. See Font.Instance for details.
+	//
+	// A nil value means to use the font's default instance.
+	Variations map[string]float64
+
+	// Palette is the index of the CPAL palette that color glyphs, from the
+	// font's COLR table, are drawn with.
+	//
+	// A zero value means to use the first palette.
+	Palette int
+
+	// Foreground is the color of the parts of color glyphs that the font
+	// leaves to the text's color.
+	//
+	// A nil value means to use opaque black.
+	Foreground color.Color
+
+	// Vertical is whether the face lays text out top-to-bottom. A vertical
+	// face's glyphs use the font's vertical alternates, from its GSUB vert
+	// feature. The dot is the glyph's vertical origin, which is centered
+	// above the glyph, and the advance is the glyph's advance height, which
+	// callers add to the dot's Y co-ordinate instead of its X co-ordinate.
+	// Glyph pairs are not kerned.
+	Vertical bool
+
+	// SubpixelHinting is whether hinting preserves the glyphs' horizontal
+	// shapes and advance widths, like C Freetype's v40 interpreter, which
+	// suits text drawn at sub-pixel X locations. See GlyphBuf's field of the
+	// same name for details. It has no effect without hinting.
+	SubpixelHinting bool
+
+	// AutoHinting is whether glyphs are hinted by an automatic hinter,
+	// instead of by the font's bytecode, for fonts without TrueType
+	// instructions. See GlyphBuf's field of the same name for details. It
+	// has no effect without hinting.
+	AutoHinting bool
 }
 
 func (o *Options) size() float64 {
@@ -86,8 +126,7 @@ func (o *Options) hinting() font.Hinting {
 	if o != nil {
 		switch o.Hinting {
 		case font.HintingVertical, font.HintingFull:
-			// TODO: support vertical hinting.
-			return font.HintingFull
+			return o.Hinting
 		}
 	}
 	return font.HintingNone
@@ -175,8 +214,44 @@ type indexCacheEntry struct {
 	index Index
 }
 
+type bitmapCacheEntry struct {
+	valid  bool
+	index  Index
+	bitmap *Bitmap
+}
+
+// An IndexFace is a font.Face that can also draw glyphs by their index in the
+// Font, such as the glyphs that Font.Substitute returns. The font.Face that
+// NewFace returns is an IndexFace.
+type IndexFace interface {
+	font.Face
+
+	// IndexGlyph is like Glyph, for the glyph with the given index.
+	IndexGlyph(dot fixed.Point26_6, i Index) (
+		dr image.Rectangle, mask image.Image, maskp image.Point, advance fixed.Int26_6, ok bool)
+
+	// IndexGlyphAdvance is like GlyphAdvance, for the glyph with the given
+	// index.
+	IndexGlyphAdvance(i Index) (advance fixed.Int26_6, ok bool)
+
+	// IndexKern is like Kern, for the glyphs with the given indexes.
+	IndexKern(i0, i1 Index) fixed.Int26_6
+}
+
+var _ IndexFace = (*face)(nil)
+
 // NewFace returns a new font.Face for the given Font.
+//
+// If the font has an embedded bitmap strike whose pixels per em match the
+// face's size, then the face draws the strike's bitmaps instead of the
+// glyphs' outlines. Otherwise, the face draws the font's COLR color glyphs
+// with the palette that the options select, and if the font has sbix
+// strikes, the images of the nearest strike, scaled to the face's size. The
+// mask of a color glyph is an *image.RGBA.
 func NewFace(f *Font, opts *Options) font.Face {
+	if opts != nil && len(opts.Variations) != 0 {
+		f = f.Instance(opts.Variations)
+	}
 	a := &face{
 		f:          f,
 		hinting:    opts.hinting(),
@@ -185,6 +260,15 @@ func NewFace(f *Font, opts *Options) font.Face {
 	}
 	a.subPixelX, a.subPixelBiasX, a.subPixelMaskX = opts.subPixelsX()
 	a.subPixelY, a.subPixelBiasY, a.subPixelMaskY = opts.subPixelsY()
+	if opts != nil {
+		a.palette, a.foreground = opts.Palette, opts.Foreground
+		a.vertical = opts.Vertical
+		a.glyphBuf.SubpixelHinting = opts.SubpixelHinting
+		a.glyphBuf.AutoHinting = opts.AutoHinting
+	}
+	if ppem := int(a.scale >> 6); a.scale&0x3f == 0 && f.hasBitmapStrike(ppem) {
+		a.bitmapPPEM = ppem
+	}
 
 	// Fill the cache with invalid entries. Valid glyph cache entries have fx
 	// and fy in the range [0, 64). Valid index cache entries have rune >= 0.
@@ -229,11 +313,26 @@ type face struct {
 	maxh          int
 	glyphBuf      GlyphBuf
 	indexCache    [indexCacheLen]indexCacheEntry
+	// bitmapPPEM is the pixels per em of the embedded bitmap strike that
+	// matches the face's size, or 0 if there is none.
+	bitmapPPEM  int
+	bitmapCache [bitmapCacheLen]bitmapCacheEntry
+	// palette and foreground are the colors of COLR glyphs.
+	palette    int
+	foreground color.Color
+	// vertical is whether the face lays text out top-to-bottom.
+	vertical bool
+	// metrics are the face's metrics, computed on the first call to
+	// Metrics.
+	metrics *font.Metrics
 
 	// TODO: clip rectangle?
 }
 
-const indexCacheLen = 256
+const (
+	indexCacheLen  = 256
+	bitmapCacheLen = 64
+)
 
 func (a *face) index(r rune) Index {
 	const mask = indexCacheLen - 1
@@ -242,31 +341,122 @@ func (a *face) index(r rune) Index {
 		return c.index
 	}
 	i := a.f.Index(r)
+	if a.vertical {
+		i = a.f.SubstituteGlyphs([]GlyphInfo{{Index: i}}, &LayoutOptions{
+			NoDefaultFeatures: true,
+			Vertical:          true,
+		})[0].Index
+	}
 	c.rune = r
 	c.index = i
 	return i
 }
 
+// bitmap returns the glyph's embedded bitmap or color glyph at the face's
+// size, or nil if it has none, in which case the face draws the glyph's
+// outline.
+func (a *face) bitmap(index Index) *Bitmap {
+	if a.bitmapPPEM == 0 && a.f.colrTable == nil && len(a.f.sbixStrikes) == 0 {
+		return nil
+	}
+	c := &a.bitmapCache[index&(bitmapCacheLen-1)]
+	if !c.valid || c.index != index {
+		// A bad bitmap is treated as a missing one.
+		var b *Bitmap
+		if a.bitmapPPEM != 0 {
+			b, _ = a.f.GlyphBitmap(a.bitmapPPEM, index)
+		}
+		if b == nil {
+			b, _ = a.f.ColorGlyph(a.scale, index, a.palette, a.foreground)
+		}
+		if b == nil && len(a.f.sbixStrikes) != 0 {
+			if b, _ = a.f.SbixBitmap(int((a.scale+32)>>6), index); b != nil {
+				b = a.scaleBitmap(b, index)
+			}
+		}
+		if b != nil && a.vertical {
+			b = a.verticalBitmap(b, index)
+		}
+		*c = bitmapCacheEntry{true, index, b}
+	}
+	return c.bitmap
+}
+
+// scaleBitmap returns the sbix bitmap scaled from its strike's size to the
+// face's size.
+func (a *face) scaleBitmap(b *Bitmap, index Index) *Bitmap {
+	k := float64(a.scale) / float64(b.PPEM<<6)
+	round := func(x int) int {
+		return int(math.Floor(float64(x)*k + 0.5))
+	}
+	size := b.Image.Bounds().Size()
+	w, h := round(size.X), round(size.Y)
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	return &Bitmap{
+		Image:   scaleImage(b.Image.(*image.RGBA), w, h),
+		Left:    round(b.Left),
+		Top:     round(b.Top),
+		Advance: int((a.f.HMetric(a.scale, index).AdvanceWidth + 32) >> 6),
+		PPEM:    int((a.scale + 32) >> 6),
+	}
+}
+
+// verticalBitmap returns the bitmap with its offsets measured from the
+// glyph's vertical origin, and its advance height as its advance.
+func (a *face) verticalBitmap(b *Bitmap, index Index) *Bitmap {
+	if err := a.glyphBuf.Load(a.f, a.scale, index, a.hinting); err != nil {
+		return nil
+	}
+	o := a.glyphBuf.VerticalOrigin
+	return &Bitmap{
+		Image:   b.Image,
+		Left:    b.Left - int((o.X+32)>>6),
+		Top:     b.Top - int((o.Y+32)>>6),
+		Advance: int((a.glyphBuf.AdvanceHeight + 32) >> 6),
+		PPEM:    b.PPEM,
+	}
+}
+
 // Close satisfies the font.Face interface.
 func (a *face) Close() error { return nil }
 
 // Metrics satisfies the font.Face interface.
+//
+// The x-height and cap height are from the OS/2 table or, if it does not have
+// them, from the tops of the 'x' and 'H' glyphs.
 func (a *face) Metrics() font.Metrics {
-	scale := float64(a.scale)
-	fupe := float64(a.f.FUnitsPerEm())
-	return font.Metrics{
-		Height:  a.scale,
-		Ascent:  fixed.Int26_6(math.Ceil(scale * float64(+a.f.ascent) / fupe)),
-		Descent: fixed.Int26_6(math.Ceil(scale * float64(-a.f.descent) / fupe)),
+	if a.metrics == nil {
+		scale := float64(a.scale)
+		fupe := float64(a.f.FUnitsPerEm())
+		xHeight, capHeight := a.f.heights()
+		a.metrics = &font.Metrics{
+			Height:    a.scale,
+			Ascent:    fixed.Int26_6(math.Ceil(scale * float64(+a.f.ascent) / fupe)),
+			Descent:   fixed.Int26_6(math.Ceil(scale * float64(-a.f.descent) / fupe)),
+			XHeight:   fixed.Int26_6(math.Ceil(scale * float64(xHeight) / fupe)),
+			CapHeight: fixed.Int26_6(math.Ceil(scale * float64(capHeight) / fupe)),
+		}
 	}
+	return *a.metrics
 }
 
 // Kern satisfies the font.Face interface.
 func (a *face) Kern(r0, r1 rune) fixed.Int26_6 {
-	i0 := a.index(r0)
-	i1 := a.index(r1)
+	return a.IndexKern(a.index(r0), a.index(r1))
+}
+
+// IndexKern satisfies the IndexFace interface.
+func (a *face) IndexKern(i0, i1 Index) fixed.Int26_6 {
+	if a.vertical {
+		return 0
+	}
 	kern := a.f.Kern(a.scale, i0, i1)
-	if a.hinting != font.HintingNone {
+	if a.hinting == font.HintingFull {
 		kern = (kern + 32) &^ 63
 	}
 	return kern
@@ -276,6 +466,23 @@ func (a *face) Kern(r0, r1 rune) fixed.Int26_6 {
 func (a *face) Glyph(dot fixed.Point26_6, r rune) (
 	dr image.Rectangle, mask image.Image, maskp image.Point, advance fixed.Int26_6, ok bool) {
 
+	return a.IndexGlyph(dot, a.index(r))
+}
+
+// IndexGlyph satisfies the IndexFace interface.
+func (a *face) IndexGlyph(dot fixed.Point26_6, index Index) (
+	dr image.Rectangle, mask image.Image, maskp image.Point, advance fixed.Int26_6, ok bool) {
+
+	if b := a.bitmap(index); b != nil {
+		bb := b.Image.Bounds()
+		dr.Min = image.Point{
+			X: int((dot.X+32)>>6) + b.Left,
+			Y: int((dot.Y+32)>>6) - b.Top,
+		}
+		dr.Max = dr.Min.Add(bb.Size())
+		return dr, b.Image, bb.Min, fixed.I(b.Advance), true
+	}
+
 	// Quantize to the sub-pixel granularity.
 	dotX := (dot.X + a.subPixelBiasX) & a.subPixelMaskX
 	dotY := (dot.Y + a.subPixelBiasY) & a.subPixelMaskY
@@ -284,7 +491,6 @@ func (a *face) Glyph(dot fixed.Point26_6, r rune) (
 	ix, fx := int(dotX>>6), dotX&0x3f
 	iy, fy := int(dotY>>6), dotY&0x3f
 
-	index := a.index(r)
 	cIndex := uint32(index)
 	cIndex = cIndex*a.subPixelX - uint32(fx/a.subPixelMaskX)
 	cIndex = cIndex*a.subPixelY - uint32(fy/a.subPixelMaskY)
@@ -319,7 +525,21 @@ func (a *face) Glyph(dot fixed.Point26_6, r rune) (
 }
 
 func (a *face) GlyphBounds(r rune) (bounds fixed.Rectangle26_6, advance fixed.Int26_6, ok bool) {
-	if err := a.glyphBuf.Load(a.f, a.scale, a.index(r), a.hinting); err != nil {
+	if b := a.bitmap(a.index(r)); b != nil {
+		size := b.Image.Bounds().Size()
+		return fixed.Rectangle26_6{
+			Min: fixed.Point26_6{
+				X: fixed.I(b.Left),
+				Y: fixed.I(-b.Top),
+			},
+			Max: fixed.Point26_6{
+				X: fixed.I(b.Left + size.X),
+				Y: fixed.I(size.Y - b.Top),
+			},
+		}, fixed.I(b.Advance), true
+	}
+	advance, err := a.load(a.index(r))
+	if err != nil {
 		return fixed.Rectangle26_6{}, 0, false
 	}
 	xmin := +a.glyphBuf.Bounds.Min.X
@@ -338,14 +558,39 @@ func (a *face) GlyphBounds(r rune) (bounds fixed.Rectangle26_6, advance fixed.In
 			X: xmax,
 			Y: ymax,
 		},
-	}, a.glyphBuf.AdvanceWidth, true
+	}, advance, true
 }
 
 func (a *face) GlyphAdvance(r rune) (advance fixed.Int26_6, ok bool) {
-	if err := a.glyphBuf.Load(a.f, a.scale, a.index(r), a.hinting); err != nil {
-		return 0, false
+	return a.IndexGlyphAdvance(a.index(r))
+}
+
+// IndexGlyphAdvance satisfies the IndexFace interface.
+func (a *face) IndexGlyphAdvance(index Index) (advance fixed.Int26_6, ok bool) {
+	if b := a.bitmap(index); b != nil {
+		return fixed.I(b.Advance), true
 	}
-	return a.glyphBuf.AdvanceWidth, true
+	advance, err := a.load(index)
+	return advance, err == nil
+}
+
+// load loads the glyph into a.glyphBuf and returns its advance. For a
+// vertical face, the glyph's points and bounds are moved so that its vertical
+// origin is at (0, 0), and the advance is its advance height.
+func (a *face) load(index Index) (advance fixed.Int26_6, err error) {
+	if err := a.glyphBuf.Load(a.f, a.scale, index, a.hinting); err != nil {
+		return 0, err
+	}
+	if !a.vertical {
+		return a.glyphBuf.AdvanceWidth, nil
+	}
+	o := a.glyphBuf.VerticalOrigin
+	for i := range a.glyphBuf.Points {
+		a.glyphBuf.Points[i].X -= o.X
+		a.glyphBuf.Points[i].Y -= o.Y
+	}
+	a.glyphBuf.Bounds = a.glyphBuf.Bounds.Sub(o)
+	return a.glyphBuf.AdvanceHeight, nil
 }
 
 // rasterize returns the advance width, integer-pixel offset to render at, and
@@ -353,7 +598,8 @@ func (a *face) GlyphAdvance(r rune) (advance fixed.Int26_6, ok bool) {
 //
 // The 26.6 fixed point arguments fx and fy must be in the range [0, 1).
 func (a *face) rasterize(index Index, fx, fy fixed.Int26_6) (v glyphCacheVal, ok bool) {
-	if err := a.glyphBuf.Load(a.f, a.scale, index, a.hinting); err != nil {
+	advance, err := a.load(index)
+	if err != nil {
 		return glyphCacheVal{}, false
 	}
 	// Calculate the integer-pixel bounds for the glyph.
@@ -382,7 +628,7 @@ func (a *face) rasterize(index Index, fx, fy fixed.Int26_6) (v glyphCacheVal, ok
 	}
 	a.r.Rasterize(a.p)
 	return glyphCacheVal{
-		a.glyphBuf.AdvanceWidth,
+		advance,
 		image.Point{xmin, ymin},
 		xmax - xmin,
 		ymax - ymin,
@@ -397,32 +643,38 @@ func clear(pix []byte) {
 
 // drawContour draws the given closed contour with the given offset.
 func (a *face) drawContour(ps []Point, dx, dy fixed.Int26_6) {
+	drawContour(&a.r, ps, func(p Point) fixed.Point26_6 {
+		return fixed.Point26_6{
+			X: dx + p.X,
+			Y: dy - p.Y,
+		}
+	})
+}
+
+// drawContour adds the contour's curves to the Adder, transforming each
+// point, which is measured with positive Y going upwards, to the Adder's
+// co-ordinates.
+func drawContour(a raster.Adder, ps []Point, transform func(Point) fixed.Point26_6) {
 	if len(ps) == 0 {
 		return
 	}
 
 	// The low bit of each point's Flags value is whether the point is on the
-	// curve. Truetype fonts only have quadratic Bézier curves, not cubics.
+	// curve. Truetype outlines only have quadratic Bézier curves, not cubics.
 	// Thus, two consecutive off-curve points imply an on-curve point in the
-	// middle of those two.
+	// middle of those two. CFF outlines only have cubic Bézier curves, whose
+	// off-curve points have the FlagCubic bit set and come in pairs.
 	//
 	// See http://chanae.walon.org/pub/ttf/ttf_glyphs.htm for more details.
 
 	// ps[0] is a truetype.Point measured in FUnits and positive Y going
-	// upwards. start is the same thing measured in fixed point units and
-	// positive Y going downwards, and offset by (dx, dy).
-	start := fixed.Point26_6{
-		X: dx + ps[0].X,
-		Y: dy - ps[0].Y,
-	}
+	// upwards. start is the same thing, transformed.
+	start := transform(ps[0])
 	var others []Point
 	if ps[0].Flags&0x01 != 0 {
 		others = ps[1:]
 	} else {
-		last := fixed.Point26_6{
-			X: dx + ps[len(ps)-1].X,
-			Y: dy - ps[len(ps)-1].Y,
-		}
+		last := transform(ps[len(ps)-1])
 		if ps[len(ps)-1].Flags&0x01 != 0 {
 			start = last
 			others = ps[:len(ps)-1]
@@ -434,20 +686,24 @@ func (a *face) drawContour(ps []Point, dx, dy fixed.Int26_6) {
 			others = ps
 		}
 	}
-	a.r.Start(start)
-	q0, on0 := start, true
+	a.Start(start)
+	q0, on0, cubic0 := start, true, false
+	// ctrl is the first of a pair of cubic control points.
+	var ctrl fixed.Point26_6
 	for _, p := range others {
-		q := fixed.Point26_6{
-			X: dx + p.X,
-			Y: dy - p.Y,
-		}
+		q := transform(p)
 		on := p.Flags&0x01 != 0
+		cubic := !on && p.Flags&FlagCubic != 0
 		if on {
 			if on0 {
-				a.r.Add1(q)
+				a.Add1(q)
+			} else if cubic0 {
+				a.Add3(ctrl, q0, q)
 			} else {
-				a.r.Add2(q0, q)
+				a.Add2(q0, q)
 			}
+		} else if cubic {
+			ctrl = q0
 		} else {
 			if on0 {
 				// No-op.
@@ -456,16 +712,18 @@ func (a *face) drawContour(ps []Point, dx, dy fixed.Int26_6) {
 					X: (q0.X + q.X) / 2,
 					Y: (q0.Y + q.Y) / 2,
 				}
-				a.r.Add2(q0, mid)
+				a.Add2(q0, mid)
 			}
 		}
-		q0, on0 = q, on
+		q0, on0, cubic0 = q, on, cubic
 	}
 	// Close the curve.
 	if on0 {
-		a.r.Add1(start)
+		a.Add1(start)
+	} else if cubic0 {
+		a.Add3(ctrl, q0, start)
 	} else {
-		a.r.Add2(q0, start)
+		a.Add2(q0, start)
 	}
 }
 
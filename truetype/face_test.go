@@ -46,3 +46,71 @@ func BenchmarkDrawString(b *testing.B) {
 		}
 	}
 }
+
+func TestVerticalFace(t *testing.T) {
+	base, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The vert feature substitutes b for a.
+	gsub := makeTestLayoutTable(
+		nil,
+		[]string{"vert"},
+		[][]int{{0}},
+		[][]byte{cat(be16(2, 8, 1, int(base.Index('b'))), be16(1, 1, int(base.Index('a'))))},
+		[]int{gsubSingle},
+	)
+	f := makeTestFont(t, map[string][]byte{"GSUB": gsub})
+	A, a, b := f.Index('A'), f.Index('a'), f.Index('b')
+
+	// Glyph A's vertical origin is above it by its top side bearing of 553,
+	// and its advance height is 2465, in FUnits.
+	fupe := fixed.Int26_6(f.FUnitsPerEm())
+	g := &GlyphBuf{}
+	if err := g.Load(f, fupe, A, font.HintingNone); err != nil {
+		t.Fatal(err)
+	}
+	if want := (fixed.Point26_6{X: 683, Y: 1480 + 553}); g.VerticalOrigin != want {
+		t.Errorf("VerticalOrigin: got %v, want %v", g.VerticalOrigin, want)
+	}
+	if g.AdvanceHeight != 2465 {
+		t.Errorf("AdvanceHeight: got %v, want 2465", g.AdvanceHeight)
+	}
+
+	if got := f.Substitute([]rune("a"), &LayoutOptions{Vertical: true}); len(got) != 1 || got[0] != b {
+		t.Errorf("Substitute: got %v, want [%d]", got, b)
+	}
+	if got := f.Substitute([]rune("a"), nil); len(got) != 1 || got[0] != a {
+		t.Errorf("Substitute: got %v, want [%d]", got, a)
+	}
+
+	face := NewFace(f, &Options{Size: 40, Vertical: true})
+	dot := fixed.P(100, 100)
+	dr, _, _, advance, ok := face.Glyph(dot, 'A')
+	if !ok {
+		t.Fatal("Glyph: not ok")
+	}
+	if err := g.Load(f, fixed.I(40), A, font.HintingNone); err != nil {
+		t.Fatal(err)
+	}
+	if advance != g.AdvanceHeight {
+		t.Errorf("Glyph: got advance %v, want %v", advance, g.AdvanceHeight)
+	}
+	// The glyph hangs below the dot, centered on it.
+	if dr.Min.Y <= 100 || dr.Min.X >= 100 || dr.Max.X <= 100 {
+		t.Errorf("Glyph: got dr %v, want below and around (100, 100)", dr)
+	}
+	if got, want := (dr.Min.X+dr.Max.X)/2, 100; got < want-1 || want+1 < got {
+		t.Errorf("Glyph: got center X %d, want %d", got, want)
+	}
+
+	// A vertical face uses the vert feature's alternates.
+	boundsA, _, _ := face.GlyphBounds('a')
+	boundsB, _, _ := face.GlyphBounds('b')
+	if boundsA != boundsB {
+		t.Errorf("GlyphBounds: got %v for 'a', want %v, as for 'b'", boundsA, boundsB)
+	}
+	if kern := face.Kern('A', 'V'); kern != 0 {
+		t.Errorf("Kern: got %v, want 0", kern)
+	}
+}
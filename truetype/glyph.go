@@ -10,14 +10,14 @@ import (
 	"golang.org/x/image/math/fixed"
 )
 
-// TODO: implement VerticalHinting.
-
 // A Point is a co-ordinate pair plus whether it is 'on' a contour or an 'off'
 // control point.
 type Point struct {
 	X, Y fixed.Int26_6
 	// The Flags' LSB means whether or not this Point is 'on' the contour.
-	// Other bits are reserved for internal use.
+	// For fonts with CFF outlines, the FlagCubic bit of an 'off' control
+	// point means that it is one of a pair of cubic, not quadratic, Bézier
+	// control points. Other bits are reserved for internal use.
 	Flags uint32
 }
 
@@ -26,6 +26,14 @@ type Point struct {
 type GlyphBuf struct {
 	// AdvanceWidth is the glyph's advance width.
 	AdvanceWidth fixed.Int26_6
+	// AdvanceHeight is the glyph's advance height, for vertical layout.
+	AdvanceHeight fixed.Int26_6
+	// VerticalOrigin is the glyph's origin for vertical layout, relative to
+	// its origin for horizontal layout, with positive Y going upwards. It is
+	// horizontally centered on the glyph's advance width, and above the
+	// glyph by its top side bearing, unless the font's VORG table says
+	// otherwise.
+	VerticalOrigin fixed.Point26_6
 	// Bounds is the glyph's bounding box.
 	Bounds fixed.Rectangle26_6
 	// Points contains all Points from all contours of the glyph. If hinting
@@ -38,11 +46,35 @@ type GlyphBuf struct {
 	// consists of points Points[Ends[i-1]:Ends[i]], where Ends[-1] is
 	// interpreted to mean zero.
 	Ends []int
+	// SubpixelHinting is whether Load hints glyphs like C Freetype's v40
+	// interpreter, for text that is positioned at fractional X co-ordinates.
+	// Unless the font asks for native ClearType hinting, its programs then
+	// run in backwards compatibility mode, which ignores moves in X, and
+	// moves in Y after IUP[x] and IUP[y], and ignores DELTAP on untouched
+	// points, so that the glyph's shape and advance width are preserved.
+	// Unlike the other fields, it is set by the caller, before calling Load.
+	SubpixelHinting bool
+	// AutoHinting is whether Load hints glyphs with an automatic hinter,
+	// which snaps the glyph's stems, and its edges at the font's baseline,
+	// x-height and cap-height, to the pixel grid, instead of running the
+	// font's bytecode. It suits fonts without TrueType instructions. With
+	// font.HintingVertical, only Y co-ordinates are hinted. Like
+	// SubpixelHinting, it is set by the caller, before calling Load.
+	AutoHinting bool
+	// Trace, if non-nil, is called after every instruction of the font's
+	// hinting bytecode that Load runs, for debugging the font's hinting. The
+	// font's fpgm and prep programs are traced when they are run, which is
+	// when the GlyphBuf first loads a glyph from that font and at that
+	// scale. The HintingStep, and its slices, are only valid during the call.
+	// Like SubpixelHinting, Trace is set by the caller, before calling Load.
+	Trace func(*HintingStep)
 
 	font    *Font
 	scale   fixed.Int26_6
 	hinting font.Hinting
 	hinter  hinter
+	// autohinter is the automatic hinter, used if AutoHinting is set.
+	autohinter autohinter
 	// phantomPoints are the co-ordinates of the synthetic phantom points
 	// used for hinting and bounding box calculations.
 	phantomPoints [4]Point
@@ -55,6 +87,9 @@ type GlyphBuf struct {
 	metricsSet bool
 	// tmp is a scratch buffer.
 	tmp []Point
+	// unhintedX is a scratch buffer of the unhinted X co-ordinates, for
+	// vertical hinting.
+	unhintedX []fixed.Int26_6
 }
 
 // Flags for decoding a glyph's contours. These flags are documented at
@@ -82,7 +117,68 @@ const (
 // Load loads a glyph's contours from a Font, overwriting any previously loaded
 // contours for this GlyphBuf. scale is the number of 26.6 fixed point units in
 // 1 em, i is the glyph index, and h is the hinting policy.
+//
+// font.HintingVertical snaps the glyph's Y co-ordinates to the pixel grid, as
+// font.HintingFull does, but leaves its X co-ordinates, advance width and
+// horizontal bounds unhinted, for sub-pixel positioning.
 func (g *GlyphBuf) Load(f *Font, scale fixed.Int26_6, i Index, h font.Hinting) error {
+	if g.AutoHinting && h != font.HintingNone {
+		return g.loadAutohinted(f, scale, i, h)
+	}
+	if h != font.HintingVertical {
+		return g.loadHinted(f, scale, i, h)
+	}
+	// Load the glyph unhinted, and then fully hinted, keeping the hinted Y
+	// co-ordinates and the unhinted X co-ordinates.
+	if err := g.loadHinted(f, scale, i, font.HintingNone); err != nil {
+		return err
+	}
+	g.unhintedX = g.unhintedX[:0]
+	for _, p := range g.Points {
+		g.unhintedX = append(g.unhintedX, p.X)
+	}
+	advanceWidth, bounds, originX := g.AdvanceWidth, g.Bounds, g.VerticalOrigin.X
+	if err := g.loadHinted(f, scale, i, font.HintingFull); err != nil {
+		return err
+	}
+	if len(g.Points) != len(g.unhintedX) {
+		return FormatError("inconsistent glyph points")
+	}
+	for j, x := range g.unhintedX {
+		g.Points[j].X = x
+	}
+	g.AdvanceWidth = advanceWidth
+	g.VerticalOrigin.X = originX
+	g.Bounds.Min.X, g.Bounds.Max.X = bounds.Min.X, bounds.Max.X
+	g.hinting = font.HintingVertical
+	return nil
+}
+
+// loadAutohinted is like Load, for automatic hinting.
+func (g *GlyphBuf) loadAutohinted(f *Font, scale fixed.Int26_6, i Index, h font.Hinting) error {
+	if err := g.loadHinted(f, scale, i, font.HintingNone); err != nil {
+		return err
+	}
+	bounds := g.Bounds
+	if err := g.autohinter.hint(g, h == font.HintingFull); err != nil {
+		return err
+	}
+	if h == font.HintingFull {
+		g.AdvanceWidth = (g.AdvanceWidth + 32) &^ 63
+		g.VerticalOrigin.X = (g.AdvanceWidth/2 + 32) &^ 63
+	}
+	g.AdvanceHeight = (g.AdvanceHeight + 32) &^ 63
+	g.VerticalOrigin.Y = (g.VerticalOrigin.Y + 32) &^ 63
+	g.setBounds(true)
+	if h != font.HintingFull {
+		g.Bounds.Min.X, g.Bounds.Max.X = bounds.Min.X, bounds.Max.X
+	}
+	g.hinting = h
+	return nil
+}
+
+// loadHinted is like Load, for no or full hinting.
+func (g *GlyphBuf) loadHinted(f *Font, scale fixed.Int26_6, i Index, h font.Hinting) error {
 	g.Points = g.Points[:0]
 	g.Unhinted = g.Unhinted[:0]
 	g.InFontUnits = g.InFontUnits[:0]
@@ -95,9 +191,15 @@ func (g *GlyphBuf) Load(f *Font, scale fixed.Int26_6, i Index, h font.Hinting) e
 	g.metricsSet = false
 
 	if h != font.HintingNone {
-		if err := g.hinter.init(f, scale); err != nil {
+		g.hinter.trace = g.Trace
+		if err := g.hinter.init(f, scale, g.SubpixelHinting); err != nil {
 			return err
 		}
+		// The font's prep program can turn off grid-fitting, such as at
+		// small sizes.
+		if g.hinter.defaultGS.instructControl&instructControlInhibitGridFitting != 0 {
+			h, g.hinting = font.HintingNone, font.HintingNone
+		}
 	}
 	if err := g.load(0, i, true); err != nil {
 		return err
@@ -117,7 +219,9 @@ func (g *GlyphBuf) Load(f *Font, scale fixed.Int26_6, i Index, h font.Hinting) e
 
 	advanceWidth := g.phantomPoints[1].X - g.phantomPoints[0].X
 	if h != font.HintingNone {
-		if len(f.hdmx) >= 8 {
+		// In backwards compatibility mode, the hdmx table's device-specific
+		// advance widths are ignored, as for C Freetype's v40 interpreter.
+		if len(f.hdmx) >= 8 && !g.hinter.backwardCompatible() {
 			if n := u32(f.hdmx, 4); n > 3+uint32(i) {
 				for hdmx := f.hdmx[8:]; uint32(len(hdmx)) >= n; hdmx = hdmx[n:] {
 					if fixed.Int26_6(hdmx[0]) == scale>>6 {
@@ -131,6 +235,26 @@ func (g *GlyphBuf) Load(f *Font, scale fixed.Int26_6, i Index, h font.Hinting) e
 	}
 	g.AdvanceWidth = advanceWidth
 
+	// The third and fourth phantom points are the top and bottom of the
+	// glyph's vertical advance.
+	g.AdvanceHeight = g.phantomPoints[2].Y - g.phantomPoints[3].Y
+	g.VerticalOrigin = fixed.Point26_6{X: advanceWidth / 2, Y: g.phantomPoints[2].Y}
+	if y, ok := f.vertOriginY(i); ok {
+		g.VerticalOrigin.Y = f.scale(scale * y)
+	}
+	if h != font.HintingNone {
+		g.AdvanceHeight = (g.AdvanceHeight + 32) &^ 63
+		g.VerticalOrigin.X = (g.VerticalOrigin.X + 32) &^ 63
+		g.VerticalOrigin.Y = (g.VerticalOrigin.Y + 32) &^ 63
+	}
+
+	g.setBounds(h != font.HintingNone)
+	return nil
+}
+
+// setBounds sets g.Bounds from g.Points, snapping it to the grid if snap is
+// true.
+func (g *GlyphBuf) setBounds(snap bool) {
 	// Set g.Bounds to the 'control box', which is the bounding box of the
 	// Bézier curves' control points. This is easier to calculate, no smaller
 	// than and often equal to the tightest possible bounding box of the curves
@@ -158,7 +282,7 @@ func (g *GlyphBuf) Load(f *Font, scale fixed.Int26_6, i Index, h font.Hinting) e
 			}
 		}
 		// Snap the box to the grid, if hinting is on.
-		if h != font.HintingNone {
+		if snap {
 			g.Bounds.Min.X &^= 63
 			g.Bounds.Min.Y &^= 63
 			g.Bounds.Max.X += 63
@@ -167,7 +291,6 @@ func (g *GlyphBuf) Load(f *Font, scale fixed.Int26_6, i Index, h font.Hinting) e
 			g.Bounds.Max.Y &^= 63
 		}
 	}
-	return nil
 }
 
 func (g *GlyphBuf) load(recursion uint32, i Index, useMyMetrics bool) (err error) {
@@ -175,6 +298,9 @@ func (g *GlyphBuf) load(recursion uint32, i Index, useMyMetrics bool) (err error
 	if recursion >= 32 {
 		return UnsupportedError("excessive compound glyph recursion")
 	}
+	if g.font.cffFont != nil {
+		return g.loadCFF(i)
+	}
 	// Find the relevant slice of g.font.glyf.
 	var g0, g1 uint32
 	if g.font.locaOffsetFormat == locaOffsetFormatShort {
@@ -206,6 +332,9 @@ func (g *GlyphBuf) load(recursion uint32, i Index, useMyMetrics bool) (err error
 		{X: uhm.AdvanceWidth / 2, Y: boundsYMax + uvm.TopSideBearing - uvm.AdvanceHeight},
 	}
 	if len(glyf) == 0 {
+		if err := g.font.vary(i, g.phantomPoints[:], nil); err != nil {
+			return err
+		}
 		g.addPhantomsAndScale(len(g.Points), len(g.Points), true, true)
 		copy(g.phantomPoints[:], g.Points[len(g.Points)-4:])
 		g.Points = g.Points[:len(g.Points)-4]
@@ -220,17 +349,40 @@ func (g *GlyphBuf) load(recursion uint32, i Index, useMyMetrics bool) (err error
 			// "the values -2, -3, and so forth, are reserved for future use."
 			return UnsupportedError("negative number of contours")
 		}
-		pp1x = g.font.scale(g.scale * (boundsXMin - uhm.LeftSideBearing))
-		if err := g.loadCompound(recursion, uhm, i, glyf, useMyMetrics); err != nil {
+		// Each of the compound glyph's components is a point for the
+		// purposes of gvar, whose deltas offset the component.
+		var deltas []Point
+		if len(g.font.coords) != 0 {
+			deltas = make([]Point, countComponents(glyf)+4)
+			copy(deltas[len(deltas)-4:], g.phantomPoints[:])
+			if err := g.font.vary(i, deltas, nil); err != nil {
+				return err
+			}
+			copy(g.phantomPoints[:], deltas[len(deltas)-4:])
+			deltas = deltas[:len(deltas)-4]
+		}
+		pp1x = g.font.scale(g.scale * g.phantomPoints[0].X)
+		if err := g.loadCompound(recursion, uhm, i, glyf, useMyMetrics, deltas); err != nil {
 			return err
 		}
 	} else {
 		np0, ne0 := len(g.Points), len(g.Ends)
 		program := g.loadSimple(glyf, ne)
+		if len(g.font.coords) != 0 {
+			// Apply the gvar deltas to the points in FUnits, including the
+			// phantom points, before they are scaled and hinted.
+			g.Points = append(g.Points, g.phantomPoints[:]...)
+			if err := g.font.vary(i, g.Points[np0:], g.Ends[ne0:]); err != nil {
+				return err
+			}
+			copy(g.phantomPoints[:], g.Points[len(g.Points)-4:])
+			g.Points = g.Points[:len(g.Points)-4]
+		}
 		g.addPhantomsAndScale(np0, np0, true, true)
 		pp1x = g.Points[len(g.Points)-4].X
 		if g.hinting != font.HintingNone {
 			if len(program) != 0 {
+				g.hinter.composite = false
 				err := g.hinter.run(
 					program,
 					g.Points[np0:],
@@ -266,6 +418,45 @@ func (g *GlyphBuf) load(recursion uint32, i Index, useMyMetrics bool) (err error
 	return nil
 }
 
+// loadCFF is like load, for fonts with CFF outlines. Such fonts have no
+// compound glyphs and no TrueType hinting instructions.
+func (g *GlyphBuf) loadCFF(i Index) error {
+	if err := g.font.cffFont.load(g, i); err != nil {
+		return err
+	}
+
+	// CFF glyphs have no nominal bounding box, so use the control box.
+	boundsXMin, boundsYMax := fixed.Int26_6(0), fixed.Int26_6(0)
+	for j, p := range g.Points {
+		if j == 0 || boundsXMin > p.X {
+			boundsXMin = p.X
+		}
+		if j == 0 || boundsYMax < p.Y {
+			boundsYMax = p.Y
+		}
+	}
+
+	// Create the phantom points, scale and drop them, as per load.
+	uhm := g.font.unscaledHMetric(i)
+	uvm := g.font.unscaledVMetric(i, boundsYMax)
+	g.phantomPoints = [4]Point{
+		{X: boundsXMin - uhm.LeftSideBearing},
+		{X: boundsXMin - uhm.LeftSideBearing + uhm.AdvanceWidth},
+		{X: uhm.AdvanceWidth / 2, Y: boundsYMax + uvm.TopSideBearing},
+		{X: uhm.AdvanceWidth / 2, Y: boundsYMax + uvm.TopSideBearing - uvm.AdvanceHeight},
+	}
+	g.addPhantomsAndScale(0, 0, true, true)
+	if g.hinting != font.HintingNone {
+		g.InFontUnits = g.InFontUnits[:len(g.InFontUnits)-4]
+		g.Unhinted = g.Unhinted[:len(g.Unhinted)-4]
+	}
+	copy(g.phantomPoints[:], g.Points[len(g.Points)-4:])
+	g.Points = g.Points[:len(g.Points)-4]
+	g.metricsSet = true
+	g.pp1x = g.phantomPoints[0].X
+	return nil
+}
+
 // loadOffset is the initial offset for loadSimple and loadCompound. The first
 // 10 bytes are the number of contours and the bounding box.
 const loadOffset = 10
@@ -345,8 +536,39 @@ func (g *GlyphBuf) loadSimple(glyf []byte, ne int) (program []byte) {
 	return program
 }
 
+// countComponents returns the number of components of a compound glyph.
+func countComponents(glyf []byte) (n int) {
+	const (
+		flagArg1And2AreWords   = 1 << 0
+		flagWeHaveAScale       = 1 << 3
+		flagMoreComponents     = 1 << 5
+		flagWeHaveAnXAndYScale = 1 << 6
+		flagWeHaveATwoByTwo    = 1 << 7
+	)
+	for offset := loadOffset; offset+2 <= len(glyf); {
+		flags := u16(glyf, offset)
+		n++
+		offset += 6
+		if flags&flagArg1And2AreWords != 0 {
+			offset += 2
+		}
+		switch {
+		case flags&flagWeHaveAScale != 0:
+			offset += 2
+		case flags&flagWeHaveAnXAndYScale != 0:
+			offset += 4
+		case flags&flagWeHaveATwoByTwo != 0:
+			offset += 8
+		}
+		if flags&flagMoreComponents == 0 {
+			break
+		}
+	}
+	return n
+}
+
 func (g *GlyphBuf) loadCompound(recursion uint32, uhm HMetric, i Index,
-	glyf []byte, useMyMetrics bool) error {
+	glyf []byte, useMyMetrics bool, deltas []Point) error {
 
 	// Flags for decoding a compound glyph. These flags are documented at
 	// http://developer.apple.com/fonts/TTRefMan/RM06/Chap6glyf.html.
@@ -365,7 +587,7 @@ func (g *GlyphBuf) loadCompound(recursion uint32, uhm HMetric, i Index,
 	)
 	np0, ne0 := len(g.Points), len(g.Ends)
 	offset := loadOffset
-	for {
+	for k := 0; ; k++ {
 		flags := u16(glyf, offset)
 		component := Index(u16(glyf, offset+2))
 		dx, dy, transform, hasTransform := fixed.Int26_6(0), fixed.Int26_6(0), [4]int16{}, false
@@ -381,6 +603,10 @@ func (g *GlyphBuf) loadCompound(recursion uint32, uhm HMetric, i Index,
 		if flags&flagArgsAreXYValues == 0 {
 			return UnsupportedError("compound glyph transform vector")
 		}
+		if k < len(deltas) {
+			dx += deltas[k].X
+			dy += deltas[k].Y
+		}
 		if flags&(flagWeHaveAScale|flagWeHaveAnXAndYScale|flagWeHaveATwoByTwo) != 0 {
 			hasTransform = true
 			switch {
@@ -469,6 +695,7 @@ func (g *GlyphBuf) loadCompound(recursion uint32, uhm HMetric, i Index,
 	// Hinting instructions of a composite glyph completely refer to the
 	// (already) hinted subglyphs.
 	g.tmp = append(g.tmp[:0], points...)
+	g.hinter.composite = true
 	if err := g.hinter.run(program, points, g.tmp, g.tmp, ends); err != nil {
 		return err
 	}
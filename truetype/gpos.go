@@ -0,0 +1,230 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+// This file implements the GPOS table's pair adjustment and mark attachment
+// lookups, as described at
+// https://www.microsoft.com/typography/otspec/gpos.htm
+
+import (
+	"sort"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// GPOS lookup types.
+const (
+	gposPair     = 2
+	gposMarkBase = 4
+	gposMarkMark = 6
+	gposExt      = 9
+)
+
+// parseGPOS parses the GPOS table. The table is optional, so a malformed
+// table is ignored, and the font falls back to its kern table and to no mark
+// positioning.
+func (f *Font) parseGPOS() {
+	if len(f.gpos) == 0 {
+		return
+	}
+	t, err := parseLayoutTable(f.gpos, "GPOS", gposExt)
+	if err != nil {
+		return
+	}
+	f.gposTable = t
+	// Kern and MarkAttachment take no script or language system, so they use
+	// the default ones' lookups, as Substitute does without LayoutOptions.
+	// The abvm and blwm features are the mark features of Indic scripts.
+	for _, l := range t.lookups("", "", map[string]int{"kern": 1}, nil) {
+		f.kernLookups = append(f.kernLookups, l.index)
+	}
+	marks := map[string]int{"mark": 1, "mkmk": 1, "abvm": 1, "blwm": 1}
+	for _, l := range t.lookups("", "", marks, nil) {
+		f.markLookups = append(f.markLookups, l.index)
+	}
+}
+
+// gposKern returns the GPOS pair adjustment, in FUnits, for the given glyph
+// pair. ok is whether the font has GPOS kerning, in which case any kern
+// table is ignored.
+func (f *Font) gposKern(i0, i1 Index) (kern int, ok bool) {
+	if len(f.kernLookups) == 0 {
+		return 0, false
+	}
+	for _, l := range f.kernLookups {
+		lookupType, _, subtables := f.gposTable.lookup(l)
+		if lookupType != gposPair {
+			continue
+		}
+		for _, b := range subtables {
+			if v, ok := pairAdjustment(b, i0, i1); ok {
+				kern += v
+				break
+			}
+		}
+	}
+	return kern, true
+}
+
+// valueRecordSize returns the size of a ValueRecord with the given format.
+func valueRecordSize(format uint16) int {
+	return 2 * bitCount(format&0xff)
+}
+
+// bitCount returns the number of set bits in x.
+func bitCount(x uint16) (n int) {
+	for ; x != 0; x &= x - 1 {
+		n++
+	}
+	return n
+}
+
+// xAdvance returns the XAdvance field of the ValueRecord b with the given
+// format.
+func xAdvance(b []byte, format uint16) int {
+	const (
+		xPlacement = 0x0001
+		yPlacement = 0x0002
+		xAdv       = 0x0004
+	)
+	if format&xAdv == 0 {
+		return 0
+	}
+	return int(int16(u16(b, 2*bitCount(format&(xPlacement|yPlacement)))))
+}
+
+// pairAdjustment returns the horizontal adjustment that the PairPos subtable
+// b applies between the given glyphs, which is the XAdvance of the first
+// glyph's ValueRecord. ok is whether the subtable applies to the pair.
+func pairAdjustment(b []byte, i0, i1 Index) (v int, ok bool) {
+	if len(b) < 10 {
+		return 0, false
+	}
+	c, ok := coverageIndex(offsetTable(b, 2), i0)
+	if !ok {
+		return 0, false
+	}
+	vf1, vf2 := u16(b, 4), u16(b, 6)
+	s1, s2 := valueRecordSize(vf1), valueRecordSize(vf2)
+	switch u16(b, 0) {
+	case 1:
+		if c >= int(u16(b, 8)) {
+			return 0, false
+		}
+		set := offsetTable(b, 10+2*c)
+		if set == nil {
+			return 0, false
+		}
+		n, size := int(u16(set, 0)), 2+s1+s2
+		if len(set) < 2+n*size {
+			return 0, false
+		}
+		g := uint16(i1)
+		j := sort.Search(n, func(j int) bool { return u16(set, 2+size*j) >= g })
+		if j < n && u16(set, 2+size*j) == g {
+			return xAdvance(set[2+size*j+2:], vf1), true
+		}
+	case 2:
+		if len(b) < 16 {
+			return 0, false
+		}
+		c1 := classDef(offsetTable(b, 8), i0)
+		c2 := classDef(offsetTable(b, 10), i1)
+		n1, n2 := int(u16(b, 12)), int(u16(b, 14))
+		if c1 >= n1 || c2 >= n2 {
+			return 0, false
+		}
+		x := 16 + (c1*n2+c2)*(s1+s2)
+		if len(b) < x+s1+s2 {
+			return 0, false
+		}
+		return xAdvance(b[x:], vf1), true
+	}
+	return 0, false
+}
+
+// MarkAttachment returns the position of the mark glyph's origin relative to
+// the base glyph's origin, as given by the font's GPOS mark-to-base and
+// mark-to-mark attachment lookups. For mark-to-mark attachment, base is the
+// preceding mark. As for GlyphBuf's Points, the Y axis points up. ok is
+// whether the font attaches the mark to the base.
+//
+// A lookup whose flags ignore the base or the mark, such as one that only
+// attaches marks of another mark attachment class, does not apply.
+func (f *Font) MarkAttachment(scale fixed.Int26_6, base, mark Index) (p fixed.Point26_6, ok bool) {
+	for _, l := range f.markLookups {
+		lookupType, flags, subtables := f.gposTable.lookup(l)
+		if lookupType != gposMarkBase && lookupType != gposMarkMark {
+			continue
+		}
+		if f.ignored(base, flags) || f.ignored(mark, flags) {
+			continue
+		}
+		for _, b := range subtables {
+			if dx, dy, ok := markAttachment(b, base, mark); ok {
+				return fixed.Point26_6{
+					X: f.scale(scale * fixed.Int26_6(dx)),
+					Y: f.scale(scale * fixed.Int26_6(dy)),
+				}, true
+			}
+		}
+	}
+	return fixed.Point26_6{}, false
+}
+
+// markAttachment returns the offset, in FUnits, from the base to the mark
+// that the MarkBasePos or MarkMarkPos subtable b gives. Both subtables have
+// the same layout, with the second (Mark2) array of a MarkMarkPos subtable
+// taking the place of the BaseArray.
+func markAttachment(b []byte, base, mark Index) (dx, dy int, ok bool) {
+	if len(b) < 12 || u16(b, 0) != 1 {
+		return 0, 0, false
+	}
+	mi, ok := coverageIndex(offsetTable(b, 2), mark)
+	if !ok {
+		return 0, 0, false
+	}
+	bi, ok := coverageIndex(offsetTable(b, 4), base)
+	if !ok {
+		return 0, 0, false
+	}
+	classCount := int(u16(b, 6))
+	markArray, baseArray := offsetTable(b, 8), offsetTable(b, 10)
+	if markArray == nil || baseArray == nil {
+		return 0, 0, false
+	}
+	if mi >= int(u16(markArray, 0)) || len(markArray) < 2+4*(mi+1) {
+		return 0, 0, false
+	}
+	class := int(u16(markArray, 2+4*mi))
+	if class >= classCount {
+		return 0, 0, false
+	}
+	mx, my, ok := anchor(offsetTable(markArray, 2+4*mi+2))
+	if !ok {
+		return 0, 0, false
+	}
+	if bi >= int(u16(baseArray, 0)) || len(baseArray) < 2+2*classCount*(bi+1) {
+		return 0, 0, false
+	}
+	bx, by, ok := anchor(offsetTable(baseArray, 2+2*(bi*classCount+class)))
+	if !ok {
+		return 0, 0, false
+	}
+	return bx - mx, by - my, true
+}
+
+// anchor returns the co-ordinates of the Anchor table b. The contour point
+// of format 2 and the Device tables of format 3 are ignored.
+func anchor(b []byte) (x, y int, ok bool) {
+	if len(b) < 6 {
+		return 0, 0, false
+	}
+	if format := u16(b, 0); format < 1 || 3 < format {
+		return 0, 0, false
+	}
+	return int(int16(u16(b, 2))), int(int16(u16(b, 4))), true
+}
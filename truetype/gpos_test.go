@@ -0,0 +1,254 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+import (
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// be16 returns the big-endian encoding of the given 16-bit values.
+func be16(vs ...int) []byte {
+	b := make([]byte, 0, 2*len(vs))
+	for _, v := range vs {
+		b = append(b, byte(v>>8), byte(v))
+	}
+	return b
+}
+
+// cat concatenates byte slices.
+func cat(bs ...[]byte) []byte {
+	var ret []byte
+	for _, b := range bs {
+		ret = append(ret, b...)
+	}
+	return ret
+}
+
+// makeTestLayoutTable returns a GPOS or GSUB table. features maps feature
+// tags, in order, to their lookup indexes, and lookups holds each lookup's
+// type and single subtable. A lookup type's upper 16 bits hold the lookup's
+// flags. A nil scriptList means an empty ScriptList.
+func makeTestLayoutTable(scriptList []byte, features []string, featureLookups [][]int, lookups [][]byte, lookupTypes []int) []byte {
+	if scriptList == nil {
+		scriptList = be16(0)
+	}
+
+	featureList := be16(len(features))
+	offset := 2 + 6*len(features)
+	var featureTables []byte
+	for i, tag := range features {
+		featureList = append(featureList, tag...)
+		featureList = append(featureList, be16(offset+len(featureTables))...)
+		featureTables = append(featureTables, be16(0, len(featureLookups[i]))...)
+		featureTables = append(featureTables, be16(featureLookups[i]...)...)
+	}
+	featureList = append(featureList, featureTables...)
+
+	lookupList := be16(len(lookups))
+	offset = 2 + 2*len(lookups)
+	var lookupTables []byte
+	for i, sub := range lookups {
+		lookupList = append(lookupList, be16(offset+len(lookupTables))...)
+		lookupTables = append(lookupTables, be16(lookupTypes[i]&0xffff, lookupTypes[i]>>16, 1, 8)...)
+		lookupTables = append(lookupTables, sub...)
+	}
+	lookupList = append(lookupList, lookupTables...)
+
+	return cat(
+		be16(1, 0, 10, 10+len(scriptList), 10+len(scriptList)+len(featureList)),
+		scriptList, featureList, lookupList,
+	)
+}
+
+// markPos returns a MarkBasePos or MarkMarkPos subtable that attaches the
+// mark's anchor at (mx, my) to the base's anchor at (bx, by).
+func markPos(base, mark, bx, by, mx, my int) []byte {
+	return cat(
+		be16(1, 12, 18, 1, 24, 36),
+		be16(1, 1, mark),
+		be16(1, 1, base),
+		be16(1, 0, 6), be16(1, mx, my),
+		be16(1, 4), be16(1, bx, by),
+	)
+}
+
+// makeTestGPOSFont returns luxisr.ttf plus a GPOS table, which kerns "AV" by
+// -100 and "To" by -50, attaches x as a mark to e, and y as a mark to x.
+func makeTestGPOSFont(t *testing.T) *Font {
+	f, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	A, V, T, o := int(f.Index('A')), int(f.Index('V')), int(f.Index('T')), int(f.Index('o'))
+	e, x, y := int(f.Index('e')), int(f.Index('x')), int(f.Index('y'))
+
+	// A PairPos format 1 subtable.
+	pair1 := cat(
+		be16(1, 12, 0x0004, 0, 1, 18),
+		be16(1, 1, A),
+		be16(1, V, -100),
+	)
+	// A PairPos format 2 subtable, wrapped in an Extension subtable.
+	pair2 := cat(
+		be16(2, 24, 0x0004, 0, 34, 42, 2, 2),
+		be16(0, 0, 0, -50),
+		be16(2, 1, T, T, 0),
+		be16(1, T, 1, 1),
+		be16(2, 1, o, o, 1),
+	)
+	ext := cat(be16(1, gposPair, 0, 8), pair2)
+	gpos := makeTestLayoutTable(
+		nil,
+		[]string{"kern", "mark", "mkmk"},
+		[][]int{{0, 1}, {2}, {3}},
+		[][]byte{pair1, ext, markPos(e, x, 300, 500, 10, 20), markPos(x, y, 5, 200, 0, 0)},
+		[]int{gposPair, gposExt, gposMarkBase, gposMarkMark},
+	)
+
+	return makeTestFont(t, map[string][]byte{"GPOS": gpos})
+}
+
+func TestGPOSKern(t *testing.T) {
+	f := makeTestGPOSFont(t)
+	fupe := fixed.Int26_6(f.FUnitsPerEm())
+	testCases := []struct {
+		r0, r1 rune
+		want   fixed.Int26_6
+	}{
+		{'A', 'V', -100},
+		{'V', 'A', 0},
+		{'T', 'o', -50},
+		{'T', 'A', 0},
+		{'o', 'T', 0},
+	}
+	for _, tc := range testCases {
+		if got := f.Kern(fupe, f.Index(tc.r0), f.Index(tc.r1)); got != tc.want {
+			t.Errorf("Kern(%q, %q): got %v, want %v", tc.r0, tc.r1, got, tc.want)
+		}
+	}
+
+	// The kern table is ignored for fonts with GPOS kerning.
+	if len(f.kernSubtables) == 0 {
+		t.Fatal("no kern table pairs")
+	}
+	i0, i1 := Index(u16(f.kern, 18)), Index(u16(f.kern, 20))
+	if got := f.Kern(fupe, i0, i1); got != 0 {
+		t.Errorf("kern table pair (%d, %d): got %v, want 0", i0, i1, got)
+	}
+}
+
+func TestMarkAttachment(t *testing.T) {
+	f := makeTestGPOSFont(t)
+	fupe := fixed.Int26_6(f.FUnitsPerEm())
+	testCases := []struct {
+		base, mark rune
+		want       fixed.Point26_6
+		ok         bool
+	}{
+		{'e', 'x', fixed.Point26_6{X: 290, Y: 480}, true},
+		{'x', 'y', fixed.Point26_6{X: 5, Y: 200}, true},
+		{'e', 'y', fixed.Point26_6{}, false},
+		{'o', 'x', fixed.Point26_6{}, false},
+	}
+	for _, tc := range testCases {
+		got, ok := f.MarkAttachment(fupe, f.Index(tc.base), f.Index(tc.mark))
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("MarkAttachment(%q, %q): got %v, %t, want %v, %t",
+				tc.base, tc.mark, got, ok, tc.want, tc.ok)
+		}
+	}
+
+	// The face's Kern does not report mark attachment, which would move the
+	// pen back over the base for every later glyph.
+	face := NewFace(f, &Options{Size: float64(fupe) / 64})
+	if got := face.Kern('e', 'x'); got != 0 {
+		t.Errorf("face.Kern('e', 'x'): got %v, want 0", got)
+	}
+	want := fixed.Int26_6(0)
+	for _, r := range "exa" {
+		want += f.HMetric(fupe, f.Index(r)).AdvanceWidth
+	}
+	if got := font.MeasureString(face, "exa"); got != want {
+		t.Errorf("MeasureString(%q): got %v, want %v", "exa", got, want)
+	}
+}
+
+func TestGPOSLookups(t *testing.T) {
+	g, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	A, V := int(g.Index('A')), int(g.Index('V'))
+	e, x, y := int(g.Index('e')), int(g.Index('x')), int(g.Index('y'))
+	if y != x+1 {
+		t.Fatalf("glyph indexes: x=%d, y=%d are not consecutive", x, y)
+	}
+	pair := func(v int) []byte {
+		return cat(be16(1, 12, 0x0004, 0, 1, 18), be16(1, 1, A), be16(1, V, v))
+	}
+	scriptList := cat(
+		be16(2), []byte("DFLT"), be16(14), []byte("grek"), be16(28),
+		// The DFLT script's default language system uses features 0 and 2.
+		be16(4, 0), be16(0, 0xffff, 2, 0, 2),
+		// The grek script's default language system uses feature 1.
+		be16(4, 0), be16(0, 0xffff, 1, 1),
+	)
+	gpos := makeTestLayoutTable(
+		scriptList,
+		[]string{"kern", "kern", "mark"},
+		[][]int{{0}, {1}, {2, 3}},
+		[][]byte{pair(-100), pair(-200), markPos(e, x, 300, 500, 0, 0), markPos(e, y, 300, 500, 0, 0)},
+		// The mark lookups only attach marks of mark attachment class 1.
+		[]int{gposPair, gposPair, gposMarkBase | 1<<24, gposMarkBase | 1<<24},
+	)
+	// The GDEF table makes x and y marks, of mark attachment classes 2 and 1.
+	gdef := cat(be16(1, 0, 12, 0, 0, 22), be16(1, x, 2, 3, 3), be16(1, x, 2, 2, 1))
+	f := makeTestFont(t, map[string][]byte{"GDEF": gdef, "GPOS": gpos})
+
+	fupe := fixed.Int26_6(f.FUnitsPerEm())
+	if got, want := f.Kern(fupe, Index(A), Index(V)), fixed.Int26_6(-100); got != want {
+		t.Errorf("Kern(A, V): got %v, want %v", got, want)
+	}
+	if _, ok := f.MarkAttachment(fupe, Index(e), Index(x)); ok {
+		t.Errorf("MarkAttachment(e, x): got ok, want not ok")
+	}
+	if got, ok := f.MarkAttachment(fupe, Index(e), Index(y)); !ok || got != (fixed.Point26_6{X: 300, Y: 500}) {
+		t.Errorf("MarkAttachment(e, y): got %v, %t, want (300, 500), true", got, ok)
+	}
+}
+
+func TestGPOSBadTables(t *testing.T) {
+	g, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fupe := fixed.Int26_6(g.FUnitsPerEm())
+	e, x := g.Index('e'), g.Index('x')
+	i0, i1 := Index(u16(g.kern, 18)), Index(u16(g.kern, 20))
+	want := g.Kern(fupe, i0, i1)
+	if want == 0 {
+		t.Fatalf("kern table pair (%d, %d): got 0, want non-zero", i0, i1)
+	}
+
+	// A malformed GPOS table is ignored, so the font falls back to its kern
+	// table, and has no mark positioning.
+	f := makeTestFont(t, map[string][]byte{"GPOS": be16(1, 0, 0, 10)})
+	if got := f.Kern(fupe, i0, i1); got != want {
+		t.Errorf("bad GPOS: kern table pair (%d, %d): got %v, want %v", i0, i1, got, want)
+	}
+	if _, ok := f.MarkAttachment(fupe, e, x); ok {
+		t.Errorf("bad GPOS: MarkAttachment(e, x): got ok, want not ok")
+	}
+
+	// A malformed GDEF table is ignored, so the glyphs are unclassified.
+	f = makeTestFont(t, map[string][]byte{"GDEF": be16(1, 0, 12)})
+	if got := f.GlyphClass(x); got != 0 {
+		t.Errorf("bad GDEF: GlyphClass(x): got %d, want 0", got)
+	}
+}
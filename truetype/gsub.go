@@ -0,0 +1,807 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+// This file implements the GSUB table's glyph substitution lookups, as
+// described at https://www.microsoft.com/typography/otspec/gsub.htm
+
+// A Feature is an OpenType layout feature, such as "liga" for standard
+// ligatures or "smcp" for small capitals.
+type Feature struct {
+	// Tag is the feature's four byte tag.
+	Tag string
+	// Value is 0 to disable the feature and 1 to enable it. For features
+	// with alternate substitutions, such as "salt", a value of n selects the
+	// n'th alternate.
+	Value int
+	// Mask, if non-zero, restricts the feature to those glyphs whose
+	// GlyphInfo.Mask has any of the same bits set. Shapers use this to apply
+	// positional features, such as Arabic "init" and "fina", to some glyphs
+	// but not others.
+	Mask uint32
+}
+
+// GlyphInfo is a glyph in a run of glyphs passed to Font.SubstituteGlyphs.
+type GlyphInfo struct {
+	// Index is the glyph index.
+	Index Index
+	// Cluster is the index of the first rune that the glyph was produced
+	// from. Substitutions preserve clusters: a ligature takes the smallest
+	// cluster of its components, and a glyph that is replaced by several
+	// glyphs passes its cluster to each of them.
+	Cluster int
+	// Mask selects which masked features apply to the glyph.
+	Mask uint32
+}
+
+// LayoutOptions are options for Font.Substitute.
+type LayoutOptions struct {
+	// Script is the OpenType script tag, such as "latn" or "arab".
+	//
+	// An empty value means to use the font's default script.
+	Script string
+
+	// Language is the OpenType language system tag, such as "TRK" for
+	// Turkish.
+	//
+	// An empty value means to use the script's default language system.
+	Language string
+
+	// Features enable or disable features, in addition to the default
+	// features: ccmp, locl, rlig, liga, clig and calt.
+	Features []Feature
+
+	// NoDefaultFeatures is whether to disable the default features, so that
+	// only those listed in Features are enabled.
+	NoDefaultFeatures bool
+
+	// Vertical is whether the text is laid out top-to-bottom, which enables
+	// the vert feature's vertical alternates, such as rotated brackets.
+	Vertical bool
+}
+
+// defaultFeatures are the features that Substitute enables by default.
+var defaultFeatures = []string{"ccmp", "locl", "rlig", "liga", "clig", "calt"}
+
+// GSUB lookup types.
+const (
+	gsubSingle    = 1
+	gsubMultiple  = 2
+	gsubAlternate = 3
+	gsubLigature  = 4
+	gsubContext   = 5
+	gsubChain     = 6
+	gsubExt       = 7
+)
+
+// maxNestedLookups limits the recursion depth of contextual lookups, which
+// defends against malformed fonts.
+const maxNestedLookups = 8
+
+// parseGSUB parses the GSUB table. The table is optional, so a malformed table
+// is ignored, and the font makes no substitutions.
+func (f *Font) parseGSUB() {
+	if len(f.gsub) == 0 {
+		return
+	}
+	t, err := parseLayoutTable(f.gsub, "GSUB", gsubExt)
+	if err != nil {
+		return
+	}
+	f.gsubTable = t
+}
+
+// parseGDEF parses the GDEF table. The table is optional, so a table that is
+// too short is ignored, and the font's glyphs are left unclassified.
+func (f *Font) parseGDEF() {
+	if len(f.gdef) < 12 {
+		return
+	}
+	f.glyphClasses = offsetTable(f.gdef, 4)
+	f.markAttachClasses = offsetTable(f.gdef, 10)
+}
+
+// GlyphClass returns the glyph's class in the font's GDEF table: 1 for base
+// glyphs, 2 for ligatures, 3 for marks and 4 for ligature components. It
+// returns 0 if the font does not classify the glyph.
+func (f *Font) GlyphClass(i Index) int {
+	return classDef(f.glyphClasses, i)
+}
+
+// Substitute returns the glyphs for the given runes, after applying the
+// font's GSUB substitutions, such as ligatures and alternates, for the
+// selected script, language system and features. The result may have more
+// or fewer glyphs than there are runes. A nil o means to use the default
+// script, language system and features.
+//
+// For a font without a GSUB table, Substitute maps each rune to a glyph, as
+// Index does. A rune followed by a variation selector maps to a glyph as
+// IndexVariation does.
+func (f *Font) Substitute(s []rune, o *LayoutOptions) []Index {
+	glyphs := make([]GlyphInfo, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		g := GlyphInfo{Index: f.Index(s[i]), Cluster: i}
+		if i+1 < len(s) && IsVariationSelector(s[i+1]) {
+			g.Index = f.IndexVariation(s[i], s[i+1])
+			i++
+		}
+		glyphs = append(glyphs, g)
+	}
+	glyphs = f.SubstituteGlyphs(glyphs, o)
+	ret := make([]Index, len(glyphs))
+	for i, g := range glyphs {
+		ret[i] = g.Index
+	}
+	return ret
+}
+
+// SubstituteGlyphs is like Substitute, but it substitutes an existing run of
+// glyphs, tracking each glyph's cluster and applying masked features only to
+// the glyphs that they select. It may modify the given slice.
+func (f *Font) SubstituteGlyphs(glyphs []GlyphInfo, o *LayoutOptions) []GlyphInfo {
+	if f.gsubTable == nil {
+		return glyphs
+	}
+	if o == nil {
+		o = &LayoutOptions{}
+	}
+	values := map[string]int{}
+	masks := map[string]uint32{}
+	if !o.NoDefaultFeatures {
+		for _, tag := range defaultFeatures {
+			values[tag] = 1
+		}
+	}
+	if o.Vertical {
+		values["vert"] = 1
+	}
+	for _, ft := range o.Features {
+		tag := padTag(ft.Tag)
+		values[tag] = ft.Value
+		masks[tag] = ft.Mask
+	}
+	for _, l := range f.gsubTable.lookups(o.Script, o.Language, values, masks) {
+		glyphs = f.applyLookup(glyphs, l)
+	}
+	return glyphs
+}
+
+// HasScript returns whether the font's GSUB or GPOS table has lookups for
+// the given OpenType script tag, such as "dev2".
+func (f *Font) HasScript(script string) bool {
+	for _, t := range []*layoutTable{f.gsubTable, f.gposTable} {
+		if t != nil && t.script(script) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// padTag pads an OpenType tag, such as "TRK", with trailing spaces to be
+// four bytes long.
+func padTag(tag string) string {
+	for len(tag) < 4 {
+		tag += " "
+	}
+	return tag
+}
+
+// layoutLookup is a lookup to apply, and the value and mask of the feature
+// that selected it.
+type layoutLookup struct {
+	index, value int
+	mask         uint32
+}
+
+// langSys returns the LangSys table for the given script and language
+// system, falling back to the default script and language system. It
+// returns nil if there is no such LangSys table.
+func (t *layoutTable) langSys(script, lang string) []byte {
+	var s []byte
+	for _, tag := range []string{script, "DFLT", "dflt", "latn"} {
+		if tag == "" {
+			continue
+		}
+		if s = t.script(tag); s != nil {
+			break
+		}
+	}
+	if len(s) < 4 {
+		return nil
+	}
+	if lang != "" {
+		if ls := findTagged(s, 4, int(u16(s, 2)), padTag(lang)); ls != nil {
+			return ls
+		}
+	}
+	return offsetTable(s, 0)
+}
+
+// script returns the Script table for the given script tag, or nil.
+func (t *layoutTable) script(tag string) []byte {
+	sl := t.scriptList
+	if len(sl) < 2 {
+		return nil
+	}
+	return findTagged(sl, 2, int(u16(sl, 0)), padTag(tag))
+}
+
+// findTagged returns the table of the record with the given tag, out of the n
+// six byte tag and offset records at b[x:].
+func findTagged(b []byte, x, n int, tag string) []byte {
+	for i := 0; i < n && len(b) >= x+6*i+6; i++ {
+		if string(b[x+6*i:x+6*i+4]) == tag {
+			return offsetTable(b, x+6*i+4)
+		}
+	}
+	return nil
+}
+
+// feature returns the tag and lookup indexes of the i'th feature.
+func (t *layoutTable) feature(i int) (tag string, lookups []int) {
+	fl := t.featureList
+	if len(fl) < 2 || int(u16(fl, 0)) <= i || len(fl) < 8+6*i {
+		return "", nil
+	}
+	x := 2 + 6*i
+	b := offsetTable(fl, x+4)
+	if len(b) < 4 {
+		return "", nil
+	}
+	n := int(u16(b, 2))
+	if len(b) < 4+2*n {
+		return "", nil
+	}
+	lookups = make([]int, n)
+	for j := range lookups {
+		lookups[j] = int(u16(b, 4+2*j))
+	}
+	return string(fl[x : x+4]), lookups
+}
+
+// lookups returns the lookups, in LookupList order, of the features of the
+// given script and language system whose values are non-zero. A font
+// without a ScriptList uses every feature.
+func (t *layoutTable) lookups(script, lang string, values map[string]int, masks map[string]uint32) []layoutLookup {
+	var features []int
+	required := -1
+	if ls := t.langSys(script, lang); ls != nil {
+		if len(ls) < 6 {
+			return nil
+		}
+		if r := u16(ls, 2); r != 0xffff {
+			required = int(r)
+			features = append(features, required)
+		}
+		n := int(u16(ls, 4))
+		for j := 0; j < n && len(ls) >= 8+2*j; j++ {
+			features = append(features, int(u16(ls, 6+2*j)))
+		}
+	} else if len(t.scriptList) < 2 || u16(t.scriptList, 0) == 0 {
+		if len(t.featureList) >= 2 {
+			for j := 0; j < int(u16(t.featureList, 0)); j++ {
+				features = append(features, j)
+			}
+		}
+	}
+
+	var ret []layoutLookup
+	seen := map[int]bool{}
+	for _, i := range features {
+		tag, lookups := t.feature(i)
+		value, mask := values[tag], masks[tag]
+		if i == required {
+			value, mask = 1, 0
+		}
+		if value == 0 {
+			continue
+		}
+		for _, l := range lookups {
+			if !seen[l] {
+				seen[l] = true
+				ret = append(ret, layoutLookup{l, value, mask})
+			}
+		}
+	}
+	// Sort by lookup index. The slices are short, so insertion sort is fine.
+	for i := 1; i < len(ret); i++ {
+		for j := i; j > 0 && ret[j].index < ret[j-1].index; j-- {
+			ret[j], ret[j-1] = ret[j-1], ret[j]
+		}
+	}
+	return ret
+}
+
+// Lookup flags.
+const (
+	lookupIgnoreBaseGlyphs   = 0x0002
+	lookupIgnoreLigatures    = 0x0004
+	lookupIgnoreMarks        = 0x0008
+	lookupMarkAttachmentType = 0xff00
+)
+
+// ignored returns whether a lookup with the given flags skips the glyph,
+// based on the glyph's GDEF class.
+func (f *Font) ignored(i Index, flags uint16) bool {
+	if flags&(lookupIgnoreBaseGlyphs|lookupIgnoreLigatures|lookupIgnoreMarks|lookupMarkAttachmentType) == 0 {
+		return false
+	}
+	switch classDef(f.glyphClasses, i) {
+	case 1:
+		return flags&lookupIgnoreBaseGlyphs != 0
+	case 2:
+		return flags&lookupIgnoreLigatures != 0
+	case 3:
+		if flags&lookupIgnoreMarks != 0 {
+			return true
+		}
+		if t := int(flags >> 8); t != 0 {
+			return classDef(f.markAttachClasses, i) != t
+		}
+	}
+	return false
+}
+
+// applyLookup applies the GSUB lookup l at each position of glyphs.
+func (f *Font) applyLookup(glyphs []GlyphInfo, l layoutLookup) []GlyphInfo {
+	lookupType, flags, subtables := f.gsubTable.lookup(l.index)
+	for i := 0; i < len(glyphs); {
+		if f.ignored(glyphs[i].Index, flags) || l.mask != 0 && glyphs[i].Mask&l.mask == 0 {
+			i++
+			continue
+		}
+		n := len(glyphs)
+		g, next, ok := f.applySubtables(glyphs, i, lookupType, flags, subtables, l.value, 0)
+		if !ok {
+			i++
+			continue
+		}
+		glyphs = g
+		// A deletion leaves the next glyph at position i, but a malformed
+		// font must not stall the loop.
+		if next < i || next == i && len(glyphs) >= n {
+			next = i + 1
+		}
+		i = next
+	}
+	return glyphs
+}
+
+// applySubtables applies the first of the lookup's subtables that matches at
+// position i. It returns the new glyphs and the position after the
+// substituted glyphs.
+func (f *Font) applySubtables(glyphs []GlyphInfo, i int, lookupType, flags uint16,
+	subtables [][]byte, value, depth int) ([]GlyphInfo, int, bool) {
+
+	for _, b := range subtables {
+		if g, next, ok := f.applySubtable(glyphs, i, lookupType, flags, b, value, depth); ok {
+			return g, next, true
+		}
+	}
+	return glyphs, i, false
+}
+
+func (f *Font) applySubtable(glyphs []GlyphInfo, i int, lookupType, flags uint16,
+	b []byte, value, depth int) ([]GlyphInfo, int, bool) {
+
+	if len(b) < 4 {
+		return glyphs, i, false
+	}
+	format := u16(b, 0)
+	switch lookupType {
+	case gsubSingle, gsubMultiple, gsubAlternate, gsubLigature:
+		c, ok := coverageIndex(offsetTable(b, 2), glyphs[i].Index)
+		if !ok {
+			return glyphs, i, false
+		}
+		switch lookupType {
+		case gsubSingle:
+			switch format {
+			case 1:
+				if len(b) < 6 {
+					return glyphs, i, false
+				}
+				glyphs[i].Index = Index(uint16(glyphs[i].Index) + u16(b, 4))
+				return glyphs, i + 1, true
+			case 2:
+				if len(b) < 6 || c >= int(u16(b, 4)) || len(b) < 8+2*c {
+					return glyphs, i, false
+				}
+				glyphs[i].Index = Index(u16(b, 6+2*c))
+				return glyphs, i + 1, true
+			}
+		case gsubMultiple, gsubAlternate:
+			if format != 1 || len(b) < 6 || c >= int(u16(b, 4)) {
+				return glyphs, i, false
+			}
+			seq := offsetTable(b, 6+2*c)
+			if seq == nil {
+				return glyphs, i, false
+			}
+			n := int(u16(seq, 0))
+			if len(seq) < 2+2*n {
+				return glyphs, i, false
+			}
+			if lookupType == gsubAlternate {
+				if value < 1 || n < value {
+					return glyphs, i, false
+				}
+				glyphs[i].Index = Index(u16(seq, 2*value))
+				return glyphs, i + 1, true
+			}
+			out := make([]GlyphInfo, 0, len(glyphs)+n-1)
+			out = append(out, glyphs[:i]...)
+			for j := 0; j < n; j++ {
+				g := glyphs[i]
+				g.Index = Index(u16(seq, 2+2*j))
+				out = append(out, g)
+			}
+			out = append(out, glyphs[i+1:]...)
+			return out, i + n, true
+		case gsubLigature:
+			if format != 1 || len(b) < 6 || c >= int(u16(b, 4)) {
+				return glyphs, i, false
+			}
+			return f.applyLigature(glyphs, i, flags, offsetTable(b, 6+2*c))
+		}
+	case gsubContext:
+		return f.applyContextSubtable(glyphs, i, flags, b, value, depth)
+	case gsubChain:
+		return f.applyChainSubtable(glyphs, i, flags, b, value, depth)
+	}
+	return glyphs, i, false
+}
+
+// applyLigature applies the first matching Ligature of the LigatureSet b.
+func (f *Font) applyLigature(glyphs []GlyphInfo, i int, flags uint16, b []byte) ([]GlyphInfo, int, bool) {
+	if b == nil {
+		return glyphs, i, false
+	}
+	n := int(u16(b, 0))
+	if len(b) < 2+2*n {
+		return glyphs, i, false
+	}
+	for j := 0; j < n; j++ {
+		lig := offsetTable(b, 2+2*j)
+		if len(lig) < 4 {
+			continue
+		}
+		count := int(u16(lig, 2))
+		if count < 1 || len(lig) < 4+2*(count-1) {
+			continue
+		}
+		positions, ok := f.matchForward(glyphs, i, count-1, flags, func(k int, g Index) bool {
+			return uint16(g) == u16(lig, 4+2*k)
+		})
+		if !ok {
+			continue
+		}
+		// Replace the first component with the ligature and remove the
+		// others. Skipped glyphs, such as marks, follow the ligature. The
+		// ligature's cluster is the earliest of its components' clusters.
+		g := glyphs[i]
+		g.Index = Index(u16(lig, 0))
+		for _, p := range positions {
+			if g.Cluster > glyphs[p].Cluster {
+				g.Cluster = glyphs[p].Cluster
+			}
+		}
+		out := make([]GlyphInfo, 0, len(glyphs)-len(positions))
+		out = append(out, glyphs[:i]...)
+		out = append(out, g)
+		p := 0
+		for k := i + 1; k < len(glyphs); k++ {
+			if p < len(positions) && positions[p] == k {
+				p++
+				continue
+			}
+			out = append(out, glyphs[k])
+		}
+		return out, i + 1, true
+	}
+	return glyphs, i, false
+}
+
+// matchForward returns the positions of the n glyphs after position i,
+// skipping ignored glyphs, if match returns true for each of them. match's
+// first argument is the glyph's index in the sequence, starting at 0.
+func (f *Font) matchForward(glyphs []GlyphInfo, i, n int, flags uint16, match func(k int, g Index) bool) ([]int, bool) {
+	positions := make([]int, 0, n)
+	for j := i + 1; len(positions) < n; j++ {
+		if j >= len(glyphs) {
+			return nil, false
+		}
+		if f.ignored(glyphs[j].Index, flags) {
+			continue
+		}
+		if !match(len(positions), glyphs[j].Index) {
+			return nil, false
+		}
+		positions = append(positions, j)
+	}
+	return positions, true
+}
+
+// matchBackward is like matchForward, for the n glyphs before position i,
+// in reverse order.
+func (f *Font) matchBackward(glyphs []GlyphInfo, i, n int, flags uint16, match func(k int, g Index) bool) bool {
+	k := 0
+	for j := i - 1; k < n; j-- {
+		if j < 0 {
+			return false
+		}
+		if f.ignored(glyphs[j].Index, flags) {
+			continue
+		}
+		if !match(k, glyphs[j].Index) {
+			return false
+		}
+		k++
+	}
+	return true
+}
+
+// contextRule is a contextual or chaining contextual rule. The match
+// functions are called for the backtrack glyphs, the input glyphs after the
+// first and the lookahead glyphs.
+type contextRule struct {
+	nBacktrack, nInput, nLookahead int
+	backtrack, input, lookahead    func(k int, g Index) bool
+	records                        []byte
+}
+
+// applyRule applies the rule at position i, whose first input glyph has
+// already been matched. It applies the rule's nested lookups to the matched
+// input glyphs.
+func (f *Font) applyRule(glyphs []GlyphInfo, i int, flags uint16, r *contextRule, value, depth int) ([]GlyphInfo, int, bool) {
+	if depth >= maxNestedLookups || r.nInput < 1 {
+		return glyphs, i, false
+	}
+	if r.nBacktrack > 0 && !f.matchBackward(glyphs, i, r.nBacktrack, flags, r.backtrack) {
+		return glyphs, i, false
+	}
+	rest, ok := f.matchForward(glyphs, i, r.nInput-1, flags, r.input)
+	if !ok {
+		return glyphs, i, false
+	}
+	positions := append([]int{i}, rest...)
+	if r.nLookahead > 0 {
+		last := positions[len(positions)-1]
+		if _, ok := f.matchForward(glyphs, last, r.nLookahead, flags, r.lookahead); !ok {
+			return glyphs, i, false
+		}
+	}
+	// A nested lookup may lengthen, shorten or reorder the input glyphs,
+	// such as by moving skipped marks after a ligature, so the positions of
+	// the remaining input glyphs are recomputed after each one. end is the
+	// position after the last input glyph.
+	end := positions[len(positions)-1] + 1
+	for x := 0; x+4 <= len(r.records); x += 4 {
+		seqIndex, lookupIndex := int(u16(r.records, x)), int(u16(r.records, x+2))
+		if seqIndex >= len(positions) || positions[seqIndex] >= len(glyphs) {
+			continue
+		}
+		lookupType, lookupFlags, subtables := f.gsubTable.lookup(lookupIndex)
+		start, tail := positions[seqIndex], len(glyphs)-end
+		g, _, ok := f.applySubtables(glyphs, start, lookupType, lookupFlags, subtables, value, depth+1)
+		if !ok {
+			continue
+		}
+		glyphs = g
+		end = len(glyphs) - tail
+		if end < start {
+			end = start
+		} else if end > len(glyphs) {
+			end = len(glyphs)
+		}
+		positions = positions[:seqIndex]
+		for k := start; k < end; k++ {
+			if k == start || !f.ignored(glyphs[k].Index, flags) {
+				positions = append(positions, k)
+			}
+		}
+	}
+	return glyphs, end, true
+}
+
+// u16Matcher returns a match function that compares the 16-bit values at
+// b[x:] to the result of key.
+func u16Matcher(b []byte, x int, key func(g Index) int) func(k int, g Index) bool {
+	return func(k int, g Index) bool {
+		return key(g) == int(u16(b, x+2*k))
+	}
+}
+
+// coverageMatcher returns a match function that checks the glyphs against
+// the Coverage tables whose offsets, relative to b, are at b[x:].
+func coverageMatcher(b []byte, x int) func(k int, g Index) bool {
+	return func(k int, g Index) bool {
+		_, ok := coverageIndex(offsetTable(b, x+2*k), g)
+		return ok
+	}
+}
+
+func glyphKey(g Index) int { return int(g) }
+
+// applyContextSubtable applies a SequenceContext (type 5) subtable.
+func (f *Font) applyContextSubtable(glyphs []GlyphInfo, i int, flags uint16, b []byte, value, depth int) ([]GlyphInfo, int, bool) {
+	g := glyphs[i].Index
+	switch u16(b, 0) {
+	case 1, 2:
+		c, ok := coverageIndex(offsetTable(b, 2), g)
+		if !ok {
+			return glyphs, i, false
+		}
+		key, x := glyphKey, 6
+		if u16(b, 0) == 2 {
+			cd := offsetTable(b, 4)
+			c, key, x = classDef(cd, g), func(g Index) int { return classDef(cd, g) }, 8
+		}
+		if len(b) < x || c >= int(u16(b, x-2)) {
+			return glyphs, i, false
+		}
+		set := offsetTable(b, x+2*c)
+		if set == nil {
+			return glyphs, i, false
+		}
+		for j := 0; j < int(u16(set, 0)) && len(set) >= 4+2*j; j++ {
+			rule := offsetTable(set, 2+2*j)
+			if len(rule) < 4 {
+				continue
+			}
+			nInput, nRecords := int(u16(rule, 0)), int(u16(rule, 2))
+			x := 4 + 2*(nInput-1)
+			if nInput < 1 || len(rule) < x+4*nRecords {
+				continue
+			}
+			r := &contextRule{
+				nInput:  nInput,
+				input:   u16Matcher(rule, 4, key),
+				records: rule[x : x+4*nRecords],
+			}
+			if out, next, ok := f.applyRule(glyphs, i, flags, r, value, depth); ok {
+				return out, next, true
+			}
+		}
+	case 3:
+		if len(b) < 6 {
+			return glyphs, i, false
+		}
+		nInput, nRecords := int(u16(b, 2)), int(u16(b, 4))
+		x := 6 + 2*nInput
+		if nInput < 1 || len(b) < x+4*nRecords {
+			return glyphs, i, false
+		}
+		if _, ok := coverageIndex(offsetTable(b, 6), g); !ok {
+			return glyphs, i, false
+		}
+		r := &contextRule{
+			nInput:  nInput,
+			input:   coverageMatcher(b, 8),
+			records: b[x : x+4*nRecords],
+		}
+		return f.applyRule(glyphs, i, flags, r, value, depth)
+	}
+	return glyphs, i, false
+}
+
+// applyChainSubtable applies a ChainedSequenceContext (type 6) subtable.
+func (f *Font) applyChainSubtable(glyphs []GlyphInfo, i int, flags uint16, b []byte, value, depth int) ([]GlyphInfo, int, bool) {
+	g := glyphs[i].Index
+	switch u16(b, 0) {
+	case 1, 2:
+		c, ok := coverageIndex(offsetTable(b, 2), g)
+		if !ok {
+			return glyphs, i, false
+		}
+		bKey, iKey, lKey, x := glyphKey, glyphKey, glyphKey, 6
+		if u16(b, 0) == 2 {
+			if len(b) < 12 {
+				return glyphs, i, false
+			}
+			bcd, icd, lcd := offsetTable(b, 4), offsetTable(b, 6), offsetTable(b, 8)
+			bKey = func(g Index) int { return classDef(bcd, g) }
+			iKey = func(g Index) int { return classDef(icd, g) }
+			lKey = func(g Index) int { return classDef(lcd, g) }
+			c, x = classDef(icd, g), 12
+		}
+		if len(b) < x || c >= int(u16(b, x-2)) {
+			return glyphs, i, false
+		}
+		set := offsetTable(b, x+2*c)
+		if set == nil {
+			return glyphs, i, false
+		}
+		for j := 0; j < int(u16(set, 0)) && len(set) >= 4+2*j; j++ {
+			r, ok := parseChainRule(offsetTable(set, 2+2*j), bKey, iKey, lKey)
+			if !ok {
+				continue
+			}
+			if out, next, ok := f.applyRule(glyphs, i, flags, r, value, depth); ok {
+				return out, next, true
+			}
+		}
+	case 3:
+		r, ok := parseChainCoverages(b)
+		if !ok {
+			return glyphs, i, false
+		}
+		if _, ok := coverageIndex(offsetTable(b, 4+2*r.nBacktrack+2), g); !ok {
+			return glyphs, i, false
+		}
+		return f.applyRule(glyphs, i, flags, r, value, depth)
+	}
+	return glyphs, i, false
+}
+
+// parseChainRule parses a ChainedSequenceRule or ChainedClassSequenceRule:
+// the backtrack, input and lookahead sequences, each preceded by its length,
+// and then the SequenceLookupRecords. The input sequence omits the first
+// glyph, which the rule set's coverage or class has already matched.
+func parseChainRule(b []byte, bKey, iKey, lKey func(Index) int) (*contextRule, bool) {
+	if len(b) < 2 {
+		return nil, false
+	}
+	r := &contextRule{}
+	x := 0
+	r.nBacktrack = int(u16(b, x))
+	r.backtrack = u16Matcher(b, x+2, bKey)
+	x += 2 + 2*r.nBacktrack
+	if len(b) < x+2 {
+		return nil, false
+	}
+	r.nInput = int(u16(b, x))
+	r.input = u16Matcher(b, x+2, iKey)
+	x += 2 + 2*(r.nInput-1)
+	if r.nInput < 1 || len(b) < x+2 {
+		return nil, false
+	}
+	r.nLookahead = int(u16(b, x))
+	r.lookahead = u16Matcher(b, x+2, lKey)
+	x += 2 + 2*r.nLookahead
+	return r.parseRecords(b, x)
+}
+
+// parseChainCoverages parses a ChainedSequenceContext format 3 subtable,
+// whose sequences are Coverage offsets. Its input sequence includes the
+// first glyph.
+func parseChainCoverages(b []byte) (*contextRule, bool) {
+	if len(b) < 4 {
+		return nil, false
+	}
+	r := &contextRule{}
+	x := 2
+	r.nBacktrack = int(u16(b, x))
+	r.backtrack = coverageMatcher(b, x+2)
+	x += 2 + 2*r.nBacktrack
+	if len(b) < x+2 {
+		return nil, false
+	}
+	r.nInput = int(u16(b, x))
+	r.input = coverageMatcher(b, x+4)
+	x += 2 + 2*r.nInput
+	if r.nInput < 1 || len(b) < x+2 {
+		return nil, false
+	}
+	r.nLookahead = int(u16(b, x))
+	r.lookahead = coverageMatcher(b, x+2)
+	x += 2 + 2*r.nLookahead
+	return r.parseRecords(b, x)
+}
+
+// parseRecords sets r's SequenceLookupRecords, from the count at b[x:] and
+// the records that follow it.
+func (r *contextRule) parseRecords(b []byte, x int) (*contextRule, bool) {
+	if len(b) < x+2 {
+		return nil, false
+	}
+	n := int(u16(b, x))
+	if len(b) < x+2+4*n {
+		return nil, false
+	}
+	r.records = b[x+2 : x+2+4*n]
+	return r, true
+}
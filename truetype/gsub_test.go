@@ -0,0 +1,232 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+import (
+	"fmt"
+	"testing"
+)
+
+// testGSUB is a GSUB table with each of the substitution lookup types.
+func testGSUB(f *Font) []byte {
+	g := func(r rune) int { return int(f.Index(r)) }
+	single := func(from, to int) []byte {
+		return cat(be16(2, 8, 1, to), be16(1, 1, from))
+	}
+	lookups := [][]byte{
+		// 0: a → b.
+		single(g('a'), g('b')),
+		// 1: f i → Z.
+		cat(be16(1, 8, 1, 14), be16(1, 1, g('f')), be16(1, 4), be16(g('Z'), 2, g('i'))),
+		// 2: x → y z.
+		cat(be16(1, 8, 1, 14), be16(1, 1, g('x')), be16(2, g('y'), g('z'))),
+		// 3: g → h or j.
+		cat(be16(1, 8, 1, 14), be16(1, 1, g('g')), be16(2, g('h'), g('j'))),
+		// 4: u → lookup 5, after q and before e.
+		cat(
+			be16(3, 1, 20, 1, 26, 1, 32, 1, 0, 5),
+			be16(1, 1, g('q')), be16(1, 1, g('u')), be16(1, 1, g('e')),
+		),
+		// 5: u → the next glyph, by delta.
+		cat(be16(1, 6, 1), be16(1, 1, g('u'))),
+		// 6: k l → k, then lookup 7 on l.
+		cat(be16(1, 8, 1, 14), be16(1, 1, g('k')), be16(1, 4), be16(2, 1, g('l'), 1, 7)),
+		// 7: l → m.
+		single(g('l'), g('m')),
+	}
+	return makeTestLayoutTable(
+		nil,
+		[]string{"smcp", "liga", "ccmp", "salt", "calt"},
+		[][]int{{0}, {1}, {2}, {3}, {4, 6}},
+		lookups,
+		[]int{gsubSingle, gsubLigature, gsubMultiple, gsubAlternate, gsubChain, gsubSingle, gsubContext, gsubSingle},
+	)
+}
+
+func TestSubstitute(t *testing.T) {
+	base, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := makeTestFont(t, map[string][]byte{"GSUB": testGSUB(base)})
+	g := func(s string) []Index {
+		var ret []Index
+		for _, r := range s {
+			ret = append(ret, f.Index(r))
+		}
+		return ret
+	}
+	v := f.Index('u') + 1
+	testCases := []struct {
+		s        string
+		features []Feature
+		want     []Index
+	}{
+		{"fix", nil, g("Zyz")},
+		{"fix", []Feature{{Tag: "liga", Value: 0}}, g("fiyz")},
+		{"a", nil, g("a")},
+		{"a", []Feature{{Tag: "smcp", Value: 1}}, g("b")},
+		{"g", nil, g("g")},
+		{"g", []Feature{{Tag: "salt", Value: 1}}, g("h")},
+		{"g", []Feature{{Tag: "salt", Value: 2}}, g("j")},
+		{"g", []Feature{{Tag: "salt", Value: 3}}, g("g")},
+		{"que", nil, []Index{f.Index('q'), v, f.Index('e')}},
+		{"qua", nil, g("qua")},
+		{"ue", nil, g("ue")},
+		{"kl", nil, g("km")},
+		{"l", nil, g("l")},
+	}
+	for _, tc := range testCases {
+		got := f.Substitute([]rune(tc.s), &LayoutOptions{Features: tc.features})
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("%q %v: got %v, want %v", tc.s, tc.features, got, tc.want)
+		}
+	}
+
+	// A malformed GSUB table is ignored, so there are no substitutions.
+	f = makeTestFont(t, map[string][]byte{"GSUB": be16(1, 0, 0, 10)})
+	if got, want := f.Substitute([]rune("fix"), nil), g("fix"); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("bad GSUB: got %v, want %v", got, want)
+	}
+}
+
+func TestSubstituteScripts(t *testing.T) {
+	base, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scriptList := cat(
+		be16(2), []byte("DFLT"), be16(14), []byte("latn"), be16(26),
+		// The DFLT script's default language system uses feature 0.
+		be16(4, 0), be16(0, 0xffff, 1, 0),
+		// The latn script's TRK language system uses feature 1.
+		be16(0, 1), []byte("TRK "), be16(10), be16(0, 0xffff, 1, 1),
+	)
+	a, b, i, Z := int(base.Index('a')), int(base.Index('b')), int(base.Index('i')), int(base.Index('Z'))
+	gsub := makeTestLayoutTable(
+		scriptList,
+		[]string{"liga", "locl"},
+		[][]int{{1}, {0}},
+		[][]byte{
+			cat(be16(2, 8, 1, b), be16(1, 1, a)),
+			cat(be16(2, 8, 1, Z), be16(1, 1, i)),
+		},
+		[]int{gsubSingle, gsubSingle},
+	)
+	f := makeTestFont(t, map[string][]byte{"GSUB": gsub})
+	testCases := []struct {
+		script, lang string
+		want         string
+	}{
+		{"", "", "aZ"},
+		{"cyrl", "", "aZ"},
+		{"latn", "TRK", "bi"},
+		{"latn", "", "ai"},
+	}
+	for _, tc := range testCases {
+		got := f.Substitute([]rune("ai"), &LayoutOptions{Script: tc.script, Language: tc.lang})
+		var want []Index
+		for _, r := range tc.want {
+			want = append(want, f.Index(r))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("script %q, language %q: got %v, want %v", tc.script, tc.lang, got, want)
+		}
+	}
+}
+
+func TestSubstituteGlyphs(t *testing.T) {
+	base, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := makeTestFont(t, map[string][]byte{"GSUB": testGSUB(base)})
+	glyphs := func(s string, masks ...uint32) []GlyphInfo {
+		var ret []GlyphInfo
+		for i, r := range []rune(s) {
+			ret = append(ret, GlyphInfo{Index: f.Index(r), Cluster: i, Mask: masks[i]})
+		}
+		return ret
+	}
+	g := func(r rune, cluster int, mask uint32) GlyphInfo {
+		return GlyphInfo{Index: f.Index(r), Cluster: cluster, Mask: mask}
+	}
+	testCases := []struct {
+		in   []GlyphInfo
+		o    *LayoutOptions
+		want []GlyphInfo
+	}{{
+		glyphs("afix", 0, 1, 2, 3),
+		nil,
+		[]GlyphInfo{g('a', 0, 0), g('Z', 1, 1), g('y', 3, 3), g('z', 3, 3)},
+	}, {
+		glyphs("aa", 1, 2),
+		&LayoutOptions{Features: []Feature{{Tag: "smcp", Value: 1, Mask: 2}}},
+		[]GlyphInfo{g('a', 0, 1), g('b', 1, 2)},
+	}, {
+		glyphs("ax", 0, 0),
+		&LayoutOptions{Features: []Feature{{Tag: "smcp", Value: 1}}, NoDefaultFeatures: true},
+		[]GlyphInfo{g('b', 0, 0), g('x', 1, 0)},
+	}}
+	for i, tc := range testCases {
+		got := f.SubstituteGlyphs(tc.in, tc.o)
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("test case #%d: got %v, want %v", i, got, tc.want)
+		}
+	}
+}
+
+func TestSubstituteSequences(t *testing.T) {
+	base, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := func(r rune) int { return int(base.Index(r)) }
+	gsub := makeTestLayoutTable(
+		nil,
+		[]string{"ccmp", "calt"},
+		[][]int{{0}, {1}},
+		[][]byte{
+			// 0: x → nothing.
+			cat(be16(1, 8, 1, 14), be16(1, 1, g('x')), be16(0)),
+			// 1: f i a → lookup 2 on f, then lookup 3 on a, ignoring
+			// marks.
+			cat(
+				be16(3, 3, 2, 20, 26, 32, 0, 2, 1, 3),
+				be16(1, 1, g('f')), be16(1, 1, g('i')), be16(1, 1, g('a')),
+			),
+			// 2: f i → Z, ignoring marks.
+			cat(be16(1, 8, 1, 14), be16(1, 1, g('f')), be16(1, 4), be16(g('Z'), 2, g('i'))),
+			// 3: a → b.
+			cat(be16(2, 8, 1, g('b')), be16(1, 1, g('a'))),
+		},
+		[]int{gsubMultiple, gsubContext | lookupIgnoreMarks<<16, gsubLigature | lookupIgnoreMarks<<16, gsubSingle},
+	)
+	f := makeTestFont(t, map[string][]byte{"GSUB": gsub})
+	// Treat c as a mark.
+	f.glyphClasses = be16(1, int(f.Index('c')), 1, 3)
+
+	testCases := []struct {
+		s, want string
+	}{
+		{"xx", ""},
+		{"axxb", "ab"},
+		{"fia", "Zb"},
+		// The ligature moves the mark after it, and the second nested lookup
+		// still applies to the a.
+		{"fcia", "Zcb"},
+	}
+	for _, tc := range testCases {
+		got := f.Substitute([]rune(tc.s), nil)
+		var want []Index
+		for _, r := range tc.want {
+			want = append(want, f.Index(r))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%q: got %v, want %v", tc.s, got, want)
+		}
+	}
+}
@@ -35,6 +35,7 @@ type callStackEntry struct {
 	program   []byte
 	pc        int
 	loopCount int32
+	function  int32
 }
 
 // hinter implements bytecode hinting. A hinter can be re-used to hint a series
@@ -64,6 +65,33 @@ type hinter struct {
 	// scaledCVT is the lazily initialized scaled Control Value Table.
 	scaledCVTInitialized bool
 	scaledCVT            []fixed.Int26_6
+
+	// inPrep is whether the font's prep program is running, the only
+	// program that may use INSTCTRL.
+	inPrep bool
+
+	// subpixel is whether the hinter is in subpixel hinting mode, like C
+	// Freetype's v40 interpreter. In that mode, unless the font's prep
+	// program asks for native ClearType hinting, programs run in backwards
+	// compatibility mode: points are never moved in X, and not moved in Y
+	// after IUP has been called on both axes, although they are still marked
+	// as touched. backwardCompat is whether the running program is in that
+	// mode, and iupXCalled and iupYCalled are whether it has called IUP[x]
+	// and IUP[y]. composite is whether the running program is a composite
+	// glyph's.
+	subpixel, backwardCompat bool
+	iupXCalled, iupYCalled   bool
+	composite                bool
+
+	// trace, if non-nil, is called after every instruction with step,
+	// which describes it. program is the table tag of the running program,
+	// stepping is whether step is still to be reported, and before is the
+	// points' positions before the instruction ran.
+	trace    func(*HintingStep)
+	program  string
+	step     HintingStep
+	stepping bool
+	before   [numZone][]Point
 }
 
 // graphicsState is described at https://developer.apple.com/fonts/TTRefMan/RM04/Chap4.html
@@ -85,8 +113,23 @@ type graphicsState struct {
 	roundSuper45                            bool
 	// Auto-flip.
 	autoFlip bool
+	// Instruction control, as set by INSTCTRL.
+	instructControl int32
 }
 
+// These are the instructControl bits, one per INSTCTRL selector.
+const (
+	// instructControlInhibitGridFitting means that glyph programs are not
+	// run, and glyphs are loaded as if unhinted.
+	instructControlInhibitGridFitting = 1 << 0
+	// instructControlIgnoreCVTGS means that glyph programs start with the
+	// global default graphics state, not the one set by the prep program.
+	instructControlIgnoreCVTGS = 1 << 1
+	// instructControlNativeClearType means that the font asks for native
+	// ClearType, and not backwards compatibility, hinting.
+	instructControlNativeClearType = 1 << 2
+)
+
 var globalDefaultGS = graphicsState{
 	pv:                [2]f2dot14{0x4000, 0}, // Unit vector along the X axis.
 	fv:                [2]f2dot14{0x4000, 0},
@@ -115,14 +158,14 @@ func resetTwilightPoints(f *Font, p []Point) []Point {
 	return p
 }
 
-func (h *hinter) init(f *Font, scale fixed.Int26_6) error {
+func (h *hinter) init(f *Font, scale fixed.Int26_6, subpixel bool) error {
 	h.points[twilightZone][0] = resetTwilightPoints(f, h.points[twilightZone][0])
 	h.points[twilightZone][1] = resetTwilightPoints(f, h.points[twilightZone][1])
 	h.points[twilightZone][2] = resetTwilightPoints(f, h.points[twilightZone][2])
 
 	rescale := h.scale != scale
-	if h.font != f {
-		h.font, rescale = f, true
+	if h.font != f || h.subpixel != subpixel {
+		h.font, h.subpixel, rescale = f, subpixel, true
 		if h.functions == nil {
 			h.functions = make(map[int32][]byte)
 		} else {
@@ -142,6 +185,7 @@ func (h *hinter) init(f *Font, scale fixed.Int26_6) error {
 			h.store = make([]int32, x)
 		}
 		if len(f.fpgm) != 0 {
+			h.program = "fpgm"
 			if err := h.run(f.fpgm, nil, nil, nil, nil); err != nil {
 				return err
 			}
@@ -155,10 +199,17 @@ func (h *hinter) init(f *Font, scale fixed.Int26_6) error {
 		h.defaultGS = globalDefaultGS
 
 		if len(f.prep) != 0 {
-			if err := h.run(f.prep, nil, nil, nil, nil); err != nil {
+			h.inPrep, h.program = true, "prep"
+			err := h.run(f.prep, nil, nil, nil, nil)
+			h.inPrep = false
+			if err != nil {
 				return err
 			}
 			h.defaultGS = h.gs
+			if h.gs.instructControl&instructControlIgnoreCVTGS != 0 {
+				h.defaultGS = globalDefaultGS
+				h.defaultGS.instructControl = h.gs.instructControl
+			}
 			// The MS rasterizer doesn't allow the following graphics state
 			// variables to be modified by the CVT program.
 			h.defaultGS.pv = globalDefaultGS.pv
@@ -169,15 +220,18 @@ func (h *hinter) init(f *Font, scale fixed.Int26_6) error {
 			h.defaultGS.loop = globalDefaultGS.loop
 		}
 	}
+	h.program = "glyf"
 	return nil
 }
 
-func (h *hinter) run(program []byte, pCurrent, pUnhinted, pInFontUnits []Point, ends []int) error {
+func (h *hinter) run(program []byte, pCurrent, pUnhinted, pInFontUnits []Point, ends []int) (err error) {
 	h.gs = h.defaultGS
 	h.points[glyphZone][current] = pCurrent
 	h.points[glyphZone][unhinted] = pUnhinted
 	h.points[glyphZone][inFontUnits] = pInFontUnits
 	h.ends = ends
+	h.backwardCompat = h.backwardCompatible()
+	h.iupXCalled, h.iupYCalled = false, false
 
 	if len(program) > 50000 {
 		return errors.New("truetype: hinting: too many instructions")
@@ -185,12 +239,21 @@ func (h *hinter) run(program []byte, pCurrent, pUnhinted, pInFontUnits []Point,
 	var (
 		steps, pc, top int
 		opcode         uint8
+		// function is the number of the running function, or -1.
+		function = int32(-1)
 
 		callStack    [32]callStackEntry
 		callStackTop int
 	)
+	if h.trace != nil {
+		defer func() { h.endStep(top, err) }()
+	}
 
 	for 0 <= pc && pc < len(program) {
+		if h.trace != nil {
+			h.endStep(top, nil)
+			h.beginStep(function, pc, program[pc])
+		}
 		steps++
 		if steps == 100000 {
 			return errors.New("truetype: hinting: too many steps")
@@ -448,11 +511,12 @@ func (h *hinter) run(program []byte, pCurrent, pUnhinted, pInFontUnits []Point,
 				return errors.New("truetype: hinting: call stack overflow")
 			}
 			top--
-			f, ok := h.functions[h.stack[top]]
+			fn := h.stack[top]
+			f, ok := h.functions[fn]
 			if !ok {
 				return errors.New("truetype: hinting: undefined function")
 			}
-			callStack[callStackTop] = callStackEntry{program, pc, 1}
+			callStack[callStackTop] = callStackEntry{program, pc, 1, function}
 			if opcode == opLOOPCALL {
 				top--
 				if h.stack[top] == 0 {
@@ -461,7 +525,7 @@ func (h *hinter) run(program []byte, pCurrent, pUnhinted, pInFontUnits []Point,
 				callStack[callStackTop].loopCount = h.stack[top]
 			}
 			callStackTop++
-			program, pc = f, 0
+			program, pc, function = f, 0, fn
 			continue
 
 		case opFDEF:
@@ -500,7 +564,8 @@ func (h *hinter) run(program []byte, pCurrent, pUnhinted, pInFontUnits []Point,
 				pc = 0
 				continue
 			}
-			program, pc = callStack[callStackTop].program, callStack[callStackTop].pc
+			e := &callStack[callStackTop]
+			program, pc, function = e.program, e.pc, e.function
 
 		case opMDAP0, opMDAP1:
 			top--
@@ -512,7 +577,6 @@ func (h *hinter) run(program []byte, pCurrent, pUnhinted, pInFontUnits []Point,
 			distance := fixed.Int26_6(0)
 			if opcode == opMDAP1 {
 				distance = dotProduct(p.X, p.Y, h.gs.pv)
-				// TODO: metrics compensation.
 				distance = h.round(distance) - distance
 			}
 			h.move(p, distance, true)
@@ -520,6 +584,18 @@ func (h *hinter) run(program []byte, pCurrent, pUnhinted, pInFontUnits []Point,
 			h.gs.rp[1] = i
 
 		case opIUP0, opIUP1:
+			// In backwards compatibility mode, IUP is a no-op once it has
+			// been called on both axes.
+			if h.backwardCompat {
+				if h.iupXCalled && h.iupYCalled {
+					break
+				}
+				if opcode == opIUP1 {
+					h.iupXCalled = true
+				} else {
+					h.iupYCalled = true
+				}
+			}
 			iupY, mask := opcode == opIUP0, uint32(flagTouchedX)
 			if iupY {
 				mask = flagTouchedY
@@ -577,17 +653,23 @@ func (h *hinter) run(program []byte, pCurrent, pUnhinted, pInFontUnits []Point,
 			if !ok {
 				return errors.New("truetype: hinting: point out of range")
 			}
-			if h.gs.zp[2] == 0 {
-				// TODO: implement this when we have a glyph that does this.
-				return errors.New("hinting: unimplemented SHC instruction")
-			}
 			contour := h.stack[top]
-			if contour < 0 || len(ends) <= int(contour) {
-				return errors.New("truetype: hinting: contour out of range")
-			}
-			j0, j1 := int32(0), int32(h.ends[contour])
-			if contour > 0 {
-				j0 = int32(h.ends[contour-1])
+			j0, j1 := int32(0), int32(0)
+			if h.gs.zp[2] == 0 {
+				// As per C Freetype, the twilight zone is a single contour
+				// that holds all of its points.
+				if contour != 0 {
+					return errors.New("truetype: hinting: contour out of range")
+				}
+				j1 = int32(len(h.points[twilightZone][current]))
+			} else {
+				if contour < 0 || len(h.ends) <= int(contour) {
+					return errors.New("truetype: hinting: contour out of range")
+				}
+				j1 = int32(h.ends[contour])
+				if contour > 0 {
+					j0 = int32(h.ends[contour-1])
+				}
 			}
 			move := h.gs.zp[zonePointer] != h.gs.zp[2]
 			for j := j0; j < j1; j++ {
@@ -627,6 +709,11 @@ func (h *hinter) run(program []byte, pCurrent, pUnhinted, pInFontUnits []Point,
 				if p == nil {
 					return errors.New("truetype: hinting: point out of range")
 				}
+				// As per C Freetype, in backwards compatibility mode SHPIX is
+				// like DELTAP, except that it can move twilight points.
+				if h.gs.zp[2] != twilightZone && h.deltaIgnored(p) {
+					continue
+				}
 				h.move(p, d, true)
 			}
 			h.gs.loop = 1
@@ -671,20 +758,29 @@ func (h *hinter) run(program []byte, pCurrent, pUnhinted, pInFontUnits []Point,
 			i := h.stack[top]
 			distance := fixed.Int26_6(h.stack[top+1])
 
-			// TODO: special case h.gs.zp[1] == 0 in C Freetype.
 			ref := h.point(0, current, h.gs.rp[0])
 			p := h.point(1, current, i)
 			if ref == nil || p == nil {
 				return errors.New("truetype: hinting: point out of range")
 			}
+			if h.gs.zp[1] == 0 {
+				// As per C Freetype, a twilight point is first placed at the
+				// reference point's original position, moved along the
+				// freedom vector by the distance.
+				q := h.point(1, unhinted, i)
+				refQ := h.point(0, unhinted, h.gs.rp[0])
+				q.X, q.Y = refQ.X, refQ.Y
+				h.rawMove(q, distance, false)
+				p.X, p.Y = q.X, q.Y
+			}
 			curDist := dotProduct(p.X-ref.X, p.Y-ref.Y, h.gs.pv)
 
 			// Set-RP0 bit.
+			h.gs.rp[1] = h.gs.rp[0]
+			h.gs.rp[2] = i
 			if opcode == opMSIRP1 {
 				h.gs.rp[0] = i
 			}
-			h.gs.rp[1] = h.gs.rp[0]
-			h.gs.rp[2] = i
 
 			// Move the point.
 			h.move(p, distance-curDist, true)
@@ -730,7 +826,6 @@ func (h *hinter) run(program []byte, pCurrent, pUnhinted, pInFontUnits []Point,
 				if fabs(distance-oldDist) > h.gs.controlValueCutIn {
 					distance = oldDist
 				}
-				// TODO: metrics compensation.
 				distance = h.round(distance)
 			}
 			h.move(p, distance-oldDist, true)
@@ -940,7 +1035,8 @@ func (h *hinter) run(program []byte, pCurrent, pUnhinted, pInFontUnits []Point,
 			// characteristics", to cater for things like "different dot-size printers".
 			// https://developer.apple.com/fonts/TTRefMan/RM02/Chap2.html#engine_compensation
 			// This code does not implement engine compensation, as we don't expect to
-			// be used to output on dot-matrix printers.
+			// be used to output on dot-matrix printers. Nor do the rounding MDAP,
+			// MIAP, MDRP and MIRP instructions.
 
 		case opWCVTF:
 			top -= 2
@@ -1071,6 +1167,25 @@ func (h *hinter) run(program []byte, pCurrent, pUnhinted, pInFontUnits []Point,
 
 		case opGETINFO:
 			res := int32(0)
+			if h.subpixel {
+				// As per C Freetype's v40 interpreter, report a ClearType
+				// engine that does subpixel hinting and positioning, and
+				// symmetrical smoothing, but not grayscale.
+				if h.stack[top-1]&(1<<0) != 0 {
+					res |= 40
+				}
+				if h.stack[top-1]&(1<<6) != 0 {
+					res |= 1 << 13
+				}
+				if h.stack[top-1]&(1<<10) != 0 {
+					res |= 1 << 17
+				}
+				if h.stack[top-1]&(1<<11) != 0 {
+					res |= 1 << 18
+				}
+				h.stack[top-1] = res
+				break
+			}
 			if h.stack[top-1]&(1<<0) != 0 {
 				// Set the engine version. We hard-code this to 35, the same as
 				// the C freetype code, which says that "Version~35 corresponds
@@ -1109,13 +1224,19 @@ func (h *hinter) run(program []byte, pCurrent, pUnhinted, pInFontUnits []Point,
 			top--
 
 		case opINSTCTRL:
-			// TODO: support instruction execution control? It seems rare, and even when
-			// nominally used (e.g. Source Sans Pro), it seems conditional on extreme or
-			// unusual rasterization conditions. For example, the code snippet at
-			// https://developer.apple.com/fonts/TTRefMan/RM05/Chap5.html#INSTCTRL
-			// uses INSTCTRL when grid-fitting a rotated or stretched glyph, but
-			// freetype-go does not support rotated or stretched glyphs.
 			top -= 2
+			// As per C Freetype, INSTCTRL is ignored outside of the prep
+			// program, as are unknown selectors. The selected bit is set if
+			// the value is non-zero, and cleared otherwise.
+			selector, value := h.stack[top+1], h.stack[top]
+			if !h.inPrep || selector < 1 || 3 < selector {
+				break
+			}
+			mask := int32(1) << uint(selector-1)
+			h.gs.instructControl &^= mask
+			if value != 0 {
+				h.gs.instructControl |= mask
+			}
 
 		default:
 			if opcode < opPUSHB000 {
@@ -1166,7 +1287,6 @@ func (h *hinter) run(program []byte, pCurrent, pUnhinted, pInFontUnits []Point,
 				}
 
 				// Rounding bit.
-				// TODO: metrics compensation.
 				distance := oldDist
 				if opcode&0x04 != 0 {
 					distance = h.round(oldDist)
@@ -1210,17 +1330,20 @@ func (h *hinter) run(program []byte, pCurrent, pUnhinted, pInFontUnits []Point,
 					}
 				}
 
-				if h.gs.zp[1] == 0 {
-					// TODO: implement once we have a .ttf file that triggers
-					// this, so that we can step through C's freetype.
-					return errors.New("truetype: hinting: unimplemented twilight point adjustment")
-				}
-
 				ref := h.point(0, unhinted, h.gs.rp[0])
 				p := h.point(1, unhinted, i)
 				if ref == nil || p == nil {
 					return errors.New("truetype: hinting: point out of range")
 				}
+				if h.gs.zp[1] == 0 {
+					// As per C Freetype, a twilight point is first placed at
+					// the CVT distance from the reference point's original
+					// position, along the freedom vector.
+					p.X = ref.X + fixed.Int26_6((int64(cvtDist)*int64(h.gs.fv[0]))>>14)
+					p.Y = ref.Y + fixed.Int26_6((int64(cvtDist)*int64(h.gs.fv[1]))>>14)
+					q := h.point(1, current, i)
+					q.X, q.Y = p.X, p.Y
+				}
 				oldDist := dotProduct(p.X-ref.X, p.Y-ref.Y, h.gs.dv)
 
 				ref = h.point(0, current, h.gs.rp[0])
@@ -1235,7 +1358,6 @@ func (h *hinter) run(program []byte, pCurrent, pUnhinted, pInFontUnits []Point,
 				}
 
 				// Rounding bit.
-				// TODO: metrics compensation.
 				distance := cvtDist
 				if opcode&0x04 != 0 {
 					// The CVT value is only used if close enough to oldDist.
@@ -1386,6 +1508,9 @@ func (h *hinter) run(program []byte, pCurrent, pUnhinted, pInFontUnits []Point,
 					if p == nil {
 						return errors.New("truetype: hinting: point out of range")
 					}
+					if h.deltaIgnored(p) {
+						continue
+					}
 					h.move(p, fixed.Int26_6(b), true)
 				}
 			}
@@ -1442,7 +1567,46 @@ func (h *hinter) point(zonePointer uint32, pt pointType, i int32) *Point {
 	return &points[i]
 }
 
+// backwardCompatible returns whether glyph programs run in backwards
+// compatibility mode.
+func (h *hinter) backwardCompatible() bool {
+	return h.subpixel && h.defaultGS.instructControl&instructControlNativeClearType == 0
+}
+
+// deltaIgnored returns whether, in backwards compatibility mode, DELTAP
+// leaves p where it is. As per C Freetype, DELTAP only moves points that
+// have already been touched in Y, or any point of a composite glyph if the
+// freedom vector has a Y component, and only before IUP has been called on
+// both axes.
+func (h *hinter) deltaIgnored(p *Point) bool {
+	if !h.backwardCompat {
+		return false
+	}
+	if h.iupXCalled && h.iupYCalled {
+		return true
+	}
+	return p.Flags&flagTouchedY == 0 && !(h.composite && h.gs.fv[1] != 0)
+}
+
+// move moves p along the freedom vector so that its projection onto the
+// projection vector changes by distance, unless backwards compatibility mode
+// says otherwise.
 func (h *hinter) move(p *Point, distance fixed.Int26_6, touch bool) {
+	if !h.backwardCompat {
+		h.rawMove(p, distance, touch)
+		return
+	}
+	x, y := p.X, p.Y
+	h.rawMove(p, distance, touch)
+	p.X = x
+	if h.iupXCalled && h.iupYCalled {
+		p.Y = y
+	}
+}
+
+// rawMove is like move, ignoring backwards compatibility mode. It is also
+// used to move the original positions of twilight points.
+func (h *hinter) rawMove(p *Point, distance fixed.Int26_6, touch bool) {
 	fvx := int64(h.gs.fv[0])
 	pvx := int64(h.gs.pv[0])
 	if fvx == 0x4000 && pvx == 0x4000 {
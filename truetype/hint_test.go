@@ -6,10 +6,12 @@
 package truetype
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
 
+	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -553,6 +555,123 @@ func TestBytecode(t *testing.T) {
 			[]int32{99, 99, 99, 99, 20, 20},
 			"",
 		},
+		{
+			"SHC in the twilight zone",
+			[]byte{
+				opPUSHB000, // [0]
+				0,
+				opSZPS,     // []
+				opSVTCA1,   // []
+				opPUSHB001, // [1, 64]
+				1,
+				64,
+				opSCFS,     // []
+				opPUSHB001, // [1, 128]
+				1,
+				128,
+				opSHPIX,    // []
+				opPUSHB000, // [1]
+				1,
+				opMDAP0,    // []
+				opPUSHB000, // [0]
+				0,
+				opSHC1,     // []
+				opPUSHB000, // [0]
+				0,
+				opGC0,      // [128]
+				opPUSHB000, // [128, 1]
+				1,
+				opGC0,      // [128, 192]
+				opPUSHB000, // [128, 192, 3]
+				3,
+				opGC0, // [128, 192, 128]
+			},
+			[]int32{128, 192, 128},
+			"",
+		},
+		{
+			"SHC of a missing twilight contour",
+			[]byte{
+				opPUSHB000, // [0]
+				0,
+				opSZPS,     // []
+				opPUSHB000, // [1]
+				1,
+				opSHC1,
+			},
+			nil,
+			"contour out of range",
+		},
+		{
+			"MSIRP in the twilight zone",
+			[]byte{
+				opPUSHB000, // [0]
+				0,
+				opSZPS,     // []
+				opSVTCA1,   // []
+				opPUSHB001, // [1, 64]
+				1,
+				64,
+				opSCFS,     // []
+				opPUSHB000, // [1]
+				1,
+				opMDAP0,    // []
+				opPUSHB001, // [2, 32]
+				2,
+				32,
+				opMSIRP1,   // []
+				opPUSHB000, // [2]
+				2,
+				opGC0,      // [96]
+				opPUSHB000, // [96, 2]
+				2,
+				opGC1, // [96, 96]
+			},
+			[]int32{96, 96},
+			"",
+		},
+		{
+			"MIRP in the twilight zone",
+			[]byte{
+				opPUSHB000, // [0]
+				0,
+				opSZPS,     // []
+				opSVTCA1,   // []
+				opPUSHB001, // [0, 100]
+				0,
+				100,
+				opWCVTP,    // []
+				opPUSHB001, // [1, 64]
+				1,
+				64,
+				opSCFS,     // []
+				opPUSHB000, // [1]
+				1,
+				opMDAP0,    // []
+				opPUSHB001, // [2, 0]
+				2,
+				0,
+				opMIRP00000, // []
+				opPUSHB001,  // [3, 0]
+				3,
+				0,
+				opMIRP00100, // []
+				opPUSHB000,  // [2]
+				2,
+				opGC0,      // [164]
+				opPUSHB000, // [164, 2]
+				2,
+				opGC1,      // [164, 164]
+				opPUSHB000, // [164, 164, 3]
+				3,
+				opGC0,      // [164, 164, 192]
+				opPUSHB000, // [164, 164, 192, 3]
+				3,
+				opGC1, // [164, 164, 192, 164]
+			},
+			[]int32{164, 164, 192, 164},
+			"",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -560,7 +679,9 @@ func TestBytecode(t *testing.T) {
 		h.init(&Font{
 			maxStorage:       32,
 			maxStackElements: 100,
-		}, 768)
+			fUnitsPerEm:      2048,
+			cvt:              make([]byte, 8),
+		}, 768, false)
 		err, errStr := h.run(tc.prog, nil, nil, nil, nil), ""
 		if err != nil {
 			errStr = err.Error()
@@ -583,6 +704,216 @@ func TestBytecode(t *testing.T) {
 			continue
 		}
 	}
+
+	// In subpixel hinting's backwards compatibility mode, DELTAP moves a point
+	// that is untouched in Y only if it is a composite glyph's point and the
+	// freedom vector has a Y component.
+	deltaCases := []struct {
+		desc      string
+		composite bool
+		prog      []byte
+		want      int32
+	}{
+		{
+			"DELTAP of a simple glyph's untouched point",
+			false,
+			[]byte{
+				opSVTCA0,   // []
+				opPUSHB010, // [0x38, 0, 1]
+				0x38,
+				0,
+				1,
+				opDELTAP1,  // []
+				opPUSHB000, // [0]
+				0,
+				opGC0, // [0]
+			},
+			0,
+		},
+		{
+			"DELTAP of a composite glyph's untouched point",
+			true,
+			[]byte{
+				opSVTCA0,   // []
+				opPUSHB010, // [0x38, 0, 1]
+				0x38,
+				0,
+				1,
+				opDELTAP1,  // []
+				opPUSHB000, // [0]
+				0,
+				opGC0, // [8]
+			},
+			8,
+		},
+		{
+			"DELTAP of a composite glyph's untouched point, along X",
+			true,
+			[]byte{
+				opSVTCA0,   // []
+				opSFVTCA1,  // []
+				opPUSHB010, // [0x38, 0, 1]
+				0x38,
+				0,
+				1,
+				opDELTAP1,  // []
+				opPUSHB000, // [0]
+				0,
+				opGC0, // [0]
+			},
+			0,
+		},
+	}
+	for _, tc := range deltaCases {
+		h := &hinter{}
+		h.init(&Font{
+			maxStorage:       32,
+			maxStackElements: 100,
+			fUnitsPerEm:      2048,
+		}, 768, true)
+		h.composite = tc.composite
+		points := make([]Point, 4)
+		unhinted := make([]Point, 4)
+		if err := h.run(tc.prog, points, unhinted, unhinted, []int{3}); err != nil {
+			t.Errorf("%s: %v", tc.desc, err)
+			continue
+		}
+		if got := h.stack[0]; got != tc.want {
+			t.Errorf("%s: got %d, want %d", tc.desc, got, tc.want)
+		}
+	}
+}
+
+// TestInstructionControl tests that INSTCTRL takes effect only in a font's
+// prep program, and that inhibiting grid-fitting loads glyphs unhinted.
+func TestInstructionControl(t *testing.T) {
+	testCases := []struct {
+		desc string
+		prep []byte
+		want int32
+	}{
+		{
+			"inhibit grid-fitting",
+			[]byte{opPUSHB001, 1, 1, opINSTCTRL},
+			instructControlInhibitGridFitting,
+		},
+		{
+			"ignore CVT graphics state",
+			[]byte{opPUSHB001, 1, 2, opINSTCTRL},
+			instructControlIgnoreCVTGS,
+		},
+		{
+			"set and clear",
+			[]byte{opPUSHB011, 0, 1, 1, 1, opINSTCTRL, opINSTCTRL},
+			0,
+		},
+		{
+			"unknown selector",
+			[]byte{opPUSHB001, 1, 4, opINSTCTRL},
+			0,
+		},
+	}
+	for _, tc := range testCases {
+		// Every prep program also sets the minimum distance to 2.
+		prep := append([]byte{opPUSHB000, 128, opSMD}, tc.prep...)
+		h := &hinter{}
+		if err := h.init(&Font{prep: prep, maxStackElements: 100}, 768, false); err != nil {
+			t.Errorf("%s: init: %v", tc.desc, err)
+			continue
+		}
+		if got := h.defaultGS.instructControl; got != tc.want {
+			t.Errorf("%s: got instruction control %#x, want %#x", tc.desc, got, tc.want)
+		}
+		wantMinDist := fixed.Int26_6(128)
+		if tc.want&instructControlIgnoreCVTGS != 0 {
+			wantMinDist = globalDefaultGS.minDist
+		}
+		if got := h.defaultGS.minDist; got != wantMinDist {
+			t.Errorf("%s: got minimum distance %v, want %v", tc.desc, got, wantMinDist)
+		}
+		// Outside of the prep program, INSTCTRL is a no-op.
+		if err := h.run(tc.prep, nil, nil, nil, nil); err != nil {
+			t.Errorf("%s: run: %v", tc.desc, err)
+		} else if got := h.gs.instructControl; got != tc.want {
+			t.Errorf("%s: run: got instruction control %#x, want %#x", tc.desc, got, tc.want)
+		}
+	}
+
+	f, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inhibited := *f
+	inhibited.prep = append(append([]byte(nil), f.prep...), opPUSHB001, 1, 1, opINSTCTRL)
+	i := f.Index('A')
+	g0, g1 := &GlyphBuf{}, &GlyphBuf{}
+	if err := g0.Load(f, fixed.I(12), i, font.HintingNone); err != nil {
+		t.Fatal(err)
+	}
+	if err := g1.Load(&inhibited, fixed.I(12), i, font.HintingFull); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g0.Points, g1.Points) || g0.AdvanceWidth != g1.AdvanceWidth {
+		t.Errorf("inhibited grid-fitting: got %v, %v, want unhinted %v, %v",
+			g1.Points, g1.AdvanceWidth, g0.Points, g0.AdvanceWidth)
+	}
+}
+
+func TestHintingTrace(t *testing.T) {
+	var got []string
+	h := &hinter{}
+	h.init(&Font{maxStackElements: 100}, 768, false)
+	h.trace = func(s *HintingStep) {
+		got = append(got, fmt.Sprintf("%s %d %d %s %v %v", s.Program, s.Function, s.PC, s.Name(), s.Stack, s.Err))
+	}
+	err := h.run([]byte{
+		opPUSHB001, // [1, 2]
+		1,
+		2,
+		opADD, // [3]
+		opPOP, // []
+		opDUP,
+	}, nil, nil, nil, nil)
+	if err == nil {
+		t.Fatal("run: got no error, want underflow")
+	}
+	want := []string{
+		"glyf -1 0 PUSHB[001] [1 2] <nil>",
+		"glyf -1 3 ADD [3] <nil>",
+		"glyf -1 4 POP [] <nil>",
+		"glyf -1 5 DUP [] " + err.Error(),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got steps\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Tracing a glyph reports its font's programs and functions, and the
+	// points that its instructions move.
+	f, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	programs, inFunctions, moved := map[string]bool{}, false, false
+	g := &GlyphBuf{Trace: func(s *HintingStep) {
+		programs[s.Program] = true
+		inFunctions = inFunctions || s.Function >= 0
+		moved = moved || len(s.Moved[glyphZone]) != 0
+		if s.Err != nil {
+			t.Errorf("%s: pc %d: %v", s.Program, s.PC, s.Err)
+		}
+	}}
+	if err := g.Load(f, fixed.I(12), f.Index('A'), font.HintingFull); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]bool{"fpgm": true, "prep": true, "glyf": true}; !reflect.DeepEqual(programs, want) {
+		t.Errorf("got programs %v, want %v", programs, want)
+	}
+	if !inFunctions {
+		t.Error("no step was in a function")
+	}
+	if !moved {
+		t.Error("no step moved a glyph point")
+	}
 }
 
 // TestMove tests that the hinter.move method matches the output of the C
@@ -0,0 +1,161 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+// This file implements the OpenType Layout common table formats, shared by
+// the GPOS and GSUB tables, as described at
+// https://www.microsoft.com/typography/otspec/chapter2.htm
+
+import (
+	"fmt"
+	"sort"
+)
+
+// layoutTable holds a parsed GPOS or GSUB table header.
+type layoutTable struct {
+	scriptList, featureList, lookupList []byte
+	// extensionType is the lookup type of Extension lookups: 9 for GPOS and
+	// 7 for GSUB.
+	extensionType uint16
+}
+
+// parseLayoutTable parses the header of the GPOS or GSUB table b.
+func parseLayoutTable(b []byte, name string, extensionType uint16) (*layoutTable, error) {
+	if len(b) < 10 {
+		return nil, FormatError(name + " data too short")
+	}
+	if major := u16(b, 0); major != 1 {
+		return nil, UnsupportedError(fmt.Sprintf("%s version: %d", name, major))
+	}
+	t := &layoutTable{extensionType: extensionType}
+	lists := [3]*[]byte{&t.scriptList, &t.featureList, &t.lookupList}
+	for i, l := range lists {
+		o := int(u16(b, 4+2*i))
+		if o == 0 {
+			continue
+		}
+		if len(b) < o+2 {
+			return nil, FormatError(fmt.Sprintf("bad %s list offset", name))
+		}
+		*l = b[o:]
+	}
+	return t, nil
+}
+
+// lookup returns the type, flags and subtables of the i'th lookup. Extension
+// subtables are resolved to the subtables that they wrap, and their type.
+func (t *layoutTable) lookup(i int) (lookupType, flags uint16, subtables [][]byte) {
+	if len(t.lookupList) < 2 || int(u16(t.lookupList, 0)) <= i || len(t.lookupList) < 4+2*i {
+		return 0, 0, nil
+	}
+	o := int(u16(t.lookupList, 2+2*i))
+	if len(t.lookupList) < o+6 {
+		return 0, 0, nil
+	}
+	l := t.lookupList[o:]
+	lookupType, flags = u16(l, 0), u16(l, 2)
+	n := int(u16(l, 4))
+	if len(l) < 6+2*n {
+		return 0, 0, nil
+	}
+	for j := 0; j < n; j++ {
+		o := int(u16(l, 6+2*j))
+		if len(l) < o+2 {
+			continue
+		}
+		sub := l[o:]
+		if lookupType == t.extensionType {
+			if len(sub) < 8 || u16(sub, 0) != 1 {
+				continue
+			}
+			extType, extOffset := u16(sub, 2), int(u32(sub, 4))
+			if extOffset < 0 || len(sub) < extOffset+2 {
+				continue
+			}
+			// All of a lookup's subtables must have the same type.
+			if j == 0 {
+				lookupType = extType
+			} else if extType != lookupType {
+				continue
+			}
+			sub = sub[extOffset:]
+		}
+		subtables = append(subtables, sub)
+	}
+	return lookupType, flags, subtables
+}
+
+// coverageIndex returns the index of the glyph in the Coverage table b.
+func coverageIndex(b []byte, i Index) (int, bool) {
+	if len(b) < 4 {
+		return 0, false
+	}
+	g := uint16(i)
+	switch u16(b, 0) {
+	case 1:
+		n := int(u16(b, 2))
+		if len(b) < 4+2*n {
+			return 0, false
+		}
+		j := sort.Search(n, func(j int) bool { return u16(b, 4+2*j) >= g })
+		if j < n && u16(b, 4+2*j) == g {
+			return j, true
+		}
+	case 2:
+		n := int(u16(b, 2))
+		if len(b) < 4+6*n {
+			return 0, false
+		}
+		j := sort.Search(n, func(j int) bool { return u16(b, 4+6*j+2) >= g })
+		if j < n && u16(b, 4+6*j) <= g {
+			return int(u16(b, 4+6*j+4)) + int(g-u16(b, 4+6*j)), true
+		}
+	}
+	return 0, false
+}
+
+// classDef returns the class of the glyph in the ClassDef table b. Glyphs
+// that are not listed are in class 0.
+func classDef(b []byte, i Index) int {
+	if len(b) < 4 {
+		return 0
+	}
+	g := uint16(i)
+	switch u16(b, 0) {
+	case 1:
+		if len(b) < 6 {
+			return 0
+		}
+		start, n := u16(b, 2), int(u16(b, 4))
+		if g < start || int(g-start) >= n || len(b) < 6+2*n {
+			return 0
+		}
+		return int(u16(b, 6+2*int(g-start)))
+	case 2:
+		n := int(u16(b, 2))
+		if len(b) < 4+6*n {
+			return 0
+		}
+		j := sort.Search(n, func(j int) bool { return u16(b, 4+6*j+2) >= g })
+		if j < n && u16(b, 4+6*j) <= g {
+			return int(u16(b, 4+6*j+4))
+		}
+	}
+	return 0
+}
+
+// offsetTable returns the table at the 16-bit offset stored at b[x:], or nil
+// if the offset is zero or out of bounds.
+func offsetTable(b []byte, x int) []byte {
+	if len(b) < x+2 {
+		return nil
+	}
+	o := int(u16(b, x))
+	if o == 0 || len(b) < o+2 {
+		return nil
+	}
+	return b[o:]
+}
@@ -0,0 +1,280 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+// A NameRecord is one record of a font's name table, described at
+// https://www.microsoft.com/typography/otspec/name.htm
+type NameRecord struct {
+	// PlatformID, EncodingID and LanguageID identify the record's platform,
+	// encoding and language, such as 3, 1 and 0x0411 for Japanese on
+	// Windows, or 1, 0 and 0 for English in Mac OS Roman.
+	PlatformID, EncodingID, LanguageID uint16
+	// NameID identifies what the record names.
+	NameID NameID
+	// Language is the BCP 47 language tag, such as "ja-JP", of the record's
+	// language, or "" if it is unknown.
+	Language string
+	// Value is the record's string, decoded to UTF-8.
+	Value string
+}
+
+// NameRecords returns the records of the font's name table, in table order.
+// Records whose strings are in an encoding other than UTF-16 or Mac OS Roman,
+// or that are malformed, are omitted.
+func (f *Font) NameRecords() []NameRecord {
+	if len(f.name) < 6 {
+		return nil
+	}
+	n := int(u16(f.name, 2))
+	if len(f.name) < 6+12*n {
+		return nil
+	}
+	var ret []NameRecord
+	for i, x := 0, 6; i < n; i, x = i+1, x+12 {
+		r := NameRecord{
+			PlatformID: u16(f.name, x),
+			EncodingID: u16(f.name, x+2),
+			LanguageID: u16(f.name, x+4),
+			NameID:     NameID(u16(f.name, x+6)),
+		}
+		var ok bool
+		if r.Value, ok = f.nameString(r.PlatformID, r.EncodingID, x); !ok {
+			continue
+		}
+		r.Language = f.nameLanguage(r.PlatformID, r.LanguageID)
+		ret = append(ret, r)
+	}
+	return ret
+}
+
+// LocalizedName returns the Font's name value for the given NameID in the
+// language closest to the given BCP 47 language tag, such as "ja" or
+// "zh-Hant". If there is no name in that language, it returns the English
+// name or, failing that, any name. It returns "" if that name was not found.
+func (f *Font) LocalizedName(id NameID, lang string) string {
+	want := parseLanguageTag(lang)
+	english := parseLanguageTag("en-US")
+	best, bestScore := "", -1
+	for _, r := range f.NameRecords() {
+		if r.NameID != id {
+			continue
+		}
+		have := parseLanguageTag(r.Language)
+		// A match for the wanted language beats English, which beats any
+		// other language. Ties go to the Windows and Unicode platforms,
+		// whose strings are Unicode, and then to the earlier record.
+		score := 0
+		if s := have.match(want); s > 0 {
+			score = 2 * (8 + s)
+		} else if s := have.match(english); s > 0 {
+			score = 2 * s
+		}
+		if r.PlatformID != 1 {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = r.Value, score
+		}
+	}
+	return best
+}
+
+// nameString decodes the string of the name record at the given offset of
+// the name table.
+func (f *Font) nameString(platformID, encodingID uint16, x int) (string, bool) {
+	offset := int(u16(f.name, 4)) + int(u16(f.name, x+10))
+	length := int(u16(f.name, x+8))
+	if offset+length > len(f.name) {
+		return "", false
+	}
+	return decodeName(platformID, encodingID, f.name[offset:offset+length])
+}
+
+// decodeName decodes a name table string in the given platform's encoding.
+// The Unicode platform, and the Microsoft platform's Symbol, UCS-2 and UCS-4
+// encodings, use UTF-16BE. The Macintosh platform's Roman encoding is Mac OS
+// Roman. ok is false for other encodings.
+func decodeName(platformID, encodingID uint16, b []byte) (s string, ok bool) {
+	switch {
+	case platformID == 0,
+		platformID == 3 && (encodingID == 0 || encodingID == 1 || encodingID == 10):
+		if len(b)&1 != 0 {
+			return "", false
+		}
+		u := make([]uint16, len(b)/2)
+		for i := range u {
+			u[i] = u16(b, 2*i)
+		}
+		return string(utf16.Decode(u)), true
+	case platformID == 1 && encodingID == 0:
+		r := make([]rune, len(b))
+		for i, c := range b {
+			r[i] = macRomanRune(uint32(c))
+		}
+		return string(r), true
+	}
+	return "", false
+}
+
+// nameLanguage returns the BCP 47 language tag of a name record's language
+// ID, or "" if it is unknown. Version 1 name tables list their own tags.
+func (f *Font) nameLanguage(platformID, languageID uint16) string {
+	if languageID >= 0x8000 && platformID != 1 {
+		n := 6 + 12*int(u16(f.name, 2))
+		if u16(f.name, 0) != 1 || len(f.name) < n+2 {
+			return ""
+		}
+		i := int(languageID - 0x8000)
+		if i >= int(u16(f.name, n)) || len(f.name) < n+2+4*(i+1) {
+			return ""
+		}
+		x := n + 2 + 4*i
+		offset := int(u16(f.name, 4)) + int(u16(f.name, x+2))
+		length := int(u16(f.name, x))
+		if offset+length > len(f.name) {
+			return ""
+		}
+		s, _ := decodeName(0, 0, f.name[offset:offset+length])
+		return s
+	}
+	switch platformID {
+	case 1:
+		if int(languageID) < len(macLanguages) {
+			return macLanguages[languageID]
+		}
+	case 3:
+		return windowsLanguages[languageID]
+	}
+	return ""
+}
+
+// languageTag is a parsed BCP 47 language tag, in lower case.
+type languageTag struct {
+	language, script, region string
+}
+
+// parseLanguageTag parses the language, script and region subtags of a BCP
+// 47 language tag, such as "zh-Hant-TW". Chinese tags without a script get
+// the script implied by their region.
+func parseLanguageTag(s string) (t languageTag) {
+	subtags := strings.Split(strings.ToLower(strings.Replace(s, "_", "-", -1)), "-")
+	t.language = subtags[0]
+	for _, sub := range subtags[1:] {
+		switch {
+		case len(sub) == 4 && t.script == "" && t.region == "":
+			t.script = sub
+		case (len(sub) == 2 || len(sub) == 3 && '0' <= sub[0] && sub[0] <= '9') && t.region == "":
+			t.region = sub
+		}
+	}
+	if t.language == "zh" && t.script == "" {
+		switch t.region {
+		case "tw", "hk", "mo":
+			t.script = "hant"
+		default:
+			t.script = "hans"
+		}
+	}
+	return t
+}
+
+// match returns how well t matches u: 0 if their languages differ, and more
+// if their scripts or regions also match.
+func (t languageTag) match(u languageTag) int {
+	if t.language == "" || t.language != u.language {
+		return 0
+	}
+	score := 1
+	if t.script == u.script {
+		score += 4
+	}
+	if u.region == "" {
+		score++
+	} else if t.region == u.region {
+		score += 2
+	}
+	return score
+}
+
+// macLanguages are the BCP 47 language tags of the Macintosh language IDs.
+var macLanguages = [...]string{
+	0: "en", 1: "fr", 2: "de", 3: "it", 4: "nl", 5: "sv", 6: "es", 7: "da",
+	8: "pt", 9: "nb", 10: "he", 11: "ja", 12: "ar", 13: "fi", 14: "el", 15: "is",
+	16: "mt", 17: "tr", 18: "hr", 19: "zh-Hant", 20: "ur", 21: "hi", 22: "th", 23: "ko",
+	24: "lt", 25: "pl", 26: "hu", 27: "et", 28: "lv", 29: "se", 30: "fo", 31: "fa",
+	32: "ru", 33: "zh-Hans", 34: "nl-BE", 35: "ga", 36: "sq", 37: "ro", 38: "cs", 39: "sk",
+	40: "sl", 41: "yi", 42: "sr", 43: "mk", 44: "bg", 45: "uk", 46: "be", 47: "uz",
+	48: "kk", 49: "az-Cyrl", 50: "az-Arab", 51: "hy", 52: "ka", 53: "ro-MD", 54: "ky", 55: "tg",
+	56: "tk", 57: "mn-Mong", 58: "mn-Cyrl", 59: "ps", 60: "ku", 61: "ks", 62: "sd", 63: "bo",
+	64: "ne", 65: "sa", 66: "mr", 67: "bn", 68: "as", 69: "gu", 70: "pa", 71: "or",
+	72: "ml", 73: "kn", 74: "ta", 75: "te", 76: "si", 77: "my", 78: "km", 79: "lo",
+	80: "vi", 81: "id", 82: "tl", 83: "ms", 84: "ms-Arab", 85: "am", 86: "ti", 87: "om",
+	88: "so", 89: "sw", 90: "rw", 91: "rn", 92: "ny", 93: "mg", 94: "eo",
+	128: "cy", 129: "eu", 130: "ca", 131: "la", 132: "qu", 133: "gn", 134: "ay", 135: "tt",
+	136: "ug", 137: "dz", 138: "jv", 139: "su", 140: "gl", 141: "af", 142: "br", 143: "iu",
+	144: "gd", 145: "gv", 146: "ga", 147: "to", 148: "el-polyton", 149: "kl", 150: "az-Latn",
+}
+
+// windowsLanguages are the BCP 47 language tags of the Windows language IDs.
+var windowsLanguages = map[uint16]string{
+	0x0436: "af-ZA", 0x041c: "sq-AL", 0x0484: "gsw-FR", 0x045e: "am-ET",
+	0x1401: "ar-DZ", 0x3c01: "ar-BH", 0x0c01: "ar-EG", 0x0801: "ar-IQ",
+	0x2c01: "ar-JO", 0x3401: "ar-KW", 0x3001: "ar-LB", 0x1001: "ar-LY",
+	0x1801: "ar-MA", 0x2001: "ar-OM", 0x4001: "ar-QA", 0x0401: "ar-SA",
+	0x2801: "ar-SY", 0x1c01: "ar-TN", 0x3801: "ar-AE", 0x2401: "ar-YE",
+	0x042b: "hy-AM", 0x044d: "as-IN", 0x082c: "az-Cyrl-AZ", 0x042c: "az-Latn-AZ",
+	0x046d: "ba-RU", 0x042d: "eu-ES", 0x0423: "be-BY", 0x0845: "bn-BD",
+	0x0445: "bn-IN", 0x201a: "bs-Cyrl-BA", 0x141a: "bs-Latn-BA", 0x047e: "br-FR",
+	0x0402: "bg-BG", 0x0403: "ca-ES", 0x0c04: "zh-HK", 0x1404: "zh-MO",
+	0x0804: "zh-CN", 0x1004: "zh-SG", 0x0404: "zh-TW", 0x0483: "co-FR",
+	0x041a: "hr-HR", 0x101a: "hr-BA", 0x0405: "cs-CZ", 0x0406: "da-DK",
+	0x048c: "prs-AF", 0x0465: "dv-MV", 0x0813: "nl-BE", 0x0413: "nl-NL",
+	0x0c09: "en-AU", 0x2809: "en-BZ", 0x1009: "en-CA", 0x2409: "en-029",
+	0x4009: "en-IN", 0x1809: "en-IE", 0x2009: "en-JM", 0x4409: "en-MY",
+	0x1409: "en-NZ", 0x3409: "en-PH", 0x4809: "en-SG", 0x1c09: "en-ZA",
+	0x2c09: "en-TT", 0x0809: "en-GB", 0x0409: "en-US", 0x3009: "en-ZW",
+	0x0425: "et-EE", 0x0438: "fo-FO", 0x0464: "fil-PH", 0x040b: "fi-FI",
+	0x080c: "fr-BE", 0x0c0c: "fr-CA", 0x040c: "fr-FR", 0x140c: "fr-LU",
+	0x180c: "fr-MC", 0x100c: "fr-CH", 0x0462: "fy-NL", 0x0456: "gl-ES",
+	0x0437: "ka-GE", 0x0c07: "de-AT", 0x0407: "de-DE", 0x1407: "de-LI",
+	0x1007: "de-LU", 0x0807: "de-CH", 0x0408: "el-GR", 0x046f: "kl-GL",
+	0x0447: "gu-IN", 0x0468: "ha-Latn-NG", 0x040d: "he-IL", 0x0439: "hi-IN",
+	0x040e: "hu-HU", 0x040f: "is-IS", 0x0470: "ig-NG", 0x0421: "id-ID",
+	0x045d: "iu-Cans-CA", 0x085d: "iu-Latn-CA", 0x083c: "ga-IE", 0x0434: "xh-ZA",
+	0x0435: "zu-ZA", 0x0410: "it-IT", 0x0810: "it-CH", 0x0411: "ja-JP",
+	0x044b: "kn-IN", 0x043f: "kk-KZ", 0x0453: "km-KH", 0x0486: "quc-Latn-GT",
+	0x0487: "rw-RW", 0x0441: "sw-KE", 0x0457: "kok-IN", 0x0412: "ko-KR",
+	0x0440: "ky-KG", 0x0454: "lo-LA", 0x0426: "lv-LV", 0x0427: "lt-LT",
+	0x082e: "dsb-DE", 0x046e: "lb-LU", 0x042f: "mk-MK", 0x083e: "ms-BN",
+	0x043e: "ms-MY", 0x044c: "ml-IN", 0x043a: "mt-MT", 0x0481: "mi-NZ",
+	0x047a: "arn-CL", 0x044e: "mr-IN", 0x047c: "moh-CA", 0x0450: "mn-MN",
+	0x0850: "mn-Mong-CN", 0x0461: "ne-NP", 0x0414: "nb-NO", 0x0814: "nn-NO",
+	0x0482: "oc-FR", 0x0448: "or-IN", 0x0463: "ps-AF", 0x0415: "pl-PL",
+	0x0416: "pt-BR", 0x0816: "pt-PT", 0x0446: "pa-IN", 0x046b: "quz-BO",
+	0x086b: "quz-EC", 0x0c6b: "quz-PE", 0x0418: "ro-RO", 0x0417: "rm-CH",
+	0x0419: "ru-RU", 0x243b: "smn-FI", 0x103b: "smj-NO", 0x143b: "smj-SE",
+	0x0c3b: "se-FI", 0x043b: "se-NO", 0x083b: "se-SE", 0x203b: "sms-FI",
+	0x183b: "sma-NO", 0x1c3b: "sma-SE", 0x044f: "sa-IN", 0x1c1a: "sr-Cyrl-BA",
+	0x0c1a: "sr-Cyrl-CS", 0x181a: "sr-Latn-BA", 0x081a: "sr-Latn-CS", 0x046c: "nso-ZA",
+	0x0432: "tn-ZA", 0x045b: "si-LK", 0x041b: "sk-SK", 0x0424: "sl-SI",
+	0x2c0a: "es-AR", 0x400a: "es-BO", 0x340a: "es-CL", 0x240a: "es-CO",
+	0x140a: "es-CR", 0x1c0a: "es-DO", 0x300a: "es-EC", 0x440a: "es-SV",
+	0x100a: "es-GT", 0x480a: "es-HN", 0x080a: "es-MX", 0x4c0a: "es-NI",
+	0x180a: "es-PA", 0x3c0a: "es-PY", 0x280a: "es-PE", 0x500a: "es-PR",
+	0x0c0a: "es-ES", 0x040a: "es-ES", 0x540a: "es-US", 0x380a: "es-UY",
+	0x200a: "es-VE", 0x081d: "sv-FI", 0x041d: "sv-SE", 0x045a: "syr-SY",
+	0x0428: "tg-Cyrl-TJ", 0x085f: "tzm-Latn-DZ", 0x0449: "ta-IN", 0x0444: "tt-RU",
+	0x044a: "te-IN", 0x041e: "th-TH", 0x0451: "bo-CN", 0x041f: "tr-TR",
+	0x0442: "tk-TM", 0x0480: "ug-CN", 0x0422: "uk-UA", 0x042e: "hsb-DE",
+	0x0420: "ur-PK", 0x0843: "uz-Cyrl-UZ", 0x0443: "uz-Latn-UZ", 0x042a: "vi-VN",
+	0x0452: "cy-GB", 0x0488: "wo-SN", 0x0485: "sah-RU", 0x0478: "ii-CN",
+	0x046a: "yo-NG",
+}
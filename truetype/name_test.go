@@ -0,0 +1,127 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+// makeNameTable returns a version 1 name table with the given records, whose
+// Language fields are ignored and whose Values are encoded as UTF-16BE, or
+// as raw bytes for the Macintosh platform, and with the given language tags.
+func makeNameTable(records []NameRecord, langTags ...string) []byte {
+	utf16BE := func(s string) []byte {
+		var b []byte
+		for _, u := range utf16.Encode([]rune(s)) {
+			b = append(b, byte(u>>8), byte(u))
+		}
+		return b
+	}
+	var data, langTagRecords []byte
+	for _, s := range langTags {
+		v := utf16BE(s)
+		langTagRecords = append(langTagRecords, be16(len(v), len(data))...)
+		data = append(data, v...)
+	}
+	var nameRecords []byte
+	for _, r := range records {
+		v := []byte(r.Value)
+		if r.PlatformID != 1 {
+			v = utf16BE(r.Value)
+		}
+		nameRecords = append(nameRecords, be16(int(r.PlatformID), int(r.EncodingID),
+			int(r.LanguageID), int(r.NameID), len(v), len(data))...)
+		data = append(data, v...)
+	}
+	storage := 6 + len(nameRecords) + 2 + len(langTagRecords)
+	return cat(be16(1, len(records), storage), nameRecords,
+		be16(len(langTags)), langTagRecords, data)
+}
+
+func TestNameRecords(t *testing.T) {
+	f, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := f.NameRecords()
+	if len(records) != 24 {
+		t.Fatalf("luxisr: got %d records, want 24", len(records))
+	}
+	want := []NameRecord{
+		{1, 0, 0, NameIDFontFamily, "en", "Luxi Sans"},
+		{3, 1, 0x409, NameIDFontFamily, "en-US", "Luxi Sans"},
+	}
+	if got := []NameRecord{records[1], records[13]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("luxisr:\ngot  %+v\nwant %+v", got, want)
+	}
+
+	name := makeNameTable([]NameRecord{
+		{PlatformID: 1, EncodingID: 0, LanguageID: 0, NameID: NameIDFontFamily, Value: "Caf\x8e"},
+		{PlatformID: 1, EncodingID: 1, LanguageID: 11, NameID: NameIDFontFamily, Value: "\x83m\x83g"},
+		{PlatformID: 3, EncodingID: 1, LanguageID: 0x0411, NameID: NameIDFontFamily, Value: "ノト"},
+		{PlatformID: 3, EncodingID: 1, LanguageID: 0x0409, NameID: NameIDFontFamily, Value: "Noto"},
+		{PlatformID: 3, EncodingID: 1, LanguageID: 0x0804, NameID: NameIDFontFamily, Value: "思源黑体"},
+		{PlatformID: 3, EncodingID: 1, LanguageID: 0x0404, NameID: NameIDFontFamily, Value: "思源黑體"},
+		{PlatformID: 3, EncodingID: 1, LanguageID: 0x8000, NameID: NameIDFontFamily, Value: "Schrift"},
+		{PlatformID: 3, EncodingID: 10, LanguageID: 0x0409, NameID: NameIDSampleText, Value: "\U0001d400"},
+		{PlatformID: 3, EncodingID: 1, LanguageID: 0x0c0c, NameID: NameIDSampleText, Value: "Bonjour"},
+	}, "de-CH")
+	g := &Font{name: name}
+	wantRecords := []NameRecord{
+		{1, 0, 0, NameIDFontFamily, "en", "Café"},
+		{3, 1, 0x0411, NameIDFontFamily, "ja-JP", "ノト"},
+		{3, 1, 0x0409, NameIDFontFamily, "en-US", "Noto"},
+		{3, 1, 0x0804, NameIDFontFamily, "zh-CN", "思源黑体"},
+		{3, 1, 0x0404, NameIDFontFamily, "zh-TW", "思源黑體"},
+		{3, 1, 0x8000, NameIDFontFamily, "de-CH", "Schrift"},
+		{3, 10, 0x0409, NameIDSampleText, "en-US", "\U0001d400"},
+		{3, 1, 0x0c0c, NameIDSampleText, "fr-CA", "Bonjour"},
+	}
+	if got := g.NameRecords(); !reflect.DeepEqual(got, wantRecords) {
+		t.Errorf("NameRecords:\ngot  %+v\nwant %+v", got, wantRecords)
+	}
+
+	testCases := []struct {
+		id   NameID
+		lang string
+		want string
+	}{
+		{NameIDFontFamily, "ja", "ノト"},
+		{NameIDFontFamily, "ja-JP", "ノト"},
+		{NameIDFontFamily, "en", "Noto"},
+		{NameIDFontFamily, "en-GB", "Noto"},
+		{NameIDFontFamily, "zh-Hans", "思源黑体"},
+		{NameIDFontFamily, "zh-Hant", "思源黑體"},
+		{NameIDFontFamily, "zh-HK", "思源黑體"},
+		{NameIDFontFamily, "zh_SG", "思源黑体"},
+		{NameIDFontFamily, "de", "Schrift"},
+		{NameIDFontFamily, "ko", "Noto"},
+		{NameIDFontFamily, "", "Noto"},
+		{NameIDSampleText, "fr", "Bonjour"},
+		{NameIDSampleText, "ko", "\U0001d400"},
+		{NameIDFontSubfamily, "en", ""},
+	}
+	for _, tc := range testCases {
+		if got := g.LocalizedName(tc.id, tc.lang); got != tc.want {
+			t.Errorf("LocalizedName(%d, %q): got %q, want %q", tc.id, tc.lang, got, tc.want)
+		}
+	}
+	// Name decodes non-ASCII characters too.
+	h := &Font{name: makeNameTable([]NameRecord{
+		{PlatformID: 3, EncodingID: 1, LanguageID: 0x0411, NameID: NameIDFontFamily, Value: "ノト"},
+	})}
+	if got, want := h.Name(NameIDFontFamily), "ノト"; got != want {
+		t.Errorf("Name: got %q, want %q", got, want)
+	}
+
+	// A record whose string is out of bounds is omitted.
+	name[6+8], name[6+9] = 0xff, 0xff
+	if got := g.NameRecords(); len(got) != len(wantRecords)-1 || got[0].Value != "ノト" {
+		t.Errorf("NameRecords with a bad record: got %+v", got)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+import (
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// These are the bits of an OS2's Selection field, as described at
+// https://www.microsoft.com/typography/otspec/os2.htm#fss
+const (
+	SelectionItalic         = 1 << 0
+	SelectionUnderscore     = 1 << 1
+	SelectionNegative       = 1 << 2
+	SelectionOutlined       = 1 << 3
+	SelectionStrikeout      = 1 << 4
+	SelectionBold           = 1 << 5
+	SelectionRegular        = 1 << 6
+	SelectionUseTypoMetrics = 1 << 7
+	SelectionWWS            = 1 << 8
+	SelectionOblique        = 1 << 9
+)
+
+// OS2 holds the metrics and classification of a font's OS/2 table, described
+// at https://www.microsoft.com/typography/otspec/os2.htm
+//
+// Its distances are in FUnits. The table has grown over time, and fields
+// that the font's version of the table does not have are zero.
+type OS2 struct {
+	// Version is the table's version.
+	Version uint16
+	// WeightClass is the visual weight, from 1 to 1000, where 400 is
+	// regular and 700 is bold.
+	WeightClass uint16
+	// WidthClass is the relative width, from 1 (ultra-condensed) to 9
+	// (ultra-expanded), where 5 is normal.
+	WidthClass uint16
+	// FSType is the font's embedding permissions, which Font.Permissions
+	// interprets.
+	FSType uint16
+	// Selection is the font's style, as a combination of the Selection
+	// constants.
+	Selection uint16
+	// Panose is the font's PANOSE classification.
+	Panose [10]byte
+	// UnicodeRange are the bits of the Unicode blocks that the font covers,
+	// with bit 0 the least significant bit of UnicodeRange[0].
+	UnicodeRange [4]uint32
+	// TypoAscender, TypoDescender and TypoLineGap are the typographic
+	// ascender, descender and line gap. TypoDescender is typically
+	// negative.
+	TypoAscender, TypoDescender, TypoLineGap int32
+	// WinAscent and WinDescent are the extents, above and below the
+	// baseline, of the clipping region on Windows. Both are positive.
+	WinAscent, WinDescent int32
+	// XHeight and CapHeight are the heights of the lower case 'x' and of
+	// the upper case 'H'. They are from version 2 of the table.
+	XHeight, CapHeight int32
+}
+
+// HasUnicodeRange returns whether the font covers the Unicode block with the
+// given bit, from 0 to 127, of the OS/2 table's Unicode ranges.
+func (o *OS2) HasUnicodeRange(bit int) bool {
+	if bit < 0 || 128 <= bit {
+		return false
+	}
+	return o.UnicodeRange[bit/32]&(1<<uint(bit%32)) != 0
+}
+
+// parseOS2 parses the OS/2 table. The table is optional for TrueType fonts,
+// so a table that is too short is ignored rather than failing the whole font.
+func (f *Font) parseOS2() {
+	f.os2Table = nil
+	// https://developer.apple.com/fonts/TTRefMan/RM06/Chap6OS2.html says
+	// that the table was originally 68 bytes, without the typographic
+	// metrics.
+	if len(f.os2) < 68 {
+		return
+	}
+	t := &OS2{
+		Version:     u16(f.os2, 0),
+		WeightClass: u16(f.os2, 4),
+		WidthClass:  u16(f.os2, 6),
+		FSType:      u16(f.os2, 8),
+		Selection:   u16(f.os2, 62),
+	}
+	copy(t.Panose[:], f.os2[32:42])
+	for i := range t.UnicodeRange {
+		t.UnicodeRange[i] = u32(f.os2, 42+4*i)
+	}
+	if len(f.os2) >= 78 {
+		t.TypoAscender = int32(int16(u16(f.os2, 68)))
+		t.TypoDescender = int32(int16(u16(f.os2, 70)))
+		t.TypoLineGap = int32(int16(u16(f.os2, 72)))
+		t.WinAscent = int32(u16(f.os2, 74))
+		t.WinDescent = int32(u16(f.os2, 76))
+		// The typographic metrics take precedence over the hhea table's
+		// ascender and descender if the font says so.
+		if t.Selection&SelectionUseTypoMetrics != 0 {
+			f.ascent, f.descent = t.TypoAscender, t.TypoDescender
+		}
+	}
+	if t.Version >= 2 && len(f.os2) >= 90 {
+		t.XHeight = int32(int16(u16(f.os2, 86)))
+		t.CapHeight = int32(int16(u16(f.os2, 88)))
+	}
+	f.os2Table = t
+}
+
+// OS2 returns the font's OS/2 table metrics and classification, and whether
+// the font has an OS/2 table.
+func (f *Font) OS2() (o OS2, ok bool) {
+	if f.os2Table == nil {
+		return OS2{}, false
+	}
+	return *f.os2Table, true
+}
+
+// heights returns the x-height and cap height, in FUnits, from the OS/2
+// table or, if it does not have them, from the tops of the 'x' and 'H'
+// glyphs. Either is zero if the font has neither.
+func (f *Font) heights() (xHeight, capHeight int32) {
+	if f.os2Table != nil {
+		xHeight, capHeight = f.os2Table.XHeight, f.os2Table.CapHeight
+	}
+	if xHeight > 0 && capHeight > 0 {
+		return xHeight, capHeight
+	}
+	var g GlyphBuf
+	top := func(r rune) int32 {
+		i := f.Index(r)
+		if i == 0 {
+			return 0
+		}
+		// Loading the glyph at a scale of one em per font unit gives
+		// co-ordinates in font units.
+		if err := g.Load(f, fixed.Int26_6(f.fUnitsPerEm), i, font.HintingNone); err != nil || len(g.Points) == 0 {
+			return 0
+		}
+		return int32(g.Bounds.Max.Y)
+	}
+	if xHeight <= 0 {
+		xHeight = top('x')
+	}
+	if capHeight <= 0 {
+		capHeight = top('H')
+	}
+	return xHeight, capHeight
+}
@@ -0,0 +1,86 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+import (
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestOS2(t *testing.T) {
+	f, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := f.OS2()
+	if !ok {
+		t.Fatal("no OS/2 table")
+	}
+	want := OS2{
+		Version:       2,
+		WeightClass:   400,
+		WidthClass:    5,
+		Selection:     SelectionRegular,
+		Panose:        [10]byte{2, 11, 6},
+		UnicodeRange:  [4]uint32{7},
+		TypoAscender:  1604,
+		TypoDescender: -420,
+		TypoLineGap:   167,
+		WinAscent:     1935,
+		WinDescent:    432,
+	}
+	if got != want {
+		t.Fatalf("OS2:\ngot  %+v\nwant %+v", got, want)
+	}
+	for bit, want := range map[int]bool{0: true, 2: true, 3: false, 127: false, 128: false} {
+		if got := got.HasUnicodeRange(bit); got != want {
+			t.Errorf("HasUnicodeRange(%d): got %t, want %t", bit, got, want)
+		}
+	}
+
+	// The OS/2 table has no x-height or cap height, so the face measures the
+	// 'x' and 'H' glyphs, whose tops are at 1086 and 1480 FUnits.
+	m := NewFace(f, &Options{Size: 12, DPI: 72}).Metrics()
+	wantM := font.Metrics{
+		Height:    fixed.I(12),
+		Ascent:    763,
+		Descent:   162,
+		XHeight:   408,
+		CapHeight: 555,
+	}
+	if m != wantM {
+		t.Errorf("Metrics: got %+v, want %+v", m, wantM)
+	}
+
+	// Setting USE_TYPO_METRICS selects the typographic ascender and
+	// descender, and the version 2 fields take precedence over the glyphs.
+	os2 := append([]byte(nil), f.os2...)
+	copy(os2[62:], be16(SelectionRegular|SelectionUseTypoMetrics))
+	copy(os2[86:], be16(1000, 1400))
+	g := makeTestFont(t, map[string][]byte{"OS/2": os2})
+	if o, _ := g.OS2(); o.XHeight != 1000 || o.CapHeight != 1400 {
+		t.Errorf("OS2: got XHeight %d and CapHeight %d, want 1000 and 1400", o.XHeight, o.CapHeight)
+	}
+	m = NewFace(g, &Options{Size: 12, DPI: 72}).Metrics()
+	wantM = font.Metrics{
+		Height:    fixed.I(12),
+		Ascent:    602,
+		Descent:   158,
+		XHeight:   375,
+		CapHeight: 525,
+	}
+	if m != wantM {
+		t.Errorf("Metrics with USE_TYPO_METRICS: got %+v, want %+v", m, wantM)
+	}
+
+	// A truncated table is ignored.
+	g = makeTestFont(t, map[string][]byte{"OS/2": f.os2[:60]})
+	if o, ok := g.OS2(); ok {
+		t.Errorf("OS2 with a truncated table: got %+v, want none", o)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+import (
+	"sync"
+)
+
+// Post holds the metrics of a font's post table, described at
+// https://www.microsoft.com/typography/otspec/post.htm
+type Post struct {
+	// ItalicAngle is the italic angle in degrees, counter-clockwise from the
+	// vertical. It is negative for fonts that lean to the right.
+	ItalicAngle float64
+	// UnderlinePosition is the distance, in FUnits, from the baseline to the
+	// top of the underline. It is typically negative.
+	UnderlinePosition int32
+	// UnderlineThickness is the thickness, in FUnits, of the underline.
+	UnderlineThickness int32
+	// IsFixedPitch is whether the font is monospaced.
+	IsFixedPitch bool
+}
+
+// postTable is the parsed post table.
+type postTable struct {
+	Post
+	// version is the table's version, in 16.16 fixed point.
+	version uint32
+	// nameIndexes are the offsets, in the post table, of the glyphs' name
+	// indexes: big-endian uint16s for version 2 and int8 offsets from the
+	// glyph index for version 2.5. It is 0 if the glyphs have no names.
+	nameIndexes int
+	// names are the offsets, in the post table, of the Pascal strings of
+	// the version 2 names that are not standard Macintosh names.
+	names []int
+	// indexes maps each glyph name to the first glyph with that name. It is
+	// built on the first call to GlyphIndex, and shared by the Font's
+	// copies, such as its variation instances.
+	indexesOnce sync.Once
+	indexes     map[string]Index
+}
+
+// nStandardNames is the number of standard Macintosh glyph names.
+const nStandardNames = 258
+
+// standardNames are the standard Macintosh glyph names, which are the names
+// of the glyphs of a version 1 post table, and which version 2 and 2.5
+// tables refer to by index.
+var standardNames = [nStandardNames]string{
+	".notdef", ".null", "nonmarkingreturn", "space", "exclam", "quotedbl",
+	"numbersign", "dollar", "percent", "ampersand", "quotesingle", "parenleft",
+	"parenright", "asterisk", "plus", "comma", "hyphen", "period", "slash",
+	"zero", "one", "two", "three", "four", "five", "six", "seven", "eight",
+	"nine", "colon", "semicolon", "less", "equal", "greater", "question", "at",
+	"A", "B", "C", "D", "E", "F", "G", "H", "I", "J", "K", "L", "M", "N", "O",
+	"P", "Q", "R", "S", "T", "U", "V", "W", "X", "Y", "Z", "bracketleft",
+	"backslash", "bracketright", "asciicircum", "underscore", "grave", "a", "b",
+	"c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q",
+	"r", "s", "t", "u", "v", "w", "x", "y", "z", "braceleft", "bar",
+	"braceright", "asciitilde", "Adieresis", "Aring", "Ccedilla", "Eacute",
+	"Ntilde", "Odieresis", "Udieresis", "aacute", "agrave", "acircumflex",
+	"adieresis", "atilde", "aring", "ccedilla", "eacute", "egrave",
+	"ecircumflex", "edieresis", "iacute", "igrave", "icircumflex", "idieresis",
+	"ntilde", "oacute", "ograve", "ocircumflex", "odieresis", "otilde",
+	"uacute", "ugrave", "ucircumflex", "udieresis", "dagger", "degree", "cent",
+	"sterling", "section", "bullet", "paragraph", "germandbls", "registered",
+	"copyright", "trademark", "acute", "dieresis", "notequal", "AE", "Oslash",
+	"infinity", "plusminus", "lessequal", "greaterequal", "yen", "mu",
+	"partialdiff", "summation", "product", "pi", "integral", "ordfeminine",
+	"ordmasculine", "Omega", "ae", "oslash", "questiondown", "exclamdown",
+	"logicalnot", "radical", "florin", "approxequal", "Delta", "guillemotleft",
+	"guillemotright", "ellipsis", "nonbreakingspace", "Agrave", "Atilde",
+	"Otilde", "OE", "oe", "endash", "emdash", "quotedblleft", "quotedblright",
+	"quoteleft", "quoteright", "divide", "lozenge", "ydieresis", "Ydieresis",
+	"fraction", "currency", "guilsinglleft", "guilsinglright", "fi", "fl",
+	"daggerdbl", "periodcentered", "quotesinglbase", "quotedblbase",
+	"perthousand", "Acircumflex", "Ecircumflex", "Aacute", "Edieresis",
+	"Egrave", "Iacute", "Icircumflex", "Idieresis", "Igrave", "Oacute",
+	"Ocircumflex", "apple", "Ograve", "Uacute", "Ucircumflex", "Ugrave",
+	"dotlessi", "circumflex", "tilde", "macron", "breve", "dotaccent", "ring",
+	"cedilla", "hungarumlaut", "ogonek", "caron", "Lslash", "lslash", "Scaron",
+	"scaron", "Zcaron", "zcaron", "brokenbar", "Eth", "eth", "Yacute", "yacute",
+	"Thorn", "thorn", "minus", "multiply", "onesuperior", "twosuperior",
+	"threesuperior", "onehalf", "onequarter", "threequarters", "franc",
+	"Gbreve", "gbreve", "Idotaccent", "Scedilla", "scedilla", "Cacute",
+	"cacute", "Ccaron", "ccaron", "dcroat",
+}
+
+// parsePost parses the post table. The table is optional, so a table that is
+// too short is ignored, and so are malformed glyph names, rather than failing
+// the whole font.
+func (f *Font) parsePost() {
+	f.postTable = nil
+	if len(f.post) < 32 {
+		return
+	}
+	t := &postTable{
+		Post: Post{
+			ItalicAngle:        float64(int32(u32(f.post, 4))) / 0x10000,
+			UnderlinePosition:  int32(int16(u16(f.post, 8))),
+			UnderlineThickness: int32(int16(u16(f.post, 10))),
+			IsFixedPitch:       u32(f.post, 12) != 0,
+		},
+		version: u32(f.post, 0),
+	}
+	switch t.version {
+	case 0x00020000:
+		if names, ok := f.postNames(); ok {
+			t.nameIndexes, t.names = 34, names
+		}
+	case 0x00025000:
+		if len(f.post) >= 34 && int(u16(f.post, 32)) == f.nGlyph && len(f.post) >= 34+f.nGlyph {
+			t.nameIndexes = 34
+		}
+	}
+	// Versions 1 and 3 need no more data, and other versions' names are
+	// ignored.
+	f.postTable = t
+}
+
+// postNames returns the offsets of a version 2 post table's Pascal strings,
+// and whether the table's glyph name indexes are valid.
+func (f *Font) postNames() (names []int, ok bool) {
+	if len(f.post) < 34 || int(u16(f.post, 32)) != f.nGlyph || len(f.post) < 34+2*f.nGlyph {
+		return nil, false
+	}
+	offset := 34 + 2*f.nGlyph
+	for offset < len(f.post) {
+		n := int(f.post[offset])
+		if offset+1+n > len(f.post) {
+			return nil, false
+		}
+		names = append(names, offset)
+		offset += 1 + n
+	}
+	for i := 0; i < f.nGlyph; i++ {
+		if j := int(u16(f.post, 34+2*i)); j >= nStandardNames+len(names) {
+			return nil, false
+		}
+	}
+	return names, true
+}
+
+// Post returns the font's post table metrics, and whether the font has a
+// post table.
+func (f *Font) Post() (p Post, ok bool) {
+	if f.postTable == nil {
+		return Post{}, false
+	}
+	return f.postTable.Post, true
+}
+
+// glyphName returns the name of the glyph with the given index, without
+// allocating if the name is a standard name. ok is false if the glyph has no
+// name.
+func (f *Font) glyphName(i Index) (standard string, custom []byte, ok bool) {
+	t := f.postTable
+	if t == nil || int(i) >= f.nGlyph {
+		return "", nil, false
+	}
+	if t.version != 0x00010000 && t.nameIndexes == 0 {
+		return "", nil, false
+	}
+	switch t.version {
+	case 0x00010000:
+		if int(i) < nStandardNames {
+			return standardNames[i], nil, true
+		}
+	case 0x00020000:
+		j := int(u16(f.post, t.nameIndexes+2*int(i)))
+		if j < nStandardNames {
+			return standardNames[j], nil, true
+		}
+		offset := t.names[j-nStandardNames]
+		return "", f.post[offset+1 : offset+1+int(f.post[offset])], true
+	case 0x00025000:
+		j := int(i) + int(int8(f.post[t.nameIndexes+int(i)]))
+		if 0 <= j && j < nStandardNames {
+			return standardNames[j], nil, true
+		}
+	}
+	return "", nil, false
+}
+
+// GlyphName returns the name, such as "uni00A0" or "f_f_i", of the glyph
+// with the given index in the font's post table, or "" if it has none.
+func (f *Font) GlyphName(i Index) string {
+	standard, custom, ok := f.glyphName(i)
+	if !ok || custom == nil {
+		return standard
+	}
+	return string(custom)
+}
+
+// GlyphIndex returns the index of the first glyph with the given name in the
+// font's post table, and whether there is such a glyph.
+func (f *Font) GlyphIndex(name string) (Index, bool) {
+	t := f.postTable
+	if t == nil {
+		return 0, false
+	}
+	t.indexesOnce.Do(func() {
+		// Going backwards leaves the first of any duplicate names.
+		t.indexes = map[string]Index{}
+		for i := f.nGlyph - 1; i >= 0; i-- {
+			if n := f.GlyphName(Index(i)); n != "" {
+				t.indexes[n] = Index(i)
+			}
+		}
+	})
+	i, ok := t.indexes[name]
+	return i, ok
+}
@@ -0,0 +1,146 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+import (
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGlyphNames(t *testing.T) {
+	f, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := f.Post(); !ok || p != (Post{}) {
+		t.Errorf("Post: got %+v, %t, want zero metrics and true", p, ok)
+	}
+
+	// The ttx file lists the glyph names, with a "#n" suffix for duplicates.
+	ttx, err := ioutil.ReadFile("../testdata/luxisr.ttx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches := regexp.MustCompile(`<GlyphID id="(\d+)" name="([^"]+)"/>`).FindAllSubmatch(ttx, -1)
+	if len(matches) != f.nGlyph {
+		t.Fatalf("got %d glyph names in the ttx file, want %d", len(matches), f.nGlyph)
+	}
+	for _, m := range matches {
+		i, _ := strconv.Atoi(string(m[1]))
+		want := string(m[2])
+		if j := strings.IndexByte(want, '#'); j >= 0 {
+			want = want[:j]
+		}
+		if got := f.GlyphName(Index(i)); got != want {
+			t.Errorf("GlyphName(%d): got %q, want %q", i, got, want)
+		}
+	}
+
+	for name, want := range map[string]Index{".notdef": 0, "space": 3, "A": 36, "Euro": 210} {
+		if got, ok := f.GlyphIndex(name); !ok || got != want {
+			t.Errorf("GlyphIndex(%q): got %d, %t, want %d, true", name, got, ok, want)
+		}
+	}
+	if got, ok := f.GlyphIndex("nosuchglyph"); ok {
+		t.Errorf("GlyphIndex(%q): got %d, want no glyph", "nosuchglyph", got)
+	}
+	if got := f.GlyphName(Index(f.nGlyph)); got != "" {
+		t.Errorf("GlyphName(%d): got %q, want \"\"", f.nGlyph, got)
+	}
+}
+
+func TestPostFormats(t *testing.T) {
+	f, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parse := func(post []byte) *Font {
+		return makeTestFont(t, map[string][]byte{"post": post})
+	}
+	header := func(major, minor int) []byte {
+		// An italic angle of -12.5 degrees, an underline at -150 FUnits
+		// that is 100 FUnits thick, and a fixed pitch.
+		return cat(be16(major, minor, 0xfff3, 0x8000, -150, 100, 0, 1), make([]byte, 16))
+	}
+	n := f.nGlyph
+
+	// Version 2 names the first glyphs "uni00A0", "f_f_i" and "A", and the
+	// rest ".notdef".
+	v2 := cat(header(2, 0), be16(n, 258, 259, 36), make([]byte, 2*(n-3)))
+	v2 = append(v2, 7)
+	v2 = append(v2, "uni00A0"...)
+	v2 = append(v2, 5)
+	v2 = append(v2, "f_f_i"...)
+	// Version 2.5 shifts the glyphs by one standard name.
+	v25 := append(header(2, 0x5000), be16(n)...)
+	for i := 0; i < n; i++ {
+		v25 = append(v25, 1)
+	}
+
+	testCases := []struct {
+		desc  string
+		post  []byte
+		names map[Index]string
+	}{
+		{"version 1", header(1, 0), map[Index]string{0: ".notdef", 36: "A", 257: "dcroat", 258: ""}},
+		{"version 2", v2, map[Index]string{0: "uni00A0", 1: "f_f_i", 2: "A", 3: ".notdef"}},
+		{"version 2.5", v25, map[Index]string{0: ".null", 35: "A", 257: "", 258: ""}},
+		{"version 3", header(3, 0), map[Index]string{0: "", 36: ""}},
+	}
+	for _, tc := range testCases {
+		g := parse(tc.post)
+		p, _ := g.Post()
+		if want := (Post{-12.5, -150, 100, true}); p != want {
+			t.Errorf("%s: Post: got %+v, want %+v", tc.desc, p, want)
+		}
+		for i, want := range tc.names {
+			if got := g.GlyphName(i); got != want {
+				t.Errorf("%s: GlyphName(%d): got %q, want %q", tc.desc, i, got, want)
+			}
+			if want == "" {
+				continue
+			}
+			if got, ok := g.GlyphIndex(want); !ok || g.GlyphName(got) != want {
+				t.Errorf("%s: GlyphIndex(%q): got %d, %t", tc.desc, want, got, ok)
+			}
+		}
+	}
+
+	// GlyphIndex finds the first of the glyphs with the same name.
+	if got, ok := parse(v2).GlyphIndex(".notdef"); !ok || got != 3 {
+		t.Errorf("version 2: GlyphIndex(%q): got %d, %t, want 3, true", ".notdef", got, ok)
+	}
+
+	// A malformed table, or malformed names, are ignored.
+	badCases := []struct {
+		desc string
+		post []byte
+		// hasPost is whether the table's metrics are still valid.
+		hasPost bool
+	}{
+		{"short header", header(1, 0)[:20], false},
+		{"wrong number of glyphs", cat(header(2, 0), be16(n-1), make([]byte, 2*n)), true},
+		{"missing name indexes", cat(header(2, 0), be16(n), make([]byte, 2)), true},
+		{"bad name index", cat(header(2, 0), be16(n, 258), make([]byte, 2*(n-1))), true},
+		{"truncated name", v2[:len(v2)-1], true},
+		{"short version 2.5", cat(header(2, 0x5000), be16(n), make([]byte, n-1)), true},
+	}
+	for _, tc := range badCases {
+		g := parse(tc.post)
+		if _, ok := g.Post(); ok != tc.hasPost {
+			t.Errorf("%s: Post: got %t, want %t", tc.desc, ok, tc.hasPost)
+		}
+		if got := g.GlyphName(0); got != "" {
+			t.Errorf("%s: GlyphName(0): got %q, want \"\"", tc.desc, got)
+		}
+		if got, ok := g.GlyphIndex(".notdef"); ok {
+			t.Errorf("%s: GlyphIndex(%q): got %d, want no glyph", tc.desc, ".notdef", got)
+		}
+	}
+}
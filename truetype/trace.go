@@ -0,0 +1,147 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+import (
+	"github.com/golang/freetype/truetype/bytecode"
+	"golang.org/x/image/math/fixed"
+)
+
+// HintingStep describes one instruction of a font's hinting bytecode, after
+// it has run. See GlyphBuf's Trace field.
+type HintingStep struct {
+	// Program is the table tag of the running program: "fpgm", "prep" or
+	// "glyf".
+	Program string
+	// Function is the number of the running function, or -1 if the
+	// instruction is in the program itself.
+	Function int32
+	// PC is the offset of the instruction in its function or program.
+	PC int
+	// Opcode is the instruction's opcode.
+	Opcode byte
+	// Err is the error that the instruction failed with, if any. Hinting
+	// stops after such an instruction.
+	Err error
+	// Stack is the interpreter's stack, with the top last.
+	Stack []int32
+	// GraphicsState is the interpreter's graphics state.
+	GraphicsState GraphicsState
+	// Moved are the points that the instruction moved, in the twilight zone
+	// and in the glyph zone.
+	Moved [2][]MovedPoint
+}
+
+// Name returns the mnemonic of the step's instruction, such as "MDRP[01101]",
+// or its opcode in hexadecimal if it is undefined.
+func (s *HintingStep) Name() string {
+	return bytecode.Name(s.Opcode)
+}
+
+// MovedPoint is a point that a hinting instruction moved, as in a
+// HintingStep's Moved field. Index is the point's index in its zone, and
+// From and To are its positions, in 26.6 fixed point pixels, before and after
+// the instruction.
+type MovedPoint struct {
+	Index    int
+	From, To Point
+}
+
+// GraphicsState is the state of the hinting bytecode interpreter, described
+// at https://developer.apple.com/fonts/TTRefMan/RM04/Chap4.html
+type GraphicsState struct {
+	// ProjectionVector, FreedomVector and DualProjectionVector are unit
+	// vectors, in 2.14 fixed point.
+	ProjectionVector, FreedomVector, DualProjectionVector [2]int16
+	// RP are the reference points and ZP are the zone pointers, 0 for the
+	// twilight zone and 1 for the glyph zone.
+	RP, ZP [3]int32
+	// ControlValueCutIn, SingleWidthCutIn and SingleWidth are the control
+	// value cut-in, single width cut-in and single width value.
+	ControlValueCutIn, SingleWidthCutIn, SingleWidth fixed.Int26_6
+	// DeltaBase and DeltaShift are the delta base and shift.
+	DeltaBase, DeltaShift int32
+	// MinDistance is the minimum distance.
+	MinDistance fixed.Int26_6
+	// Loop is the loop count.
+	Loop int32
+	// RoundPeriod, RoundPhase and RoundThreshold are the rounding state.
+	// A zero RoundPeriod means that rounding is off. RoundSuper45 is whether
+	// the state was set by S45ROUND.
+	RoundPeriod, RoundPhase, RoundThreshold fixed.Int26_6
+	RoundSuper45                            bool
+	// AutoFlip is the auto flip Boolean.
+	AutoFlip bool
+	// InstructControl is the instruction control state, as set by INSTCTRL,
+	// with one bit per selector.
+	InstructControl int32
+}
+
+func (gs *graphicsState) export() GraphicsState {
+	return GraphicsState{
+		ProjectionVector:     [2]int16{int16(gs.pv[0]), int16(gs.pv[1])},
+		FreedomVector:        [2]int16{int16(gs.fv[0]), int16(gs.fv[1])},
+		DualProjectionVector: [2]int16{int16(gs.dv[0]), int16(gs.dv[1])},
+		RP:                   gs.rp,
+		ZP:                   gs.zp,
+		ControlValueCutIn:    gs.controlValueCutIn,
+		SingleWidthCutIn:     gs.singleWidthCutIn,
+		SingleWidth:          gs.singleWidth,
+		DeltaBase:            gs.deltaBase,
+		DeltaShift:           gs.deltaShift,
+		MinDistance:          gs.minDist,
+		Loop:                 gs.loop,
+		RoundPeriod:          gs.roundPeriod,
+		RoundPhase:           gs.roundPhase,
+		RoundThreshold:       gs.roundThreshold,
+		RoundSuper45:         gs.roundSuper45,
+		AutoFlip:             gs.autoFlip,
+		InstructControl:      gs.instructControl,
+	}
+}
+
+// beginStep starts tracing the instruction at pc, noting the points'
+// positions so that endStep can tell which of them moved.
+func (h *hinter) beginStep(function int32, pc int, opcode uint8) {
+	h.step = HintingStep{
+		Program:  h.program,
+		Function: function,
+		PC:       pc,
+		Opcode:   opcode,
+		Stack:    h.step.Stack[:0],
+		Moved:    [2][]MovedPoint{h.step.Moved[0][:0], h.step.Moved[1][:0]},
+	}
+	for z := range h.before {
+		h.before[z] = append(h.before[z][:0], h.points[z][current]...)
+	}
+	h.stepping = true
+}
+
+// endStep reports the instruction that beginStep started tracing, if any,
+// given the top of the stack and the error, if any, after it ran.
+func (h *hinter) endStep(top int, err error) {
+	if !h.stepping {
+		return
+	}
+	h.stepping = false
+	s := &h.step
+	s.Err = err
+	if 0 <= top && top <= len(h.stack) {
+		s.Stack = append(s.Stack, h.stack[:top]...)
+	}
+	s.GraphicsState = h.gs.export()
+	for z := range h.before {
+		for i, p := range h.points[z][current] {
+			if i >= len(h.before[z]) {
+				break
+			}
+			if q := h.before[z][i]; p.X != q.X || p.Y != q.Y {
+				s.Moved[z] = append(s.Moved[z], MovedPoint{i, q, p})
+			}
+		}
+	}
+	h.trace(s)
+}
@@ -3,7 +3,7 @@
 // FreeType License or the GNU General Public License version 2 (or
 // any later version), both of which can be found in the LICENSE file.
 
-// Package truetype provides a parser for the TTF and TTC file formats.
+// Package truetype provides a parser for the TTF, OTF and TTC file formats.
 // Those formats are documented at http://developer.apple.com/fonts/TTRefMan/
 // and http://www.microsoft.com/typography/otspec/
 //
@@ -19,6 +19,8 @@ package truetype // import "github.com/golang/freetype/truetype"
 
 import (
 	"fmt"
+	"image/color"
+	"sort"
 
 	"golang.org/x/image/math/fixed"
 )
@@ -62,6 +64,8 @@ const (
 	microsoftSymbolEncoding = 0x00030000 // PID = 3 (Microsoft), PSID = 0 (Symbol)
 	microsoftUCS2Encoding   = 0x00030001 // PID = 3 (Microsoft), PSID = 1 (UCS-2)
 	microsoftUCS4Encoding   = 0x0003000a // PID = 3 (Microsoft), PSID = 10 (UCS-4)
+	unicodeEncodingUVS      = 0x00000005 // PID = 0 (Unicode), PSID = 5 (Unicode Variation Sequences)
+	macintoshRomanEncoding  = 0x00010000 // PID = 1 (Macintosh), PSID = 0 (Roman)
 )
 
 // An HMetric holds the horizontal metrics of a single glyph.
@@ -94,6 +98,11 @@ func u32(b []byte, i int) uint32 {
 	return uint32(b[i])<<24 | uint32(b[i+1])<<16 | uint32(b[i+2])<<8 | uint32(b[i+3])
 }
 
+// u24 returns the big-endian 24-bit unsigned integer at b[i:].
+func u24(b []byte, i int) uint32 {
+	return uint32(b[i])<<16 | uint32(b[i+1])<<8 | uint32(b[i+2])
+}
+
 // u16 returns the big-endian uint16 at b[i:].
 func u16(b []byte, i int) uint16 {
 	return uint16(b[i])<<8 | uint16(b[i+1])
@@ -167,54 +176,168 @@ const (
 	locaOffsetFormatLong
 )
 
-// A cm holds a parsed cmap entry.
+// A cm holds a parsed cmap entry. The runes from start to end map to
+// consecutive glyphs, from start+delta, unless offset is non-zero, in which
+// case they map to the format 4 glyph index array at that offset. If
+// constant is true, as for format 13, then they all map to the glyph delta.
 type cm struct {
 	start, end, delta, offset uint32
+	constant                  bool
+}
+
+// A kernSubtable is a format 0 or format 2 subtable of the kern table.
+type kernSubtable struct {
+	// data is the subtable, including its header, and body is the offset of
+	// the data that follows the header.
+	data []byte
+	body int
+	// format is the subtable format. If override is true, then the
+	// subtable's non-zero values replace, rather than add to, the kerning
+	// of the subtables before it.
+	format   uint8
+	override bool
+	// nPairs is the number of kerning pairs of a format 0 subtable.
+	nPairs int
 }
 
 // A Font represents a Truetype font.
 type Font struct {
 	// Tables sliced from the TTF data. The different tables are documented
 	// at http://developer.apple.com/fonts/TTRefMan/RM06/Chap6.html
-	cmap, cvt, fpgm, glyf, hdmx, head, hhea, hmtx, kern, loca, maxp, name, os2, prep, vmtx []byte
+	cmap, cvt, fpgm, glyf, hdmx, head, hhea, hmtx, kern, loca, maxp, name, os2, post, prep, vmtx []byte
+	// The "CFF " and "CFF2" tables hold an OpenType font's cubic outlines,
+	// instead of glyf and loca.
+	cff, cff2 []byte
+	// The fvar, avar, gvar and HVAR tables hold a variable font's axes and
+	// deltas.
+	fvar, avar, gvar, hvar []byte
+	// The GDEF, GPOS and GSUB tables hold OpenType glyph classes, glyph
+	// positioning and glyph substitution data.
+	gdef, gpos, gsub []byte
+	// The EBLC and EBDT tables hold monochrome and grayscale embedded
+	// bitmaps, and the CBLC and CBDT tables hold color ones.
+	eblc, ebdt, cblc, cbdt []byte
+	// The sbix table holds PNG and JPEG color glyph images.
+	sbix []byte
+	// The COLR and CPAL tables hold color glyphs' layers and palettes.
+	colr, cpal []byte
+	// The vhea and VORG tables hold vertical layout metrics, with vmtx.
+	vhea, vorg []byte
 
 	cmapIndexes []byte
+	// cmapUVS is the cmap's format 14 subtable of Unicode Variation
+	// Sequences, if any.
+	cmapUVS []byte
+	// cmapEncoding is how runes map to the codes of the selected cmap
+	// subtable.
+	cmapEncoding int
+
+	// cffFont is the parsed cff or cff2 table. It is nil for fonts with
+	// TrueType (glyf) outlines.
+	cffFont *cffFont
+
+	// Values for variable fonts. coords are the normalized co-ordinates, one
+	// per axis, of the instance that this Font represents. They are nil for
+	// the default instance.
+	axes      []VariationAxis
+	avarMaps  [][][2]float64
+	gvarTable *gvarTable
+	hvarTable *hvarTable
+	coords    []float64
+
+	// Values for OpenType glyph positioning. kernLookups and markLookups are
+	// the indexes of the GPOS lookups for the default script and language
+	// system's kern, and mark attachment, features.
+	gposTable                *layoutTable
+	kernLookups, markLookups []int
+	// Values for OpenType glyph substitution. glyphClasses and
+	// markAttachClasses are GDEF ClassDef tables.
+	gsubTable                       *layoutTable
+	glyphClasses, markAttachClasses []byte
+
+	// kernSubtables are the kern table's horizontal subtables.
+	kernSubtables []kernSubtable
+	// bitmapStrikes are the EBLC and CBLC tables' strikes, and sbixStrikes
+	// are the sbix table's.
+	bitmapStrikes []bitmapStrike
+	sbixStrikes   []sbixStrike
+	// colrTable is the parsed COLR table, and cpalPalettes are the CPAL
+	// table's palettes.
+	colrTable    *colrTable
+	cpalPalettes [][]color.NRGBA
+	// os2Table is the parsed OS/2 table, and postTable is the parsed post
+	// table.
+	os2Table  *OS2
+	postTable *postTable
 
 	// Cached values derived from the raw ttf data.
-	cm                      []cm
-	locaOffsetFormat        int
-	nGlyph, nHMetric, nKern int
-	fUnitsPerEm             int32
-	ascent                  int32               // In FUnits.
-	descent                 int32               // In FUnits; typically negative.
-	bounds                  fixed.Rectangle26_6 // In FUnits.
+	cm               []cm
+	locaOffsetFormat int
+	nGlyph, nHMetric int
+	nVMetric         int
+	fUnitsPerEm      int32
+	ascent           int32               // In FUnits.
+	descent          int32               // In FUnits; typically negative.
+	bounds           fixed.Rectangle26_6 // In FUnits.
 	// Values from the maxp section.
 	maxTwilightPoints, maxStorage, maxFunctionDefs, maxStackElements uint16
 }
 
 func (f *Font) parseCmap() error {
+	offset, _, err := parseSubtables(f.cmap, "cmap", 4, 8, nil)
+	if err != nil {
+		// Fall back to a Mac Roman subtable, as found in old Mac fonts.
+		offset = f.findCmap(macintoshRomanEncoding)
+		if offset < 0 {
+			return err
+		}
+	}
+	f.cmapUVS = f.cmapVariationSequences()
+	return f.loadCmap(offset)
+}
+
+// loadCmap loads the cmap subtable whose encoding record is at the given
+// offset.
+func (f *Font) loadCmap(offset int) error {
 	const (
-		cmapFormat4         = 4
-		cmapFormat12        = 12
-		languageIndependent = 0
+		cmapFormat0  = 0
+		cmapFormat4  = 4
+		cmapFormat6  = 6
+		cmapFormat10 = 10
+		cmapFormat12 = 12
+		cmapFormat13 = 13
 	)
 
-	offset, _, err := parseSubtables(f.cmap, "cmap", 4, 8, nil)
-	if err != nil {
-		return err
+	switch pidPsid := u32(f.cmap, offset); pidPsid {
+	case macintoshRomanEncoding:
+		f.cmapEncoding = cmapEncodingMacRoman
+	case microsoftSymbolEncoding:
+		f.cmapEncoding = cmapEncodingSymbol
+	case microsoftUCS2Encoding, microsoftUCS4Encoding:
+		f.cmapEncoding = cmapEncodingUnicode
+	default:
+		if pidPsid>>16 != 0 || pidPsid == unicodeEncodingUVS {
+			return UnsupportedError(fmt.Sprintf("cmap encoding: %d, %d", pidPsid>>16, pidPsid&0xffff))
+		}
+		f.cmapEncoding = cmapEncodingUnicode
 	}
 	offset = int(u32(f.cmap, offset+4))
-	if offset <= 0 || offset > len(f.cmap) {
+	if offset <= 0 || offset > len(f.cmap)-4 {
 		return FormatError("bad cmap offset")
 	}
+	f.cmapIndexes = nil
 
 	cmapFormat := u16(f.cmap, offset)
 	switch cmapFormat {
-	case cmapFormat4:
-		language := u16(f.cmap, offset+4)
-		if language != languageIndependent {
-			return UnsupportedError(fmt.Sprintf("language: %d", language))
+	case cmapFormat0:
+		if len(f.cmap) < offset+6+256 {
+			return FormatError("cmap too short")
 		}
+		a := f.cmap[offset+6:]
+		f.cm = cmFromArray(0, 256, func(i int) uint32 { return uint32(a[i]) })
+		return nil
+
+	case cmapFormat4:
 		segCountX2 := int(u16(f.cmap, offset+6))
 		if segCountX2%2 == 1 {
 			return FormatError(fmt.Sprintf("bad segCountX2: %d", segCountX2))
@@ -242,17 +365,37 @@ func (f *Font) parseCmap() error {
 		f.cmapIndexes = f.cmap[offset:]
 		return nil
 
-	case cmapFormat12:
-		if u16(f.cmap, offset+2) != 0 {
+	case cmapFormat6:
+		if len(f.cmap) < offset+10 {
+			return FormatError("cmap too short")
+		}
+		first, n := uint32(u16(f.cmap, offset+6)), int(u16(f.cmap, offset+8))
+		if len(f.cmap) < offset+10+2*n {
+			return FormatError("cmap too short")
+		}
+		a := f.cmap[offset+10:]
+		f.cm = cmFromArray(first, n, func(i int) uint32 { return uint32(u16(a, 2*i)) })
+		return nil
+
+	case cmapFormat10:
+		if len(f.cmap) < offset+20 {
+			return FormatError("cmap too short")
+		}
+		first, n := u32(f.cmap, offset+12), u32(f.cmap, offset+16)
+		if uint32(len(f.cmap)-offset-20)/2 < n {
+			return FormatError("cmap too short")
+		}
+		a := f.cmap[offset+20:]
+		f.cm = cmFromArray(first, int(n), func(i int) uint32 { return uint32(u16(a, 2*i)) })
+		return nil
+
+	case cmapFormat12, cmapFormat13:
+		if len(f.cmap) < offset+16 || u16(f.cmap, offset+2) != 0 {
 			return FormatError(fmt.Sprintf("cmap format: % x", f.cmap[offset:offset+4]))
 		}
 		length := u32(f.cmap, offset+4)
-		language := u32(f.cmap, offset+8)
-		if language != languageIndependent {
-			return UnsupportedError(fmt.Sprintf("language: %d", language))
-		}
 		nGroups := u32(f.cmap, offset+12)
-		if length != 12*nGroups+16 {
+		if length != 12*nGroups+16 || uint32(len(f.cmap)-offset) < length {
 			return FormatError("inconsistent cmap length")
 		}
 		offset += 16
@@ -260,7 +403,12 @@ func (f *Font) parseCmap() error {
 		for i := uint32(0); i < nGroups; i++ {
 			f.cm[i].start = u32(f.cmap, offset+0)
 			f.cm[i].end = u32(f.cmap, offset+4)
-			f.cm[i].delta = u32(f.cmap, offset+8) - f.cm[i].start
+			if cmapFormat == cmapFormat13 {
+				f.cm[i].delta = u32(f.cmap, offset+8)
+				f.cm[i].constant = true
+			} else {
+				f.cm[i].delta = u32(f.cmap, offset+8) - f.cm[i].start
+			}
 			offset += 12
 		}
 		return nil
@@ -268,6 +416,43 @@ func (f *Font) parseCmap() error {
 	return UnsupportedError(fmt.Sprintf("cmap format: %d", cmapFormat))
 }
 
+// cmFromArray returns the cm entries for a cmap subtable that maps the n
+// runes from first to the glyphs glyph(0), glyph(1), etc. Runes that map to
+// glyph 0 are omitted, and runs of runes that map to consecutive glyphs share
+// an entry.
+func cmFromArray(first uint32, n int, glyph func(i int) uint32) []cm {
+	var ret []cm
+	for i := 0; i < n; i++ {
+		g := glyph(i)
+		if g == 0 {
+			continue
+		}
+		c := first + uint32(i)
+		if k := len(ret) - 1; k >= 0 && ret[k].end+1 == c && ret[k].delta == g-c {
+			ret[k].end = c
+			continue
+		}
+		ret = append(ret, cm{start: c, end: c, delta: g - c})
+	}
+	return ret
+}
+
+// cmapVariationSequences returns the cmap's format 14 subtable, or nil.
+func (f *Font) cmapVariationSequences() []byte {
+	n := int(u16(f.cmap, 2))
+	for i := 0; i < n && len(f.cmap) >= 12+8*i; i++ {
+		if u32(f.cmap, 4+8*i) != unicodeEncodingUVS {
+			continue
+		}
+		o := int(u32(f.cmap, 8+8*i))
+		if o < 0 || len(f.cmap) < o+10 || u16(f.cmap, o) != 14 {
+			return nil
+		}
+		return f.cmap[o:]
+	}
+	return nil
+}
+
 func (f *Font) parseHead() error {
 	if len(f.head) != 54 {
 		return FormatError(fmt.Sprintf("bad head length: %d", len(f.head)))
@@ -301,6 +486,48 @@ func (f *Font) parseHhea() error {
 	return nil
 }
 
+// parseVhea finds the number of long vertical metrics. Vertical metrics are
+// optional, so a malformed vhea table is ignored, and unscaledVMetric checks
+// each vmtx read, falling back to the OS/2 table.
+func (f *Font) parseVhea() {
+	f.nVMetric = 0
+	if len(f.vmtx) == 0 {
+		return
+	}
+	if len(f.vhea) == 0 {
+		// Without a vhea table, every glyph has a long vertical metric.
+		f.nVMetric = len(f.vmtx) / 4
+		return
+	}
+	if len(f.vhea) < 36 {
+		return
+	}
+	if n := int(u16(f.vhea, 34)); n <= f.nGlyph {
+		f.nVMetric = n
+	}
+}
+
+// parseVORG checks the VORG table, and ignores it if it is malformed.
+func (f *Font) parseVORG() {
+	if len(f.vorg) < 8 || u16(f.vorg, 0) != 1 || len(f.vorg) < 8+4*int(u16(f.vorg, 6)) {
+		f.vorg = nil
+	}
+}
+
+// vertOriginY returns the Y co-ordinate, in FUnits, of the glyph's vertical
+// origin in the VORG table, which fonts with CFF outlines may have.
+func (f *Font) vertOriginY(i Index) (y fixed.Int26_6, ok bool) {
+	if len(f.vorg) == 0 {
+		return 0, false
+	}
+	n := int(u16(f.vorg, 6))
+	j := sort.Search(n, func(j int) bool { return Index(u16(f.vorg, 8+4*j)) >= i })
+	if j < n && Index(u16(f.vorg, 8+4*j)) == i {
+		return fixed.Int26_6(int16(u16(f.vorg, 8+4*j+2))), true
+	}
+	return fixed.Int26_6(int16(u16(f.vorg, 4))), true
+}
+
 func (f *Font) parseKern() error {
 	// Apple's TrueType documentation (http://developer.apple.com/fonts/TTRefMan/RM06/Chap6kern.html) says:
 	// "Previous versions of the 'kern' table defined both the version and nTables fields in the header
@@ -309,49 +536,161 @@ func (f *Font) parseKern() error {
 	// Windows still uses the older format for the 'kern' table and will not recognize the newer one.
 	// Fonts targeted for the Mac OS only should use the new format; fonts targeted for both the Mac OS
 	// and Windows should use the old format."
-	// Since we expect that almost all fonts aim to be Windows-compatible, we only parse the "older" format,
-	// just like the C Freetype implementation.
+	// We parse both formats. Their subtables differ only in their headers.
+	f.kernSubtables = nil
 	if len(f.kern) == 0 {
-		if f.nKern != 0 {
-			return FormatError("bad kern table length")
-		}
 		return nil
 	}
-	if len(f.kern) < 18 {
+	if len(f.kern) < 4 {
 		return FormatError("kern data too short")
 	}
-	version, offset := u16(f.kern, 0), 2
-	if version != 0 {
+	var n, offset, headerLen int
+	apple := false
+	switch version := u16(f.kern, 0); version {
+	case 0:
+		n, offset, headerLen = int(u16(f.kern, 2)), 4, 6
+	case 1:
+		if len(f.kern) < 8 || u16(f.kern, 2) != 0 {
+			return FormatError("bad kern table header")
+		}
+		n, offset, headerLen, apple = int(u32(f.kern, 4)), 8, 8, true
+	default:
 		return UnsupportedError(fmt.Sprintf("kern version: %d", version))
 	}
 
-	n, offset := u16(f.kern, offset), offset+2
-	if n == 0 {
-		return UnsupportedError("kern nTables: 0")
+	// Fonts such as Xolonium Regular (https://fontlibrary.org/en/font/xolonium)
+	// have more than one subtable, and their kerning is the sum of all of
+	// the horizontal subtables.
+	for i := 0; i < n; i++ {
+		if len(f.kern) < offset+headerLen {
+			return FormatError("kern data too short")
+		}
+		var (
+			length            int
+			format            uint8
+			horizontal, cross bool
+			minimum, override bool
+		)
+		if apple {
+			length = int(u32(f.kern, offset))
+			coverage := u16(f.kern, offset+4)
+			format = uint8(coverage)
+			// Variation subtables hold deltas for variable fonts' tuples,
+			// which we treat as not horizontal.
+			horizontal = coverage&0x8000 == 0 && coverage&0x2000 == 0
+			cross = coverage&0x4000 != 0
+		} else {
+			length = int(u16(f.kern, offset+2))
+			coverage := u16(f.kern, offset+4)
+			format = uint8(coverage >> 8)
+			horizontal = coverage&0x0001 != 0
+			minimum = coverage&0x0002 != 0
+			cross = coverage&0x0004 != 0
+			override = coverage&0x0008 != 0
+			if format == 0 && len(f.kern) >= offset+headerLen+2 {
+				// The 16-bit length of a large format 0 subtable overflows,
+				// so we derive it from the number of pairs instead.
+				nPairs := int(u16(f.kern, offset+headerLen))
+				l := headerLen + 8 + 6*nPairs
+				if uint16(l) != uint16(length) {
+					return FormatError("bad kern table length")
+				}
+				length = l
+			}
+		}
+		if length < headerLen || len(f.kern) < offset+length {
+			return FormatError("kern data too short")
+		}
+		s := kernSubtable{
+			data:     f.kern[offset : offset+length],
+			body:     headerLen,
+			format:   format,
+			override: override,
+		}
+		offset += length
+
+		// We only kern along the baseline of horizontal text. Cross-stream
+		// subtables move glyphs perpendicular to the baseline, and minimum
+		// subtables limit, rather than adjust, the distance between glyphs.
+		if !horizontal || cross || minimum {
+			continue
+		}
+		switch format {
+		case 0:
+			if len(s.data) < s.body+8 {
+				return FormatError("kern data too short")
+			}
+			s.nPairs = int(u16(s.data, s.body))
+			if len(s.data) != s.body+8+6*s.nPairs {
+				return FormatError("bad kern table length")
+			}
+		case 2:
+			if len(s.data) < s.body+8 {
+				return FormatError("kern data too short")
+			}
+		default:
+			// Other formats, such as Apple's state tables, are ignored.
+			continue
+		}
+		f.kernSubtables = append(f.kernSubtables, s)
 	}
-	// TODO: support multiple subtables. In practice, almost all .ttf files
-	// have only one subtable, if they have a kern table at all. But it's not
-	// impossible. Xolonium Regular (https://fontlibrary.org/en/font/xolonium)
-	// has 3 subtables. Those subtables appear to be disjoint, rather than
-	// being the same kerning pairs encoded in three different ways.
-	//
-	// For now, we'll use only the first subtable.
+	return nil
+}
 
-	offset += 2 // Skip the version.
-	length, offset := int(u16(f.kern, offset)), offset+2
-	coverage, offset := u16(f.kern, offset), offset+2
-	if coverage != 0x0001 {
-		// We only support horizontal kerning.
-		return UnsupportedError(fmt.Sprintf("kern coverage: 0x%04x", coverage))
+// kern returns the subtable's kerning, in FUnits, for the glyph pair.
+func (s *kernSubtable) kern(i0, i1 Index) int16 {
+	switch s.format {
+	case 0:
+		g := uint32(i0)<<16 | uint32(i1)
+		x := s.body + 8
+		lo, hi := 0, s.nPairs
+		for lo < hi {
+			i := (lo + hi) / 2
+			ig := u32(s.data, x+6*i)
+			if ig < g {
+				lo = i + 1
+			} else if ig > g {
+				hi = i
+			} else {
+				return int16(u16(s.data, x+6*i+4))
+			}
+		}
+	case 2:
+		// The left class values are offsets of the rows of the kerning
+		// array, and the right class values are offsets within a row, both
+		// from the start of the subtable. Glyphs without a class have the
+		// value 0, which never points into the array.
+		array := int(u16(s.data, s.body+6))
+		l := s.kernClass(int(u16(s.data, s.body+2)), i0)
+		r := s.kernClass(int(u16(s.data, s.body+4)), i1)
+		if x := l + r; l != 0 && array <= x && x+2 <= len(s.data) {
+			return int16(u16(s.data, x))
+		}
 	}
-	f.nKern, offset = int(u16(f.kern, offset)), offset+2
-	if 6*f.nKern != length-14 {
-		return FormatError("bad kern table length")
+	return 0
+}
+
+// kernClass returns the value of the glyph in the format 2 class table at
+// the given offset.
+func (s *kernSubtable) kernClass(offset int, i Index) int {
+	if offset+4 > len(s.data) {
+		return 0
 	}
-	return nil
+	first, n := int(u16(s.data, offset)), int(u16(s.data, offset+2))
+	j := int(i) - first
+	if j < 0 || n <= j || offset+4+2*j+2 > len(s.data) {
+		return 0
+	}
+	return int(u16(s.data, offset+4+2*j))
 }
 
 func (f *Font) parseMaxp() error {
+	// Fonts with CFF outlines have a version 0.5 maxp table, which holds only
+	// the number of glyphs.
+	if len(f.maxp) == 6 && u32(f.maxp, 0) == 0x00005000 {
+		f.nGlyph = int(u16(f.maxp, 4))
+		return nil
+	}
 	if len(f.maxp) != 32 {
 		return FormatError(fmt.Sprintf("bad maxp length: %d", len(f.maxp)))
 	}
@@ -363,6 +702,24 @@ func (f *Font) parseMaxp() error {
 	return nil
 }
 
+func (f *Font) parseCFF() (err error) {
+	switch {
+	case len(f.cff2) != 0:
+		f.cffFont, err = parseCFF(f.cff2, true)
+	case len(f.cff) != 0:
+		f.cffFont, err = parseCFF(f.cff, false)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if f.cffFont.charStrings.count < f.nGlyph {
+		return FormatError(fmt.Sprintf("bad CFF CharStrings count: %d", f.cffFont.charStrings.count))
+	}
+	return nil
+}
+
 // scale returns x divided by f.fUnitsPerEm, rounded to the nearest integer.
 func (f *Font) scale(x fixed.Int26_6) fixed.Int26_6 {
 	if x >= 0 {
@@ -390,7 +747,26 @@ func (f *Font) FUnitsPerEm() int32 {
 
 // Index returns a Font's index for the given rune.
 func (f *Font) Index(x rune) Index {
-	c := uint32(x)
+	switch f.cmapEncoding {
+	case cmapEncodingMacRoman:
+		c, ok := macRomanCode(x)
+		if !ok {
+			return 0
+		}
+		return f.lookup(c)
+	case cmapEncodingSymbol:
+		// Symbol fonts map the code points U+F020 to U+F0FF, for which
+		// legacy applications pass a single byte code.
+		if i := f.lookup(uint32(x)); i != 0 || x < 0x20 || 0xff < x {
+			return i
+		}
+		return f.lookup(0xf000 | uint32(x))
+	}
+	return f.lookup(uint32(x))
+}
+
+// lookup returns the glyph index for the given code of the cmap subtable.
+func (f *Font) lookup(c uint32) Index {
 	for i, j := 0, len(f.cm); i < j; {
 		h := i + (j-i)/2
 		cm := &f.cm[h]
@@ -398,52 +774,77 @@ func (f *Font) Index(x rune) Index {
 			j = h
 		} else if cm.end < c {
 			i = h + 1
-		} else if cm.offset == 0 {
-			return Index(c + cm.delta)
 		} else {
-			offset := int(cm.offset) + 2*(h-len(f.cm)+int(c-cm.start))
-			return Index(u16(f.cmapIndexes, offset))
+			return f.cmIndex(h, c)
 		}
 	}
 	return 0
 }
 
+// cmIndex returns the glyph index that the h'th cm entry maps the code c to.
+func (f *Font) cmIndex(h int, c uint32) Index {
+	cm := &f.cm[h]
+	if cm.constant {
+		return Index(cm.delta)
+	} else if cm.offset == 0 {
+		return Index(c + cm.delta)
+	}
+	offset := int(cm.offset) + 2*(h-len(f.cm)+int(c-cm.start))
+	return Index(u16(f.cmapIndexes, offset))
+}
+
+// IndexVariation returns a Font's index for the given Unicode Variation
+// Sequence, which is a base rune followed by a variation selector, such as
+// U+FE0F to request the emoji presentation of U+2764 HEAVY BLACK HEART. If
+// the font does not support the sequence, the selector is ignored, and the
+// result is the same as Index(base).
+func (f *Font) IndexVariation(base, selector rune) Index {
+	b := f.cmapUVS
+	if len(b) < 10 {
+		return f.Index(base)
+	}
+	n := int(u32(b, 6))
+	if n < 0 || (len(b)-10)/11 < n {
+		return f.Index(base)
+	}
+	i := sort.Search(n, func(i int) bool { return rune(u24(b, 10+11*i)) >= selector })
+	if i == n || rune(u24(b, 10+11*i)) != selector {
+		return f.Index(base)
+	}
+	x := 10 + 11*i
+	// The non-default table lists the sequences with their own glyph.
+	if o := int(u32(b, x+7)); o > 0 && len(b) >= o+4 {
+		t := b[o:]
+		m := int(u32(t, 0))
+		if m >= 0 && (len(t)-4)/5 >= m {
+			j := sort.Search(m, func(j int) bool { return rune(u24(t, 4+5*j)) >= base })
+			if j < m && rune(u24(t, 4+5*j)) == base {
+				return Index(u16(t, 4+5*j+3))
+			}
+		}
+	}
+	return f.Index(base)
+}
+
+// IsVariationSelector returns whether r is a variation selector, which
+// selects a variant of the preceding rune.
+func IsVariationSelector(r rune) bool {
+	return 0xfe00 <= r && r <= 0xfe0f || 0xe0100 <= r && r <= 0xe01ef
+}
+
 // Name returns the Font's name value for the given NameID. It returns "" if
-// there was an error, or if that name was not found.
+// there was an error, or if that name was not found. It prefers the Unicode
+// platform's names to the Microsoft platform's; see LocalizedName to choose
+// a name by language, and NameRecords for all of the names.
 func (f *Font) Name(id NameID) string {
-	x, platformID, err := parseSubtables(f.name, "name", 6, 12, func(b []byte) bool {
+	x, _, err := parseSubtables(f.name, "name", 6, 12, func(b []byte) bool {
 		return NameID(u16(b, 6)) == id
 	})
 	if err != nil {
 		return ""
 	}
-	offset, length := u16(f.name, 4)+u16(f.name, x+10), u16(f.name, x+8)
-	// Return the ASCII value of the encoded string.
-	// The string is encoded as UTF-16 on non-Apple platformIDs; Apple is platformID 1.
-	src := f.name[offset : offset+length]
-	var dst []byte
-	if platformID != 1 { // UTF-16.
-		if len(src)&1 != 0 {
-			return ""
-		}
-		dst = make([]byte, len(src)/2)
-		for i := range dst {
-			dst[i] = printable(u16(src, 2*i))
-		}
-	} else { // ASCII.
-		dst = make([]byte, len(src))
-		for i, c := range src {
-			dst[i] = printable(uint16(c))
-		}
-	}
-	return string(dst)
-}
-
-func printable(r uint16) byte {
-	if 0x20 <= r && r < 0x7f {
-		return byte(r)
-	}
-	return '?'
+	s, _ := f.nameString(u16(f.name, x), u16(f.name, x+2), x)
+	return s
 }
 
 // unscaledHMetric returns the unscaled horizontal metrics for the glyph with
@@ -455,15 +856,20 @@ func (f *Font) unscaledHMetric(i Index) (h HMetric) {
 	}
 	if j >= f.nHMetric {
 		p := 4 * (f.nHMetric - 1)
-		return HMetric{
+		h = HMetric{
 			AdvanceWidth:    fixed.Int26_6(u16(f.hmtx, p)),
 			LeftSideBearing: fixed.Int26_6(int16(u16(f.hmtx, p+2*(j-f.nHMetric)+4))),
 		}
+	} else {
+		h = HMetric{
+			AdvanceWidth:    fixed.Int26_6(u16(f.hmtx, 4*j)),
+			LeftSideBearing: fixed.Int26_6(int16(u16(f.hmtx, 4*j+2))),
+		}
 	}
-	return HMetric{
-		AdvanceWidth:    fixed.Int26_6(u16(f.hmtx, 4*j)),
-		LeftSideBearing: fixed.Int26_6(int16(u16(f.hmtx, 4*j+2))),
+	if f.hvarTable != nil && len(f.coords) != 0 {
+		f.varyHMetric(i, &h)
 	}
+	return h
 }
 
 // HMetric returns the horizontal metrics for the glyph with the given index.
@@ -481,10 +887,20 @@ func (f *Font) unscaledVMetric(i Index, yMax fixed.Int26_6) (v VMetric) {
 	if j < 0 || f.nGlyph <= j {
 		return VMetric{}
 	}
-	if 4*j+4 <= len(f.vmtx) {
-		return VMetric{
-			AdvanceHeight:  fixed.Int26_6(u16(f.vmtx, 4*j)),
-			TopSideBearing: fixed.Int26_6(int16(u16(f.vmtx, 4*j+2))),
+	if f.nVMetric != 0 {
+		if j < f.nVMetric {
+			if 4*j+4 <= len(f.vmtx) {
+				return VMetric{
+					AdvanceHeight:  fixed.Int26_6(u16(f.vmtx, 4*j)),
+					TopSideBearing: fixed.Int26_6(int16(u16(f.vmtx, 4*j+2))),
+				}
+			}
+		} else if q := 4*f.nVMetric + 2*(j-f.nVMetric); q+2 <= len(f.vmtx) {
+			// Glyphs after the long metrics share the last advance height.
+			return VMetric{
+				AdvanceHeight:  fixed.Int26_6(u16(f.vmtx, 4*(f.nVMetric-1))),
+				TopSideBearing: fixed.Int26_6(int16(u16(f.vmtx, q))),
+			}
 		}
 	}
 	// The OS/2 table has grown over time.
@@ -517,94 +933,214 @@ func (f *Font) VMetric(scale fixed.Int26_6, i Index) VMetric {
 
 // Kern returns the horizontal adjustment for the given glyph pair. A positive
 // kern means to move the glyphs further apart.
+//
+// Kerning is taken from the GPOS table's kern feature, if present, and from
+// the kern table otherwise.
 func (f *Font) Kern(scale fixed.Int26_6, i0, i1 Index) fixed.Int26_6 {
-	if f.nKern == 0 {
-		return 0
+	if kern, ok := f.gposKern(i0, i1); ok {
+		return f.scale(scale * fixed.Int26_6(kern))
 	}
-	g := uint32(i0)<<16 | uint32(i1)
-	lo, hi := 0, f.nKern
-	for lo < hi {
-		i := (lo + hi) / 2
-		ig := u32(f.kern, 18+6*i)
-		if ig < g {
-			lo = i + 1
-		} else if ig > g {
-			hi = i
+	kern := 0
+	for i := range f.kernSubtables {
+		s := &f.kernSubtables[i]
+		if k := int(s.kern(i0, i1)); s.override {
+			if k != 0 {
+				kern = k
+			}
 		} else {
-			return f.scale(scale * fixed.Int26_6(int16(u16(f.kern, 22+6*i))))
+			kern += k
 		}
 	}
-	return 0
+	return f.scale(scale * fixed.Int26_6(kern))
 }
 
-// Parse returns a new Font for the given TTF or TTC data.
+// Parse returns a new Font for the given TTF, OTF or TTC data. OpenType (OTF)
+// fonts may have either TrueType or CFF outlines.
 //
 // For TrueType Collections, the first font in the collection is parsed.
 func Parse(ttf []byte) (font *Font, err error) {
-	return parse(ttf, 0)
+	return ParseIndex(ttf, 0)
+}
+
+// ParseIndex returns a new Font for the i'th font in the given TTF or TTC
+// data. For TTF data, which always holds exactly one font, i must be 0.
+//
+// Use NumFonts to find the number of fonts in a TrueType Collection, and
+// CollectionNames to select a font by name.
+func ParseIndex(ttf []byte, i int) (font *Font, err error) {
+	offset, err := fontOffset(ttf, i)
+	if err != nil {
+		return nil, err
+	}
+	return parse(ttf, offset)
+}
+
+// NumFonts returns the number of fonts in the given TTF or TTC data. TTF data
+// always holds exactly one font.
+func NumFonts(ttf []byte) (int, error) {
+	if len(ttf) < 12 {
+		return 0, FormatError("TTF data is too short")
+	}
+	if u32(ttf, 0) != 0x74746366 { // "ttcf" as a big-endian uint32.
+		return 1, nil
+	}
+	ttcVersion := u32(ttf, 4)
+	if ttcVersion != 0x00010000 && ttcVersion != 0x00020000 {
+		return 0, FormatError("bad TTC version")
+	}
+	numFonts := int(u32(ttf, 8))
+	if numFonts <= 0 {
+		return 0, FormatError("bad number of TTC fonts")
+	}
+	if len(ttf[12:])/4 < numFonts {
+		return 0, FormatError("TTC offset table is too short")
+	}
+	return numFonts, nil
+}
+
+// fontOffset returns the offset of the i'th font's table directory in the
+// given TTF or TTC data.
+func fontOffset(ttf []byte, i int) (int, error) {
+	n, err := NumFonts(ttf)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 || n <= i {
+		return 0, FormatError(fmt.Sprintf("bad font index: %d", i))
+	}
+	if u32(ttf, 0) != 0x74746366 { // "ttcf" as a big-endian uint32.
+		return 0, nil
+	}
+	offset := int(u32(ttf, 12+4*i))
+	if offset <= 0 || offset > len(ttf) {
+		return 0, FormatError("bad TTC offset")
+	}
+	return offset, nil
+}
+
+// A CollectionName holds the names of one font in a TrueType Collection.
+type CollectionName struct {
+	// Family and Subfamily are the font's NameIDFontFamily and
+	// NameIDFontSubfamily values, such as "Noto Sans CJK JP" and "Bold".
+	Family, Subfamily string
+}
+
+// CollectionNames returns the names of each font in the given TTF or TTC
+// data, in collection order. The i'th element describes the font returned by
+// ParseIndex(ttf, i). Only each font's table directory and name table are
+// examined, so a font that cannot otherwise be parsed still has a name.
+func CollectionNames(ttf []byte) ([]CollectionName, error) {
+	n, err := NumFonts(ttf)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]CollectionName, n)
+	for i := range names {
+		offset, err := fontOffset(ttf, i)
+		if err != nil {
+			return nil, err
+		}
+		f := new(Font)
+		if err := f.readTables(ttf, offset); err != nil {
+			return nil, err
+		}
+		names[i] = CollectionName{
+			Family:    f.Name(NameIDFontFamily),
+			Subfamily: f.Name(NameIDFontSubfamily),
+		}
+	}
+	return names, nil
 }
 
 func parse(ttf []byte, offset int) (font *Font, err error) {
-	if len(ttf)-offset < 12 {
-		err = FormatError("TTF data is too short")
+	f := new(Font)
+	if err = f.readTables(ttf, offset); err != nil {
 		return
 	}
-	originalOffset := offset
+	// Parse and sanity-check the TTF data.
+	if err = f.parseHead(); err != nil {
+		return
+	}
+	if err = f.parseMaxp(); err != nil {
+		return
+	}
+	if err = f.parseCmap(); err != nil {
+		return
+	}
+	if err = f.parseKern(); err != nil {
+		return
+	}
+	if err = f.parseHhea(); err != nil {
+		return
+	}
+	f.parseOS2()
+	f.parsePost()
+	f.parseVhea()
+	f.parseVORG()
+	if err = f.parseCFF(); err != nil {
+		return
+	}
+	f.parseVariations()
+	f.parseGDEF()
+	f.parseGPOS()
+	f.parseGSUB()
+	f.parseBitmaps()
+	f.parseSbix()
+	f.parseColor()
+	font = f
+	return
+}
+
+// readTables assigns f's table slices from the table directory at the given
+// offset of the TTF data. It does not parse or sanity-check the tables.
+func (f *Font) readTables(ttf []byte, offset int) (err error) {
+	if len(ttf)-offset < 12 {
+		return FormatError("TTF data is too short")
+	}
 	magic, offset := u32(ttf, offset), offset+4
 	switch magic {
-	case 0x00010000:
+	case 0x00010000, 0x4f54544f: // 1.0 or "OTTO" as a big-endian uint32.
 		// No-op.
 	case 0x74746366: // "ttcf" as a big-endian uint32.
-		if originalOffset != 0 {
-			err = FormatError("recursive TTC")
-			return
-		}
-		ttcVersion, offset := u32(ttf, offset), offset+4
-		if ttcVersion != 0x00010000 && ttcVersion != 0x00020000 {
-			err = FormatError("bad TTC version")
-			return
-		}
-		numFonts, offset := int(u32(ttf, offset)), offset+4
-		if numFonts <= 0 {
-			err = FormatError("bad number of TTC fonts")
-			return
-		}
-		if len(ttf[offset:])/4 < numFonts {
-			err = FormatError("TTC offset table is too short")
-			return
-		}
-		// TODO: provide an API to select which font in a TrueType collection to return,
-		// not just the first one. This may require an API to parse a TTC's name tables,
-		// so users of this package can select the font in a TTC by name.
-		offset = int(u32(ttf, offset))
-		if offset <= 0 || offset > len(ttf) {
-			err = FormatError("bad TTC offset")
-			return
-		}
-		return parse(ttf, offset)
+		return FormatError("recursive TTC")
 	default:
-		err = FormatError("bad TTF version")
-		return
+		return FormatError("bad TTF version")
 	}
 	n, offset := int(u16(ttf, offset)), offset+2
 	offset += 6 // Skip the searchRange, entrySelector and rangeShift.
 	if len(ttf) < 16*n+offset {
-		err = FormatError("TTF data is too short")
-		return
+		return FormatError("TTF data is too short")
 	}
-	f := new(Font)
 	// Assign the table slices.
 	for i := 0; i < n; i++ {
 		x := 16*i + offset
 		switch string(ttf[x : x+4]) {
+		case "CBDT":
+			f.cbdt, err = readTable(ttf, ttf[x+8:x+16])
+		case "CBLC":
+			f.cblc, err = readTable(ttf, ttf[x+8:x+16])
+		case "CFF ":
+			f.cff, err = readTable(ttf, ttf[x+8:x+16])
+		case "CFF2":
+			f.cff2, err = readTable(ttf, ttf[x+8:x+16])
+		case "COLR":
+			f.colr, err = readTable(ttf, ttf[x+8:x+16])
+		case "CPAL":
+			f.cpal, err = readTable(ttf, ttf[x+8:x+16])
+		case "avar":
+			f.avar, err = readTable(ttf, ttf[x+8:x+16])
 		case "cmap":
 			f.cmap, err = readTable(ttf, ttf[x+8:x+16])
 		case "cvt ":
 			f.cvt, err = readTable(ttf, ttf[x+8:x+16])
 		case "fpgm":
 			f.fpgm, err = readTable(ttf, ttf[x+8:x+16])
+		case "fvar":
+			f.fvar, err = readTable(ttf, ttf[x+8:x+16])
 		case "glyf":
 			f.glyf, err = readTable(ttf, ttf[x+8:x+16])
+		case "gvar":
+			f.gvar, err = readTable(ttf, ttf[x+8:x+16])
 		case "hdmx":
 			f.hdmx, err = readTable(ttf, ttf[x+8:x+16])
 		case "head":
@@ -621,10 +1157,30 @@ func parse(ttf []byte, offset int) (font *Font, err error) {
 			f.maxp, err = readTable(ttf, ttf[x+8:x+16])
 		case "name":
 			f.name, err = readTable(ttf, ttf[x+8:x+16])
+		case "EBDT":
+			f.ebdt, err = readTable(ttf, ttf[x+8:x+16])
+		case "EBLC":
+			f.eblc, err = readTable(ttf, ttf[x+8:x+16])
+		case "GDEF":
+			f.gdef, err = readTable(ttf, ttf[x+8:x+16])
+		case "GPOS":
+			f.gpos, err = readTable(ttf, ttf[x+8:x+16])
+		case "GSUB":
+			f.gsub, err = readTable(ttf, ttf[x+8:x+16])
+		case "HVAR":
+			f.hvar, err = readTable(ttf, ttf[x+8:x+16])
 		case "OS/2":
 			f.os2, err = readTable(ttf, ttf[x+8:x+16])
+		case "VORG":
+			f.vorg, err = readTable(ttf, ttf[x+8:x+16])
+		case "post":
+			f.post, err = readTable(ttf, ttf[x+8:x+16])
 		case "prep":
 			f.prep, err = readTable(ttf, ttf[x+8:x+16])
+		case "sbix":
+			f.sbix, err = readTable(ttf, ttf[x+8:x+16])
+		case "vhea":
+			f.vhea, err = readTable(ttf, ttf[x+8:x+16])
 		case "vmtx":
 			f.vmtx, err = readTable(ttf, ttf[x+8:x+16])
 		}
@@ -632,22 +1188,5 @@ func parse(ttf []byte, offset int) (font *Font, err error) {
 			return
 		}
 	}
-	// Parse and sanity-check the TTF data.
-	if err = f.parseHead(); err != nil {
-		return
-	}
-	if err = f.parseMaxp(); err != nil {
-		return
-	}
-	if err = f.parseCmap(); err != nil {
-		return
-	}
-	if err = f.parseKern(); err != nil {
-		return
-	}
-	if err = f.parseHhea(); err != nil {
-		return
-	}
-	font = f
-	return
+	return nil
 }
@@ -11,6 +11,8 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
@@ -234,6 +236,192 @@ func TestName(t *testing.T) {
 	}
 }
 
+func TestVMetricTruncatedVmtx(t *testing.T) {
+	// Without a vhea table, the 6 bytes of vmtx are one long metric and one
+	// top side bearing. The other glyphs fall back to the OS/2 table's
+	// typographic ascender and descender, of 1604 and -420 FUnits.
+	g := makeTestFont(t, map[string][]byte{"vhea": nil, "vmtx": be16(2000, 100, 200)})
+	fupe := fixed.Int26_6(g.FUnitsPerEm())
+	testCases := []struct {
+		i    Index
+		want VMetric
+	}{
+		{0, VMetric{2000, 100}},
+		{1, VMetric{2000, 200}},
+		{5, VMetric{1604 + 420, 1604}},
+	}
+	for _, tc := range testCases {
+		if got := g.VMetric(fupe, tc.i); got != tc.want {
+			t.Errorf("VMetric(%d): got %v, want %v", tc.i, got, tc.want)
+		}
+	}
+}
+
+func TestVMetricBadTables(t *testing.T) {
+	f := makeTestFont(t, nil)
+	fupe := fixed.Int26_6(f.FUnitsPerEm())
+	// The OS/2 table's typographic ascender and descender are 1604 and -420
+	// FUnits.
+	fallback := VMetric{1604 + 420, 1604}
+	testCases := []struct {
+		desc         string
+		extra        map[string][]byte
+		want0, want5 VMetric
+	}{
+		{"truncated vhea", map[string][]byte{"vhea": f.vhea[:20]}, fallback, fallback},
+		{"truncated vmtx", map[string][]byte{"vmtx": f.vmtx[:4]}, f.VMetric(fupe, 0), fallback},
+		{"truncated VORG", map[string][]byte{"VORG": be16(1, 0, 0)}, f.VMetric(fupe, 0), f.VMetric(fupe, 5)},
+	}
+	for _, tc := range testCases {
+		g := makeTestFont(t, tc.extra)
+		if got := g.VMetric(fupe, 0); got != tc.want0 {
+			t.Errorf("%s: VMetric(0): got %v, want %v", tc.desc, got, tc.want0)
+		}
+		if got := g.VMetric(fupe, 5); got != tc.want5 {
+			t.Errorf("%s: VMetric(5): got %v, want %v", tc.desc, got, tc.want5)
+		}
+	}
+}
+
+// makeSFNT returns font data with the given magic number and tables. The
+// table directory is sorted by tag, as the OpenType specification requires.
+func makeSFNT(magic uint32, tables map[string][]byte) []byte {
+	var tags []string
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	b := []byte{byte(magic >> 24), byte(magic >> 16), byte(magic >> 8), byte(magic)}
+	b = append(b, byte(len(tags)>>8), byte(len(tags)), 0, 0, 0, 0, 0, 0)
+	offset := 12 + 16*len(tags)
+	for _, tag := range tags {
+		n := len(tables[tag])
+		b = append(b, tag...)
+		b = append(b, 0, 0, 0, 0)
+		b = append(b, byte(offset>>24), byte(offset>>16), byte(offset>>8), byte(offset))
+		b = append(b, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+		offset += (n + 3) &^ 3
+	}
+	for _, tag := range tags {
+		b = append(b, tables[tag]...)
+		for len(b)%4 != 0 {
+			b = append(b, 0)
+		}
+	}
+	return b
+}
+
+// makeTestFont returns luxisr.ttf with the tables in extra added, or
+// replacing its own, and with the tables that extra maps to nil removed. It
+// fails the test if the font does not parse.
+func makeTestFont(t *testing.T, extra map[string][]byte) *Font {
+	b, err := ioutil.ReadFile("../testdata/luxisr.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := map[string][]byte{}
+	for i, n := 0, int(u16(b, 4)); i < n; i++ {
+		x := 12 + 16*i
+		offset, length := u32(b, x+8), u32(b, x+12)
+		tables[string(b[x:x+4])] = b[offset : offset+length]
+	}
+	for tag, table := range extra {
+		if table == nil {
+			delete(tables, tag)
+		} else {
+			tables[tag] = table
+		}
+	}
+	f, err := Parse(makeSFNT(0x00010000, tables))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// makeTTC returns a TrueType Collection holding the given TTF data. Each
+// font's table directory offsets are relocated to its place in the TTC.
+func makeTTC(ttfs ...[]byte) []byte {
+	ttc := []byte("ttcf\x00\x01\x00\x00")
+	ttc = append(ttc, byte(len(ttfs)>>24), byte(len(ttfs)>>16), byte(len(ttfs)>>8), byte(len(ttfs)))
+	base := len(ttc) + 4*len(ttfs)
+	for _, ttf := range ttfs {
+		ttc = append(ttc, byte(base>>24), byte(base>>16), byte(base>>8), byte(base))
+		base += (len(ttf) + 3) &^ 3
+	}
+	for _, ttf := range ttfs {
+		start := len(ttc)
+		ttc = append(ttc, ttf...)
+		for len(ttc)%4 != 0 {
+			ttc = append(ttc, 0)
+		}
+		for i, n := 0, int(u16(ttf, 4)); i < n; i++ {
+			x := start + 12 + 16*i + 8
+			o := u32(ttc, x) + uint32(start)
+			ttc[x+0], ttc[x+1], ttc[x+2], ttc[x+3] = byte(o>>24), byte(o>>16), byte(o>>8), byte(o)
+		}
+	}
+	return ttc
+}
+
+func TestCollection(t *testing.T) {
+	names := []string{"luxisr", "luxirr", "luximr"}
+	var ttfs [][]byte
+	for _, name := range names {
+		b, err := ioutil.ReadFile(fmt.Sprintf("../testdata/%s.ttf", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ttfs = append(ttfs, b)
+	}
+	ttc := makeTTC(ttfs...)
+
+	if n, err := NumFonts(ttfs[0]); n != 1 || err != nil {
+		t.Errorf("NumFonts(TTF): got %d, %v, want 1, nil", n, err)
+	}
+	n, err := NumFonts(ttc)
+	if err != nil {
+		t.Fatalf("NumFonts: %v", err)
+	}
+	if n != len(names) {
+		t.Fatalf("NumFonts: got %d, want %d", n, len(names))
+	}
+
+	gotNames, err := CollectionNames(ttc)
+	if err != nil {
+		t.Fatalf("CollectionNames: %v", err)
+	}
+	wantNames := []CollectionName{
+		{"Luxi Sans", "Regular"},
+		{"Luxi Serif", "Regular"},
+		{"Luxi Mono", "Regular"},
+	}
+	if !reflect.DeepEqual(gotNames, wantNames) {
+		t.Errorf("CollectionNames:\ngot  %v\nwant %v", gotNames, wantNames)
+	}
+
+	for i := 0; i < n; i++ {
+		f, err := ParseIndex(ttc, i)
+		if err != nil {
+			t.Errorf("ParseIndex(%d): %v", i, err)
+			continue
+		}
+		if got, want := f.Name(NameIDFontFamily), wantNames[i].Family; got != want {
+			t.Errorf("ParseIndex(%d): family: got %q, want %q", i, got, want)
+		}
+		g := &GlyphBuf{}
+		if err := g.Load(f, fixed.I(12), f.Index('A'), font.HintingFull); err != nil {
+			t.Errorf("ParseIndex(%d): Load: %v", i, err)
+		}
+	}
+	if _, err := ParseIndex(ttc, n); err == nil {
+		t.Errorf("ParseIndex(%d): got nil error, want non-nil", n)
+	}
+	if _, err := ParseIndex(ttfs[0], 1); err == nil {
+		t.Errorf("ParseIndex(TTF, 1): got nil error, want non-nil")
+	}
+}
+
 type scalingTestData struct {
 	advanceWidth fixed.Int26_6
 	bounds       fixed.Rectangle26_6
@@ -398,3 +586,410 @@ func testScaling(t *testing.T, h font.Hinting) {
 
 func TestScalingHintingNone(t *testing.T) { testScaling(t, font.HintingNone) }
 func TestScalingHintingFull(t *testing.T) { testScaling(t, font.HintingFull) }
+
+func TestHintingVertical(t *testing.T) {
+	f, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scale := fixed.I(13)
+	for _, r := range "AVgé" {
+		i := f.Index(r)
+		var none, full, vert GlyphBuf
+		for _, x := range []struct {
+			g *GlyphBuf
+			h font.Hinting
+		}{{&none, font.HintingNone}, {&full, font.HintingFull}, {&vert, font.HintingVertical}} {
+			if err := x.g.Load(f, scale, i, x.h); err != nil {
+				t.Fatalf("%q: Load(%v): %v", r, x.h, err)
+			}
+		}
+		if len(vert.Points) != len(none.Points) || len(vert.Points) != len(full.Points) {
+			t.Errorf("%q: got %d points, want %d", r, len(vert.Points), len(none.Points))
+			continue
+		}
+		// X is unhinted and Y is hinted.
+		for j, p := range vert.Points {
+			if p.X != none.Points[j].X || p.Y != full.Points[j].Y {
+				t.Errorf("%q: point %d: got (%v, %v), want (%v, %v)",
+					r, j, p.X, p.Y, none.Points[j].X, full.Points[j].Y)
+				break
+			}
+		}
+		if vert.AdvanceWidth != none.AdvanceWidth {
+			t.Errorf("%q: AdvanceWidth: got %v, want %v", r, vert.AdvanceWidth, none.AdvanceWidth)
+		}
+		want := fixed.Rectangle26_6{
+			Min: fixed.Point26_6{X: none.Bounds.Min.X, Y: full.Bounds.Min.Y},
+			Max: fixed.Point26_6{X: none.Bounds.Max.X, Y: full.Bounds.Max.Y},
+		}
+		if vert.Bounds != want {
+			t.Errorf("%q: Bounds: got %v, want %v", r, vert.Bounds, want)
+		}
+	}
+
+	// A face with vertical hinting does not round advances.
+	face := NewFace(f, &Options{Size: 13, Hinting: font.HintingVertical})
+	advance, _ := face.GlyphAdvance('A')
+	if want := f.HMetric(scale, f.Index('A')).AdvanceWidth; advance != want {
+		t.Errorf("GlyphAdvance: got %v, want %v", advance, want)
+	}
+}
+
+func TestSubpixelHinting(t *testing.T) {
+	f, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scale := fixed.I(13)
+	for _, r := range "AVgé" {
+		i := f.Index(r)
+		none, full, sub := GlyphBuf{}, GlyphBuf{}, GlyphBuf{SubpixelHinting: true}
+		if err := none.Load(f, scale, i, font.HintingNone); err != nil {
+			t.Fatalf("%q: Load(none): %v", r, err)
+		}
+		if err := full.Load(f, scale, i, font.HintingFull); err != nil {
+			t.Fatalf("%q: Load(full): %v", r, err)
+		}
+		if err := sub.Load(f, scale, i, font.HintingFull); err != nil {
+			t.Fatalf("%q: Load(subpixel): %v", r, err)
+		}
+		if len(sub.Points) != len(none.Points) {
+			t.Errorf("%q: got %d points, want %d", r, len(sub.Points), len(none.Points))
+			continue
+		}
+		// X is unhinted, give or take IUP[x]'s rounding, and Y is hinted.
+		movedY := false
+		for j, p := range sub.Points {
+			if d := p.X - none.Points[j].X; d < -1 || 1 < d {
+				t.Errorf("%q: point %d: got X %v, want %v", r, j, p.X, none.Points[j].X)
+				break
+			}
+			movedY = movedY || p.Y != none.Points[j].Y
+		}
+		if !movedY {
+			t.Errorf("%q: no point was hinted in Y", r)
+		}
+		if want := (none.AdvanceWidth + 32) &^ 63; sub.AdvanceWidth != want {
+			t.Errorf("%q: AdvanceWidth: got %v, want %v", r, sub.AdvanceWidth, want)
+		}
+	}
+}
+
+// makeTestCmapFont returns luxisr.ttf with the given cmap subtables, each of
+// which is preceded by its 32-bit platform and encoding IDs.
+func makeTestCmapFont(t *testing.T, subtables ...[]byte) *Font {
+	cmap := be16(0, len(subtables))
+	offset := 4 + 8*len(subtables)
+	var data []byte
+	for _, s := range subtables {
+		cmap = append(cmap, s[:4]...)
+		cmap = append(cmap, be16((offset+len(data))>>16, offset+len(data))...)
+		data = append(data, s[4:]...)
+	}
+	return makeTestFont(t, map[string][]byte{"cmap": append(cmap, data...)})
+}
+
+func TestCmapFormats(t *testing.T) {
+	format0 := make([]byte, 256)
+	format0['A'], format0['B'], format0['C'] = 36, 37, 50
+	testCases := []struct {
+		desc     string
+		subtable []byte
+		want     map[rune]Index
+	}{{
+		"format 0",
+		cat(be16(0, 3, 0, 262, 0), format0),
+		map[rune]Index{'A': 36, 'B': 37, 'C': 50, 'D': 0, 0x100: 0},
+	}, {
+		"format 6",
+		cat(be16(0, 3, 6, 16, 0, 0x41, 3), be16(36, 0, 50)),
+		map[rune]Index{'@': 0, 'A': 36, 'B': 0, 'C': 50, 'D': 0},
+	}, {
+		"format 10",
+		cat(be16(0, 4, 10, 0, 0, 26, 0, 0, 1, 0xf600, 0, 3), be16(7, 8, 9)),
+		map[rune]Index{0x1f5ff: 0, 0x1f600: 7, 0x1f601: 8, 0x1f602: 9, 0x1f603: 0},
+	}, {
+		"format 13",
+		cat(be16(0, 4, 13, 0, 0, 40, 0, 0, 0, 2),
+			be16(0, 0x20, 0, 0x7e, 0, 3), be16(1, 0, 2, 0xffff, 0, 4)),
+		map[rune]Index{0x1f: 0, 0x20: 3, 'A': 3, 0x7e: 3, 0x7f: 0, 0x10000: 4, 0x2ffff: 4, 0x30000: 0},
+	}}
+	for _, tc := range testCases {
+		f := makeTestCmapFont(t, tc.subtable)
+		for r, want := range tc.want {
+			if got := f.Index(r); got != want {
+				t.Errorf("%s: Index(%#x): got %d, want %d", tc.desc, r, got, want)
+			}
+		}
+	}
+}
+
+func TestIndexVariation(t *testing.T) {
+	f := makeTestCmapFont(t,
+		cat(be16(0, 3, 6, 16, 0, 0x41, 3), be16(36, 37, 38)),
+		cat(
+			// Two selectors: FE00 has a default sequence for B and a
+			// non-default one for A, and E0100 has a non-default one for C.
+			be16(0, 5, 14, 0, 58, 0, 2),
+			[]byte{0x00, 0xfe, 0x00}, be16(0, 32, 0, 40),
+			[]byte{0x0e, 0x01, 0x00}, be16(0, 0, 0, 49),
+			be16(0, 1), []byte{0, 0, 'B', 0},
+			be16(0, 1), []byte{0, 0, 'A'}, be16(99),
+			be16(0, 1), []byte{0, 0, 'C'}, be16(98),
+		),
+	)
+	testCases := []struct {
+		base, selector rune
+		want           Index
+	}{
+		{'A', 0xfe00, 99},
+		{'B', 0xfe00, 37},
+		{'C', 0xfe00, 38},
+		{'C', 0xe0100, 98},
+		{'A', 0xe0100, 36},
+		{'A', 0xfe01, 36},
+	}
+	for _, tc := range testCases {
+		if got := f.IndexVariation(tc.base, tc.selector); got != tc.want {
+			t.Errorf("IndexVariation(%q, %#x): got %d, want %d", tc.base, tc.selector, got, tc.want)
+		}
+	}
+	if got, want := f.Substitute([]rune{'C', 0xe0100, 'A'}, nil), []Index{98, 36}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Substitute: got %v, want %v", got, want)
+	}
+}
+
+func TestCmapSubtables(t *testing.T) {
+	macRoman := make([]byte, 256)
+	macRoman['A'], macRoman[0x8a] = 36, 40
+	symbolDelta := (40 - 0xf041) & 0xffff
+	subtables := [][]byte{
+		// A Mac OS Roman subtable for the English language.
+		cat(be16(1, 0, 0, 262, 1), macRoman),
+		// A Microsoft Symbol subtable that maps U+F041 and U+F042.
+		be16(3, 0, 4, 32, 0, 4, 0, 0, 0, 0xf042, 0xffff, 0, 0xf041, 0xffff, symbolDelta, 1, 0, 0),
+		// A Microsoft Unicode BMP subtable that maps 'A'.
+		cat(be16(3, 1, 6, 12, 0, 0x41, 1), be16(37)),
+	}
+	f := makeTestCmapFont(t, subtables...)
+	want := []CmapSubtable{
+		{PlatformID: 1, EncodingID: 0, Language: 1, Format: 0},
+		{PlatformID: 3, EncodingID: 0, Language: 0, Format: 4},
+		{PlatformID: 3, EncodingID: 1, Language: 0, Format: 6},
+	}
+	if got := f.CmapSubtables(); !reflect.DeepEqual(got, want) {
+		t.Errorf("CmapSubtables: got %v, want %v", got, want)
+	}
+	if got := f.Index('A'); got != 37 {
+		t.Errorf("default: Index('A'): got %d, want 37", got)
+	}
+
+	testCases := []struct {
+		subtable int
+		want     map[rune]Index
+	}{
+		{0, map[rune]Index{'A': 36, 'ä': 40, 0x8a: 0, 'B': 0}},
+		{1, map[rune]Index{'A': 40, 'B': 41, 0xf041: 40, 'C': 0, 0x141: 0}},
+		{2, map[rune]Index{'A': 37, 'ä': 0}},
+	}
+	for _, tc := range testCases {
+		g, err := f.SelectCmap(tc.subtable)
+		if err != nil {
+			t.Errorf("SelectCmap(%d): %v", tc.subtable, err)
+			continue
+		}
+		for r, want := range tc.want {
+			if got := g.Index(r); got != want {
+				t.Errorf("SelectCmap(%d): Index(%q): got %d, want %d", tc.subtable, r, got, want)
+			}
+		}
+	}
+	if _, err := f.SelectCmap(3); err == nil {
+		t.Errorf("SelectCmap(3): got nil error, want non-nil")
+	}
+
+	// A font with only a Mac OS Roman subtable uses that.
+	f = makeTestCmapFont(t, subtables[0])
+	if got := f.Index('ä'); got != 40 {
+		t.Errorf("Mac OS Roman only: Index('ä'): got %d, want 40", got)
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	f, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	coverage := f.Coverage()
+	if len(coverage) == 0 {
+		t.Fatal("no coverage")
+	}
+	covered := func(r rune) bool {
+		for _, rr := range coverage {
+			if rr.Lo <= r && r <= rr.Hi {
+				return true
+			}
+		}
+		return false
+	}
+	for i, rr := range coverage {
+		if rr.Lo > rr.Hi || i > 0 && rr.Lo <= coverage[i-1].Hi+1 {
+			t.Fatalf("coverage %v is not sorted and merged", coverage)
+		}
+	}
+	for r := rune(0); r < 0x10000; r++ {
+		i := f.Index(r)
+		if got, want := covered(r), i != 0; got != want {
+			t.Fatalf("rune %#x: covered: got %t, want %t", r, got, want)
+		}
+		if i == 0 {
+			continue
+		}
+		found := false
+		for _, s := range f.Runes(i) {
+			if s == r {
+				found = true
+			} else if f.Index(s) != i {
+				t.Fatalf("Runes(%d) includes %#x, which maps to %d", i, s, f.Index(s))
+			}
+		}
+		if !found {
+			t.Fatalf("Runes(%d) does not include %#x", i, r)
+		}
+	}
+	if got := f.Runes(0); got != nil {
+		t.Errorf("Runes(0): got %v, want nil", got)
+	}
+}
+
+func TestCoverageEncodings(t *testing.T) {
+	macRoman := make([]byte, 256)
+	macRoman['A'], macRoman['B'], macRoman[0x8a] = 36, 37, 40
+	testCases := []struct {
+		desc     string
+		subtable []byte
+		want     []RuneRange
+		runes    map[Index][]rune
+	}{{
+		"Mac OS Roman",
+		cat(be16(1, 0, 0, 262, 0), macRoman),
+		[]RuneRange{{'A', 'B'}, {'ä', 'ä'}},
+		map[Index][]rune{36: {'A'}, 40: {'ä'}, 41: nil},
+	}, {
+		"Microsoft Symbol",
+		be16(3, 0, 4, 32, 0, 4, 0, 0, 0, 0xf042, 0xffff, 0, 0xf041, 0xffff, (40-0xf041)&0xffff, 1, 0, 0),
+		[]RuneRange{{'A', 'B'}, {0xf041, 0xf042}},
+		map[Index][]rune{40: {'A', 0xf041}, 41: {'B', 0xf042}},
+	}, {
+		"format 13",
+		cat(be16(0, 4, 13, 0, 0, 40, 0, 0, 0, 2),
+			be16(0, 0x20, 0, 0x7e, 0, 3), be16(1, 0, 2, 0xffff, 0, 4)),
+		[]RuneRange{{0x20, 0x7e}, {0x10000, 0x2ffff}},
+		map[Index][]rune{5: nil},
+	}}
+	for _, tc := range testCases {
+		f := makeTestCmapFont(t, tc.subtable)
+		if got := f.Coverage(); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: Coverage: got %v, want %v", tc.desc, got, tc.want)
+		}
+		for i, want := range tc.runes {
+			if got := f.Runes(i); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: Runes(%d): got %q, want %q", tc.desc, i, got, want)
+			}
+		}
+	}
+}
+
+// kernFormat0 returns the body of a format 0 kern subtable. The pairs, of
+// left glyph, right glyph and value, must be sorted.
+func kernFormat0(pairs ...[3]int) []byte {
+	b := be16(len(pairs), 0, 0, 0)
+	for _, p := range pairs {
+		b = append(b, be16(p[0], p[1], p[2])...)
+	}
+	return b
+}
+
+// kernFormat2 returns the body of a format 2 kern subtable, with a header of
+// length h, that kerns the glyph pair (i0, i1) by v.
+func kernFormat2(h int, i0, i1 Index, v int) []byte {
+	left := h + 8
+	right := left + 6
+	array := right + 6
+	// Each row has two classes. The left glyph's class is row 1, and the
+	// right glyph's class is column 1.
+	b := be16(4, left, right, array)
+	b = append(b, be16(int(i0), 1, array+4)...)
+	b = append(b, be16(int(i1), 1, 2)...)
+	return append(b, be16(0, 0, 0, v)...)
+}
+
+func TestKern(t *testing.T) {
+	f, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	A, V, T, o := f.Index('A'), f.Index('V'), f.Index('T'), f.Index('o')
+	format0 := kernFormat0([3]int{int(A), int(V), -50}, [3]int{int(T), int(o), -20})
+	ms := func(coverage int, body []byte) []byte {
+		return append(be16(0, 6+len(body), coverage), body...)
+	}
+	apple := func(coverage int, body []byte) []byte {
+		return append(be16(0, 8+len(body), coverage, 0), body...)
+	}
+
+	testCases := []struct {
+		desc       string
+		kern       []byte
+		AV, To, oT fixed.Int26_6
+	}{{
+		"one subtable",
+		append(be16(0, 1), ms(0x0001, format0)...),
+		-50, -20, 0,
+	}, {
+		"format 0 and format 2 subtables are summed",
+		cat(be16(0, 2), ms(0x0001, format0), ms(0x0201, kernFormat2(6, T, o, -30))),
+		-50, -50, 0,
+	}, {
+		"cross-stream, minimum and vertical subtables are ignored",
+		cat(be16(0, 4), ms(0x0005, format0), ms(0x0003, format0), ms(0x0000, format0),
+			ms(0x0201, kernFormat2(6, o, T, -10))),
+		0, 0, -10,
+	}, {
+		"an override subtable replaces the kerning before it",
+		cat(be16(0, 2), ms(0x0001, format0), ms(0x0209, kernFormat2(6, T, o, -30))),
+		-50, -30, 0,
+	}, {
+		"Apple header",
+		cat(be16(1, 0, 0, 3), apple(0x0000, format0), apple(0x4000, format0),
+			apple(0x0002, kernFormat2(8, o, T, -10))),
+		-50, -20, -10,
+	}, {
+		"Apple vertical and variation subtables are ignored",
+		cat(be16(1, 0, 0, 2), apple(0x8000, format0), apple(0x2000, format0)),
+		0, 0, 0,
+	}}
+	for _, tc := range testCases {
+		g := makeTestFont(t, map[string][]byte{"kern": tc.kern})
+		fupe := fixed.Int26_6(g.FUnitsPerEm())
+		if got := g.Kern(fupe, A, V); got != tc.AV {
+			t.Errorf("%s: Kern(A, V): got %v, want %v", tc.desc, got, tc.AV)
+		}
+		if got := g.Kern(fupe, T, o); got != tc.To {
+			t.Errorf("%s: Kern(T, o): got %v, want %v", tc.desc, got, tc.To)
+		}
+		if got := g.Kern(fupe, o, T); got != tc.oT {
+			t.Errorf("%s: Kern(o, T): got %v, want %v", tc.desc, got, tc.oT)
+		}
+	}
+
+	// The length of a large format 0 subtable overflows 16 bits, but the font
+	// still parses.
+	var pairs [][3]int
+	for i := 0; i < 11000; i++ {
+		pairs = append(pairs, [3]int{i / 100, i % 100, -1})
+	}
+	body := kernFormat0(pairs...)
+	makeTestFont(t, map[string][]byte{"kern": cat(be16(0, 1), ms(0x0001, body))})
+}
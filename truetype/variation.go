@@ -0,0 +1,919 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+// This file implements OpenType Font Variations, as described at
+// https://www.microsoft.com/typography/otspec/otvaroverview.htm
+//
+// The fvar table defines a variable font's axes and named instances. User
+// co-ordinates along those axes are normalized to the range [-1, +1] and then
+// remapped by the avar table. The gvar table holds deltas for glyf outlines,
+// and the HVAR table holds deltas for advance widths, both interpolated by
+// regions of the normalized design space.
+
+import (
+	"fmt"
+	"math"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// A VariationAxis describes one of a variable font's axes of variation, such
+// as weight or width.
+type VariationAxis struct {
+	// Tag is the axis' four byte tag, such as "wght" for weight.
+	Tag string
+	// Min, Default and Max are the axis' range and default value, in user
+	// co-ordinates. For example, a weight axis might range from 100 to 900
+	// with a default of 400.
+	Min, Default, Max float64
+	// NameID identifies the axis' name in the name table.
+	NameID NameID
+	// Hidden is whether the axis is not meant to be exposed directly in a
+	// user interface.
+	Hidden bool
+}
+
+// A NamedInstance is a predefined point in a variable font's design space,
+// such as "Bold Condensed".
+type NamedInstance struct {
+	// SubfamilyNameID identifies the instance's subfamily name, such as
+	// "Bold Condensed", in the name table.
+	SubfamilyNameID NameID
+	// PostScriptNameID identifies the instance's PostScript name in the name
+	// table. It is 0xffff if the font does not provide one.
+	PostScriptNameID NameID
+	// Coords maps axis tags to user co-ordinates.
+	Coords map[string]float64
+}
+
+// VariationAxes returns the axes of a variable font, in fvar order. It
+// returns nil for a font that is not a variable font.
+func (f *Font) VariationAxes() []VariationAxis {
+	return append([]VariationAxis(nil), f.axes...)
+}
+
+// NamedInstances returns the named instances of a variable font.
+func (f *Font) NamedInstances() []NamedInstance {
+	if len(f.fvar) < 16 {
+		return nil
+	}
+	axesOffset := int(u16(f.fvar, 4))
+	axisSize, n, size := int(u16(f.fvar, 10)), int(u16(f.fvar, 12)), int(u16(f.fvar, 14))
+	offset := axesOffset + axisSize*len(f.axes)
+	if size < 4+4*len(f.axes) || len(f.fvar) < offset+n*size {
+		return nil
+	}
+	instances := make([]NamedInstance, n)
+	for i := range instances {
+		b := f.fvar[offset+i*size:]
+		in := &instances[i]
+		in.SubfamilyNameID = NameID(u16(b, 0))
+		in.PostScriptNameID = 0xffff
+		if size >= 6+4*len(f.axes) {
+			in.PostScriptNameID = NameID(u16(b, 4+4*len(f.axes)))
+		}
+		in.Coords = make(map[string]float64, len(f.axes))
+		for j, a := range f.axes {
+			in.Coords[a.Tag] = fixedToFloat(u32(b, 4+4*j))
+		}
+	}
+	return instances
+}
+
+// Instance returns a Font for the given point in a variable font's design
+// space. coords maps axis tags, such as "wght", to user co-ordinates. Axes
+// that are absent take their default value, and values outside an axis'
+// range are clamped. Tags that are not one of the font's axes are ignored.
+//
+// The returned Font shares its data with f. For a font that is not a variable
+// font, Instance returns f.
+func (f *Font) Instance(coords map[string]float64) *Font {
+	if len(f.axes) == 0 {
+		return f
+	}
+	g := *f
+	g.coords = f.normalize(coords)
+	return &g
+}
+
+// normalize converts user co-ordinates to normalized co-ordinates, one per
+// axis, as per https://www.microsoft.com/typography/otspec/otvaroverview.htm#CSN
+func (f *Font) normalize(coords map[string]float64) []float64 {
+	ret := make([]float64, len(f.axes))
+	for i, a := range f.axes {
+		v, ok := coords[a.Tag]
+		if !ok {
+			continue
+		}
+		switch {
+		case v < a.Min:
+			v = a.Min
+		case v > a.Max:
+			v = a.Max
+		}
+		switch {
+		case v < a.Default:
+			v = (v - a.Default) / (a.Default - a.Min)
+		case v > a.Default:
+			v = (v - a.Default) / (a.Max - a.Default)
+		default:
+			v = 0
+		}
+		v = roundF2Dot14(v)
+		if i < len(f.avarMaps) {
+			v = roundF2Dot14(avarMap(f.avarMaps[i], v))
+		}
+		ret[i] = v
+	}
+	return ret
+}
+
+// roundF2Dot14 rounds x to the nearest 2.14 fixed point value.
+func roundF2Dot14(x float64) float64 {
+	return math.Floor(x*16384+0.5) / 16384
+}
+
+// f2dot14ToFloat converts a 2.14 fixed point value to a float64.
+func f2dot14ToFloat(x uint16) float64 {
+	return float64(int16(x)) / 16384
+}
+
+// fixedToFloat converts a 16.16 fixed point value to a float64.
+func fixedToFloat(x uint32) float64 {
+	return float64(int32(x)) / 65536
+}
+
+// avarMap applies an avar segment map, a piecewise linear function given by
+// its (from, to) co-ordinate pairs, to x.
+func avarMap(m [][2]float64, x float64) float64 {
+	if len(m) == 0 {
+		return x
+	}
+	for i, p := range m {
+		if x == p[0] {
+			return p[1]
+		}
+		if x < p[0] {
+			if i == 0 {
+				return x
+			}
+			q := m[i-1]
+			return q[1] + (p[1]-q[1])*(x-q[0])/(p[0]-q[0])
+		}
+	}
+	return x
+}
+
+// parseVariations parses the fvar, avar, gvar and HVAR tables. The variation
+// tables are optional, so if any of them is malformed then they are all
+// ignored, and f is left as a non-variable font.
+func (f *Font) parseVariations() {
+	err := f.parseFvar()
+	if err == nil {
+		err = f.parseAvar()
+	}
+	if err == nil {
+		err = f.parseGvar()
+	}
+	if err == nil {
+		err = f.parseHvar()
+	}
+	if err != nil {
+		f.fvar, f.axes, f.avarMaps, f.gvarTable, f.hvarTable = nil, nil, nil, nil, nil
+	}
+}
+
+func (f *Font) parseFvar() error {
+	if len(f.fvar) == 0 {
+		return nil
+	}
+	if len(f.fvar) < 16 {
+		return FormatError("fvar data too short")
+	}
+	if major := u16(f.fvar, 0); major != 1 {
+		return UnsupportedError(fmt.Sprintf("fvar version: %d", major))
+	}
+	offset, n, size := int(u16(f.fvar, 4)), int(u16(f.fvar, 8)), int(u16(f.fvar, 10))
+	if size < 20 || len(f.fvar) < offset+n*size {
+		return FormatError("bad fvar axes")
+	}
+	f.axes = make([]VariationAxis, n)
+	for i := range f.axes {
+		b := f.fvar[offset+i*size:]
+		a := VariationAxis{
+			Tag:     string(b[0:4]),
+			Min:     fixedToFloat(u32(b, 4)),
+			Default: fixedToFloat(u32(b, 8)),
+			Max:     fixedToFloat(u32(b, 12)),
+			Hidden:  u16(b, 16)&0x0001 != 0,
+			NameID:  NameID(u16(b, 18)),
+		}
+		if a.Min > a.Default || a.Default > a.Max {
+			return FormatError(fmt.Sprintf("bad fvar axis range for %q", a.Tag))
+		}
+		f.axes[i] = a
+	}
+	return nil
+}
+
+func (f *Font) parseAvar() error {
+	if len(f.avar) == 0 || len(f.axes) == 0 {
+		return nil
+	}
+	if len(f.avar) < 8 {
+		return FormatError("avar data too short")
+	}
+	if major := u16(f.avar, 0); major != 1 {
+		return UnsupportedError(fmt.Sprintf("avar version: %d", major))
+	}
+	n := int(u16(f.avar, 6))
+	if n != len(f.axes) {
+		return FormatError("inconsistent avar axis count")
+	}
+	f.avarMaps = make([][][2]float64, n)
+	offset := 8
+	for i := range f.avarMaps {
+		if len(f.avar) < offset+2 {
+			return FormatError("avar data too short")
+		}
+		count := int(u16(f.avar, offset))
+		offset += 2
+		if len(f.avar) < offset+4*count {
+			return FormatError("avar data too short")
+		}
+		m := make([][2]float64, count)
+		for j := range m {
+			m[j] = [2]float64{
+				f2dot14ToFloat(u16(f.avar, offset)),
+				f2dot14ToFloat(u16(f.avar, offset+2)),
+			}
+			offset += 4
+		}
+		f.avarMaps[i] = m
+	}
+	return nil
+}
+
+// gvarTable holds the parsed header of a gvar table.
+type gvarTable struct {
+	axisCount   int
+	glyphCount  int
+	longOffsets bool
+	// sharedTuples holds the shared peak tuples, as 2.14 fixed point values.
+	sharedTuples []byte
+	offsets      []byte
+	data         []byte
+}
+
+func (f *Font) parseGvar() error {
+	if len(f.gvar) == 0 || len(f.axes) == 0 {
+		return nil
+	}
+	b := f.gvar
+	if len(b) < 20 {
+		return FormatError("gvar data too short")
+	}
+	if major := u16(b, 0); major != 1 {
+		return UnsupportedError(fmt.Sprintf("gvar version: %d", major))
+	}
+	t := &gvarTable{
+		axisCount:   int(u16(b, 4)),
+		glyphCount:  int(u16(b, 12)),
+		longOffsets: u16(b, 14)&0x0001 != 0,
+	}
+	if t.axisCount != len(f.axes) {
+		return FormatError("inconsistent gvar axis count")
+	}
+	nShared, sharedOffset, dataOffset := int(u16(b, 6)), int(u32(b, 8)), int(u32(b, 16))
+	n := 2 * t.axisCount * nShared
+	if sharedOffset < 0 || len(b) < sharedOffset+n {
+		return FormatError("bad gvar shared tuples")
+	}
+	t.sharedTuples = b[sharedOffset : sharedOffset+n]
+	offSize := 2
+	if t.longOffsets {
+		offSize = 4
+	}
+	n = offSize * (t.glyphCount + 1)
+	if len(b) < 20+n || dataOffset < 0 || len(b) < dataOffset {
+		return FormatError("bad gvar offsets")
+	}
+	t.offsets = b[20 : 20+n]
+	t.data = b[dataOffset:]
+	f.gvarTable = t
+	return nil
+}
+
+// glyphData returns the GlyphVariationData for the glyph with the given
+// index, or nil if there is none.
+func (t *gvarTable) glyphData(i Index) []byte {
+	if int(i) >= t.glyphCount {
+		return nil
+	}
+	var o0, o1 int
+	if t.longOffsets {
+		o0, o1 = int(u32(t.offsets, 4*int(i))), int(u32(t.offsets, 4*int(i)+4))
+	} else {
+		o0, o1 = 2*int(u16(t.offsets, 2*int(i))), 2*int(u16(t.offsets, 2*int(i)+2))
+	}
+	if o0 < 0 || o1 <= o0 || len(t.data) < o1 {
+		return nil
+	}
+	return t.data[o0:o1]
+}
+
+// vary adds the gvar deltas, at f's normalized co-ordinates, to the points of
+// the glyph with the given index. ps holds the glyph's points, in FUnits,
+// followed by its four phantom points. For a compound glyph, each component
+// is a point, whose co-ordinates are the component's offset. ends holds a
+// simple glyph's contour end points, and is used to infer the deltas of
+// points that a tuple variation does not reference. It is nil for a compound
+// glyph.
+func (f *Font) vary(i Index, ps []Point, ends []int) error {
+	if f.gvarTable == nil || len(f.coords) == 0 {
+		return nil
+	}
+	data := f.gvarTable.glyphData(i)
+	if len(data) < 4 {
+		return nil
+	}
+	dx := make([]float64, len(ps))
+	dy := make([]float64, len(ps))
+	if err := f.gvarTable.deltas(data, f.coords, ps, ends, dx, dy); err != nil {
+		return err
+	}
+	n := len(ps)
+	for j := 0; j < n; j++ {
+		if f.hvarTable != nil && n-4 <= j && j < n-2 {
+			// The horizontal phantom points' deltas are superseded by HVAR,
+			// which has already been applied to the glyph's metrics.
+			continue
+		}
+		ps[j].X += fixed.Int26_6(math.Floor(dx[j] + 0.5))
+		ps[j].Y += fixed.Int26_6(math.Floor(dy[j] + 0.5))
+	}
+	return nil
+}
+
+// Tuple variation header flags.
+const (
+	tupleEmbeddedPeak        = 0x8000
+	tupleIntermediateRegion  = 0x4000
+	tuplePrivatePointNumbers = 0x2000
+	tupleIndexMask           = 0x0fff
+	tupleSharedPointNumbers  = 0x8000
+	tupleCountMask           = 0x0fff
+)
+
+// deltas accumulates the deltas of a GlyphVariationData into dx and dy.
+func (t *gvarTable) deltas(data []byte, coords []float64, ps []Point, ends []int, dx, dy []float64) error {
+	count, dataOffset := u16(data, 0), int(u16(data, 2))
+	if len(data) < dataOffset {
+		return FormatError("bad gvar data offset")
+	}
+	headers, serialized := data[4:dataOffset], data[dataOffset:]
+	var sharedPoints []int
+	if count&tupleSharedPointNumbers != 0 {
+		var err error
+		sharedPoints, serialized, err = parsePackedPoints(serialized, len(ps))
+		if err != nil {
+			return err
+		}
+	}
+	peak := make([]float64, t.axisCount)
+	start := make([]float64, t.axisCount)
+	end := make([]float64, t.axisCount)
+	tdx := make([]float64, len(ps))
+	tdy := make([]float64, len(ps))
+	touched := make([]bool, len(ps))
+	for n := int(count & tupleCountMask); n > 0; n-- {
+		if len(headers) < 4 {
+			return FormatError("gvar tuple header too short")
+		}
+		size, index := int(u16(headers, 0)), u16(headers, 2)
+		headers = headers[4:]
+		if index&tupleEmbeddedPeak != 0 {
+			if len(headers) < 2*t.axisCount {
+				return FormatError("gvar tuple header too short")
+			}
+			readTuple(peak, headers)
+			headers = headers[2*t.axisCount:]
+		} else {
+			j := int(index & tupleIndexMask)
+			if len(t.sharedTuples) < 2*t.axisCount*(j+1) {
+				return FormatError("bad gvar shared tuple index")
+			}
+			readTuple(peak, t.sharedTuples[2*t.axisCount*j:])
+		}
+		intermediate := index&tupleIntermediateRegion != 0
+		if intermediate {
+			if len(headers) < 4*t.axisCount {
+				return FormatError("gvar tuple header too short")
+			}
+			readTuple(start, headers)
+			readTuple(end, headers[2*t.axisCount:])
+			headers = headers[4*t.axisCount:]
+		}
+		if len(serialized) < size {
+			return FormatError("gvar tuple data too short")
+		}
+		tuple := serialized[:size]
+		serialized = serialized[size:]
+
+		scalar := tupleScalar(coords, peak, start, end, intermediate)
+		if scalar == 0 {
+			continue
+		}
+		points := sharedPoints
+		if index&tuplePrivatePointNumbers != 0 {
+			var err error
+			points, tuple, err = parsePackedPoints(tuple, len(ps))
+			if err != nil {
+				return err
+			}
+		}
+		nDeltas := len(ps)
+		if points != nil {
+			nDeltas = len(points)
+		}
+		xs, tuple, err := parsePackedDeltas(tuple, nDeltas)
+		if err != nil {
+			return err
+		}
+		ys, _, err := parsePackedDeltas(tuple, nDeltas)
+		if err != nil {
+			return err
+		}
+
+		if points == nil {
+			for j := range ps {
+				dx[j] += scalar * xs[j]
+				dy[j] += scalar * ys[j]
+			}
+			continue
+		}
+		for j := range tdx {
+			tdx[j], tdy[j], touched[j] = 0, 0, false
+		}
+		for k, p := range points {
+			if p < len(ps) {
+				tdx[p], tdy[p], touched[p] = xs[k], ys[k], true
+			}
+		}
+		if ends != nil {
+			inferDeltas(ps, ends, touched, tdx, tdy)
+		}
+		for j := range ps {
+			dx[j] += scalar * tdx[j]
+			dy[j] += scalar * tdy[j]
+		}
+	}
+	return nil
+}
+
+// readTuple reads len(dst) 2.14 fixed point values from b.
+func readTuple(dst []float64, b []byte) {
+	for i := range dst {
+		dst[i] = f2dot14ToFloat(u16(b, 2*i))
+	}
+}
+
+// tupleScalar returns the scalar for a tuple variation's deltas, as per
+// https://www.microsoft.com/typography/otspec/otvaroverview.htm#algorithm
+func tupleScalar(coords, peak, start, end []float64, intermediate bool) float64 {
+	scalar := 1.0
+	for i, p := range peak {
+		if p == 0 {
+			continue
+		}
+		c := 0.0
+		if i < len(coords) {
+			c = coords[i]
+		}
+		if c == p {
+			continue
+		}
+		if intermediate {
+			s, e := start[i], end[i]
+			if s > p || p > e || (s < 0 && e > 0) {
+				continue
+			}
+			if c < s || c > e {
+				return 0
+			}
+			if c < p {
+				scalar *= (c - s) / (p - s)
+			} else {
+				scalar *= (e - c) / (e - p)
+			}
+			continue
+		}
+		if c == 0 || c < math.Min(0, p) || c > math.Max(0, p) {
+			return 0
+		}
+		scalar *= c / p
+	}
+	return scalar
+}
+
+// parsePackedPoints parses packed point numbers. It returns nil points,
+// meaning all n points, if the count is zero.
+func parsePackedPoints(b []byte, n int) (points []int, rest []byte, err error) {
+	if len(b) < 1 {
+		return nil, nil, FormatError("gvar point numbers too short")
+	}
+	count := int(b[0])
+	b = b[1:]
+	if count&0x80 != 0 {
+		if len(b) < 1 {
+			return nil, nil, FormatError("gvar point numbers too short")
+		}
+		count = (count&0x7f)<<8 | int(b[0])
+		b = b[1:]
+	}
+	if count == 0 {
+		return nil, b, nil
+	}
+	points = make([]int, 0, count)
+	p := 0
+	for len(points) < count {
+		if len(b) < 1 {
+			return nil, nil, FormatError("gvar point numbers too short")
+		}
+		control := b[0]
+		b = b[1:]
+		runLen := int(control&0x7f) + 1
+		words := control&0x80 != 0
+		for ; runLen > 0 && len(points) < count; runLen-- {
+			if words {
+				if len(b) < 2 {
+					return nil, nil, FormatError("gvar point numbers too short")
+				}
+				p += int(u16(b, 0))
+				b = b[2:]
+			} else {
+				if len(b) < 1 {
+					return nil, nil, FormatError("gvar point numbers too short")
+				}
+				p += int(b[0])
+				b = b[1:]
+			}
+			points = append(points, p)
+		}
+	}
+	return points, b, nil
+}
+
+// parsePackedDeltas parses n packed deltas.
+func parsePackedDeltas(b []byte, n int) (deltas []float64, rest []byte, err error) {
+	deltas = make([]float64, 0, n)
+	for len(deltas) < n {
+		if len(b) < 1 {
+			return nil, nil, FormatError("gvar deltas too short")
+		}
+		control := b[0]
+		b = b[1:]
+		runLen := int(control&0x3f) + 1
+		for ; runLen > 0 && len(deltas) < n; runLen-- {
+			switch {
+			case control&0x80 != 0:
+				deltas = append(deltas, 0)
+			case control&0x40 != 0:
+				if len(b) < 2 {
+					return nil, nil, FormatError("gvar deltas too short")
+				}
+				deltas = append(deltas, float64(int16(u16(b, 0))))
+				b = b[2:]
+			default:
+				if len(b) < 1 {
+					return nil, nil, FormatError("gvar deltas too short")
+				}
+				deltas = append(deltas, float64(int8(b[0])))
+				b = b[1:]
+			}
+		}
+	}
+	return deltas, b, nil
+}
+
+// inferDeltas infers the deltas of the points that a tuple variation does not
+// reference, by interpolating between the nearest referenced points on the
+// same contour, as per
+// https://www.microsoft.com/typography/otspec/gvar.htm#IDUP
+func inferDeltas(ps []Point, ends []int, touched []bool, dx, dy []float64) {
+	start := 0
+	for _, end := range ends {
+		if end > len(ps) {
+			break
+		}
+		first := -1
+		for j := start; j < end; j++ {
+			if touched[j] {
+				first = j
+				break
+			}
+		}
+		if first < 0 {
+			start = end
+			continue
+		}
+		// Walk the contour from each touched point to the next.
+		prev := first
+		for k := 1; k <= end-start; k++ {
+			j := start + (first-start+k)%(end-start)
+			if !touched[j] {
+				continue
+			}
+			for m := start + (prev-start+1)%(end-start); m != j; m = start + (m-start+1)%(end-start) {
+				dx[m] = inferDelta(ps[m].X, ps[prev].X, ps[j].X, dx[prev], dx[j])
+				dy[m] = inferDelta(ps[m].Y, ps[prev].Y, ps[j].Y, dy[prev], dy[j])
+			}
+			prev = j
+		}
+		start = end
+	}
+}
+
+// inferDelta returns the inferred delta of a co-ordinate c, given the
+// co-ordinates and deltas of its two reference points.
+func inferDelta(c, c1, c2 fixed.Int26_6, d1, d2 float64) float64 {
+	if c1 == c2 {
+		if d1 == d2 {
+			return d1
+		}
+		return 0
+	}
+	if c1 > c2 {
+		c1, c2, d1, d2 = c2, c1, d2, d1
+	}
+	switch {
+	case c <= c1:
+		return d1
+	case c >= c2:
+		return d2
+	}
+	return d1 + (d2-d1)*float64(c-c1)/float64(c2-c1)
+}
+
+// itemVariationStore is a parsed ItemVariationStore, as used by the HVAR and
+// CFF2 tables.
+type itemVariationStore struct {
+	// regions[r][a] is the start, peak and end of region r on axis a.
+	regions [][][3]float64
+	data    []itemVariationData
+}
+
+// itemVariationData is a parsed ItemVariationData subtable.
+type itemVariationData struct {
+	itemCount     int
+	regionIndexes []int
+	// wordCount is the number of leading deltas in each row that are words
+	// (or 32-bit if long is set). The remaining deltas are bytes (or words).
+	wordCount int
+	long      bool
+	rowSize   int
+	rows      []byte
+}
+
+// parseItemVariationStore parses the ItemVariationStore at b.
+func parseItemVariationStore(b []byte) (*itemVariationStore, error) {
+	if len(b) < 8 {
+		return nil, FormatError("ItemVariationStore too short")
+	}
+	if format := u16(b, 0); format != 1 {
+		return nil, UnsupportedError(fmt.Sprintf("ItemVariationStore format: %d", format))
+	}
+	s := &itemVariationStore{}
+	if o := int(u32(b, 2)); o != 0 {
+		if o < 0 || len(b) < o+4 {
+			return nil, FormatError("bad VariationRegionList offset")
+		}
+		axisCount, regionCount := int(u16(b, o)), int(u16(b, o+2))
+		if len(b) < o+4+6*axisCount*regionCount {
+			return nil, FormatError("VariationRegionList too short")
+		}
+		s.regions = make([][][3]float64, regionCount)
+		for r := range s.regions {
+			s.regions[r] = make([][3]float64, axisCount)
+			for a := range s.regions[r] {
+				x := o + 4 + 6*(r*axisCount+a)
+				s.regions[r][a] = [3]float64{
+					f2dot14ToFloat(u16(b, x+0)),
+					f2dot14ToFloat(u16(b, x+2)),
+					f2dot14ToFloat(u16(b, x+4)),
+				}
+			}
+		}
+	}
+	n := int(u16(b, 6))
+	if len(b) < 8+4*n {
+		return nil, FormatError("ItemVariationStore too short")
+	}
+	s.data = make([]itemVariationData, n)
+	for i := range s.data {
+		o := int(u32(b, 8+4*i))
+		if o < 0 || len(b) < o+6 {
+			return nil, FormatError("bad ItemVariationData offset")
+		}
+		d := &s.data[i]
+		d.itemCount = int(u16(b, o))
+		wordDeltaCount := u16(b, o+2)
+		d.long = wordDeltaCount&0x8000 != 0
+		d.wordCount = int(wordDeltaCount & 0x7fff)
+		regionIndexCount := int(u16(b, o+4))
+		if len(b) < o+6+2*regionIndexCount || d.wordCount > regionIndexCount {
+			return nil, FormatError("ItemVariationData too short")
+		}
+		d.regionIndexes = make([]int, regionIndexCount)
+		for j := range d.regionIndexes {
+			d.regionIndexes[j] = int(u16(b, o+6+2*j))
+			if d.regionIndexes[j] >= len(s.regions) {
+				return nil, FormatError("bad ItemVariationData region index")
+			}
+		}
+		small, large := 1, 2
+		if d.long {
+			small, large = 2, 4
+		}
+		d.rowSize = large*d.wordCount + small*(regionIndexCount-d.wordCount)
+		start := o + 6 + 2*regionIndexCount
+		if len(b) < start+d.rowSize*d.itemCount {
+			return nil, FormatError("ItemVariationData too short")
+		}
+		d.rows = b[start : start+d.rowSize*d.itemCount]
+	}
+	return s, nil
+}
+
+// scalars returns the scalars, at the given normalized co-ordinates, of the
+// regions referenced by the outer'th ItemVariationData.
+func (s *itemVariationStore) scalars(outer int, coords []float64) []float64 {
+	if outer < 0 || len(s.data) <= outer {
+		return nil
+	}
+	d := &s.data[outer]
+	ret := make([]float64, len(d.regionIndexes))
+	for k, r := range d.regionIndexes {
+		ret[k] = regionScalar(s.regions[r], coords)
+	}
+	return ret
+}
+
+// delta returns the interpolated delta for the item with the given outer and
+// inner indexes, at the given normalized co-ordinates.
+func (s *itemVariationStore) delta(outer, inner int, coords []float64) float64 {
+	if outer < 0 || len(s.data) <= outer {
+		return 0
+	}
+	d := &s.data[outer]
+	if inner < 0 || d.itemCount <= inner {
+		return 0
+	}
+	row := d.rows[inner*d.rowSize:]
+	sum := 0.0
+	for k, r := range d.regionIndexes {
+		var v int32
+		switch {
+		case k < d.wordCount && d.long:
+			v, row = int32(u32(row, 0)), row[4:]
+		case k < d.wordCount || d.long:
+			v, row = int32(int16(u16(row, 0))), row[2:]
+		default:
+			v, row = int32(int8(row[0])), row[1:]
+		}
+		sum += regionScalar(s.regions[r], coords) * float64(v)
+	}
+	return sum
+}
+
+// regionScalar returns the scalar of a VariationRegion at the given
+// normalized co-ordinates.
+func regionScalar(region [][3]float64, coords []float64) float64 {
+	scalar := 1.0
+	for a, r := range region {
+		start, peak, end := r[0], r[1], r[2]
+		if start > peak || peak > end || (start < 0 && end > 0 && peak != 0) || peak == 0 {
+			continue
+		}
+		c := 0.0
+		if a < len(coords) {
+			c = coords[a]
+		}
+		switch {
+		case c < start || c > end:
+			return 0
+		case c == peak:
+		case c < peak:
+			scalar *= (c - start) / (peak - start)
+		default:
+			scalar *= (end - c) / (end - peak)
+		}
+	}
+	return scalar
+}
+
+// hvarTable holds a parsed HVAR table.
+type hvarTable struct {
+	store *itemVariationStore
+	// advanceMap and lsbMap are DeltaSetIndexMaps. A nil advanceMap means
+	// that glyph indexes map directly to inner indexes of the first
+	// ItemVariationData. A nil lsbMap means that there are no left side
+	// bearing variations.
+	advanceMap, lsbMap []byte
+}
+
+func (f *Font) parseHvar() error {
+	if len(f.hvar) == 0 || len(f.axes) == 0 {
+		return nil
+	}
+	b := f.hvar
+	if len(b) < 20 {
+		return FormatError("HVAR data too short")
+	}
+	if major := u16(b, 0); major != 1 {
+		return UnsupportedError(fmt.Sprintf("HVAR version: %d", major))
+	}
+	o := int(u32(b, 4))
+	if o <= 0 || len(b) <= o {
+		return FormatError("bad HVAR ItemVariationStore offset")
+	}
+	store, err := parseItemVariationStore(b[o:])
+	if err != nil {
+		return err
+	}
+	t := &hvarTable{store: store}
+	if o := int(u32(b, 8)); o != 0 {
+		if o < 0 || len(b) <= o {
+			return FormatError("bad HVAR advance width mapping offset")
+		}
+		t.advanceMap = b[o:]
+	}
+	if o := int(u32(b, 12)); o != 0 {
+		if o < 0 || len(b) <= o {
+			return FormatError("bad HVAR left side bearing mapping offset")
+		}
+		t.lsbMap = b[o:]
+	}
+	f.hvarTable = t
+	return nil
+}
+
+// deltaSetIndex looks up the outer and inner indexes for the given glyph in a
+// DeltaSetIndexMap.
+func deltaSetIndex(m []byte, i Index) (outer, inner int, ok bool) {
+	if len(m) < 4 {
+		return 0, 0, false
+	}
+	format, entryFormat := m[0], m[1]
+	var count, offset int
+	switch format {
+	case 0:
+		count, offset = int(u16(m, 2)), 4
+	case 1:
+		if len(m) < 6 {
+			return 0, 0, false
+		}
+		count, offset = int(u32(m, 2)), 6
+	default:
+		return 0, 0, false
+	}
+	if count <= 0 {
+		return 0, 0, false
+	}
+	j := int(i)
+	if j >= count {
+		j = count - 1
+	}
+	size := int(entryFormat>>4&0x03) + 1
+	innerBits := uint(entryFormat&0x0f) + 1
+	if len(m) < offset+count*size {
+		return 0, 0, false
+	}
+	entry := 0
+	for _, c := range m[offset+j*size : offset+(j+1)*size] {
+		entry = entry<<8 | int(c)
+	}
+	return entry >> innerBits, entry & (1<<innerBits - 1), true
+}
+
+// varyHMetric adds the HVAR deltas, at f's normalized co-ordinates, to the
+// unscaled horizontal metrics of the glyph with the given index.
+func (f *Font) varyHMetric(i Index, h *HMetric) {
+	t := f.hvarTable
+	outer, inner, ok := 0, int(i), true
+	if t.advanceMap != nil {
+		outer, inner, ok = deltaSetIndex(t.advanceMap, i)
+	}
+	if ok {
+		h.AdvanceWidth += fixed.Int26_6(math.Floor(t.store.delta(outer, inner, f.coords) + 0.5))
+	}
+	if t.lsbMap != nil {
+		if outer, inner, ok := deltaSetIndex(t.lsbMap, i); ok {
+			h.LeftSideBearing += fixed.Int26_6(math.Floor(t.store.delta(outer, inner, f.coords) + 0.5))
+		}
+	}
+}
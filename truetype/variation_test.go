@@ -0,0 +1,301 @@
+// Copyright 2026 The Freetype-Go Authors. All rights reserved.
+// Use of this source code is governed by your choice of either the
+// FreeType License or the GNU General Public License version 2 (or
+// any later version), both of which can be found in the LICENSE file.
+
+package truetype
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// makeTestVariableFont returns luxisr.ttf with a "wght" axis, ranging from
+// 100 to 900 with a default of 400, plus the given extra tables. The gvar
+// table varies the 'A' glyph's X co-ordinates at the maximum weight.
+func makeTestVariableFont(t *testing.T, extra map[string][]byte) *Font {
+	tables := map[string][]byte{
+		"fvar": {
+			0, 1, 0, 0, 0, 16, 0, 2, 0, 1, 0, 20, 0, 1, 0, 10,
+			// The wght axis.
+			'w', 'g', 'h', 't', 0, 100, 0, 0, 0x01, 0x90, 0, 0, 0x03, 0x84, 0, 0, 0, 0, 0x01, 0x00,
+			// The instance, with subfamily name 257, weight 700 and
+			// PostScript name 258.
+			0x01, 0x01, 0, 0, 0x02, 0xbc, 0, 0, 0x01, 0x02,
+		},
+		"avar": {
+			0, 1, 0, 0, 0, 0, 0, 1, 0, 4,
+			0xc0, 0x00, 0xc0, 0x00, // -1 → -1.
+			0x00, 0x00, 0x00, 0x00, // 0 → 0.
+			0x20, 0x00, 0x10, 0x00, // 0.5 → 0.25.
+			0x40, 0x00, 0x40, 0x00, // 1 → 1.
+		},
+	}
+
+	// The GlyphVariationData for 'A', which has 11 points plus 4 phantom
+	// points. The first tuple moves all points by +10, and the top vertical
+	// phantom point up by 20. The second tuple moves points 0 and 4 by +20
+	// and +40, and the other points on their contour by inferred deltas.
+	// The final zero pads the data to an even length.
+	const indexA = 36
+	data := []byte{
+		0, 2, 0, 16,
+		0, 21, 0xa0, 0, 0x40, 0,
+		0, 8, 0xa0, 0, 0x40, 0,
+		// The first tuple's points, X deltas and Y deltas.
+		0, 14, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 0x8c, 0, 20, 0x80,
+		// The second tuple's points, X deltas and Y deltas.
+		2, 1, 0, 4, 1, 20, 40, 0x81,
+		0,
+	}
+	gvar := []byte{
+		0, 1, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, indexA + 1, 0, 0,
+		0, 0, 0, 0,
+	}
+	for i := 0; i <= indexA+1; i++ {
+		o := 0
+		if i > indexA {
+			o = len(data) / 2
+		}
+		gvar = append(gvar, byte(o>>8), byte(o))
+	}
+	dataOffset := len(gvar)
+	gvar[16], gvar[17], gvar[18], gvar[19] = byte(dataOffset>>24), byte(dataOffset>>16), byte(dataOffset>>8), byte(dataOffset)
+	tables["gvar"] = append(gvar, data...)
+
+	for tag, b := range extra {
+		tables[tag] = b
+	}
+	return makeTestFont(t, tables)
+}
+
+// testHVAR is an HVAR table that adds 100 to the 'A' glyph's advance width at
+// the maximum weight.
+var testHVAR = func() []byte {
+	b := []byte{
+		0, 1, 0, 0, 0, 0, 0, 20, 0, 0, 0, 52, 0, 0, 0, 0, 0, 0, 0, 0,
+		// The ItemVariationStore, with one region and two items.
+		0, 1, 0, 0, 0, 12, 0, 1, 0, 0, 0, 22,
+		0, 1, 0, 1, 0x00, 0x00, 0x40, 0x00, 0x40, 0x00,
+		0, 2, 0, 0, 0, 1, 0, 0, 0, 100,
+		// The advance width mapping, with 38 single-byte entries.
+		0, 0, 0, 38,
+	}
+	for i := 0; i < 38; i++ {
+		e := byte(0)
+		if i == 36 {
+			e = 1
+		}
+		b = append(b, e)
+	}
+	return b
+}()
+
+func TestVariationAxes(t *testing.T) {
+	f := makeTestVariableFont(t, nil)
+	gotAxes := f.VariationAxes()
+	wantAxes := []VariationAxis{{Tag: "wght", Min: 100, Default: 400, Max: 900, NameID: 256}}
+	if !reflect.DeepEqual(gotAxes, wantAxes) {
+		t.Errorf("VariationAxes: got %v, want %v", gotAxes, wantAxes)
+	}
+	gotInstances := f.NamedInstances()
+	wantInstances := []NamedInstance{{
+		SubfamilyNameID:  257,
+		PostScriptNameID: 258,
+		Coords:           map[string]float64{"wght": 700},
+	}}
+	if !reflect.DeepEqual(gotInstances, wantInstances) {
+		t.Errorf("NamedInstances: got %v, want %v", gotInstances, wantInstances)
+	}
+
+	f, _, err := parseTestdataFont("luxisr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.VariationAxes(); len(got) != 0 {
+		t.Errorf("non-variable font: VariationAxes: got %v, want none", got)
+	}
+	if got := f.Instance(map[string]float64{"wght": 700}); got != f {
+		t.Errorf("non-variable font: Instance did not return the Font itself")
+	}
+}
+
+func TestVariationBadTables(t *testing.T) {
+	testCases := []struct {
+		tag  string
+		data []byte
+	}{
+		{"fvar", []byte{0, 1, 0, 0}},
+		{"fvar", []byte{0, 2, 0, 0, 0, 16, 0, 2, 0, 1, 0, 20, 0, 1, 0, 10}},
+		{"avar", []byte{0, 1, 0, 0, 0, 0, 0, 2}},
+		{"gvar", []byte{0, 1, 0, 0}},
+		{"HVAR", []byte{0, 1, 0, 0}},
+	}
+	for _, tc := range testCases {
+		f := makeTestVariableFont(t, map[string][]byte{tc.tag: tc.data})
+		if got := f.VariationAxes(); len(got) != 0 {
+			t.Errorf("%s % x: VariationAxes: got %v, want none", tc.tag, tc.data, got)
+		}
+		if got := f.NamedInstances(); len(got) != 0 {
+			t.Errorf("%s % x: NamedInstances: got %v, want none", tc.tag, tc.data, got)
+		}
+		if got := f.Instance(map[string]float64{"wght": 700}); got != f {
+			t.Errorf("%s % x: Instance did not return the Font itself", tc.tag, tc.data)
+		}
+	}
+}
+
+func TestNormalizeVariation(t *testing.T) {
+	f := makeTestVariableFont(t, nil)
+	testCases := []struct {
+		wght float64
+		want float64
+	}{
+		{0, -1},
+		{100, -1},
+		{250, -0.5},
+		{400, 0},
+		{650, 0.25},
+		{775, 0.625},
+		{900, 1},
+		{1000, 1},
+	}
+	for _, tc := range testCases {
+		got := f.normalize(map[string]float64{"wght": tc.wght})
+		if len(got) != 1 || got[0] != tc.want {
+			t.Errorf("wght=%v: got %v, want [%v]", tc.wght, got, tc.want)
+		}
+	}
+	if got := f.normalize(map[string]float64{"wdth": 50}); len(got) != 1 || got[0] != 0 {
+		t.Errorf("unknown axis: got %v, want [0]", got)
+	}
+}
+
+func TestGlyphVariations(t *testing.T) {
+	// wantDX are the X deltas for the 'A' glyph's 11 points at the maximum
+	// weight. Points 1-3 and 5-7 have inferred deltas for the second tuple.
+	wantDX := []fixed.Int26_6{30, 40, 44, 50, 50, 47, 36, 34, 10, 10, 10}
+
+	testCases := []struct {
+		desc  string
+		extra map[string][]byte
+		// shift is how far the points shift left, due to the first phantom
+		// point's delta.
+		shift   fixed.Int26_6
+		advance fixed.Int26_6
+	}{
+		{"gvar", nil, 10, 0},
+		{"gvar+HVAR", map[string][]byte{"HVAR": testHVAR}, 0, 100},
+	}
+	for _, tc := range testCases {
+		f := makeTestVariableFont(t, tc.extra)
+		fupe := fixed.Int26_6(f.FUnitsPerEm())
+		i := f.Index('A')
+		g0 := &GlyphBuf{}
+		if err := g0.Load(f, fupe, i, font.HintingNone); err != nil {
+			t.Fatalf("%s: default: Load: %v", tc.desc, err)
+		}
+		gd := &GlyphBuf{}
+		if err := gd.Load(f.Instance(map[string]float64{"wght": 400}), fupe, i, font.HintingNone); err != nil {
+			t.Fatalf("%s: wght=400: Load: %v", tc.desc, err)
+		}
+		if fmt.Sprint(gd.Points) != fmt.Sprint(g0.Points) {
+			t.Errorf("%s: wght=400: Points:\ngot  %v\nwant %v", tc.desc, gd.Points, g0.Points)
+		}
+
+		v := f.Instance(map[string]float64{"wght": 900})
+		g := &GlyphBuf{}
+		if err := g.Load(v, fupe, i, font.HintingNone); err != nil {
+			t.Fatalf("%s: wght=900: Load: %v", tc.desc, err)
+		}
+		want := make([]Point, len(g0.Points))
+		for j, p := range g0.Points {
+			want[j] = Point{X: p.X + wantDX[j] - tc.shift, Y: p.Y, Flags: p.Flags}
+		}
+		if got, want := fmt.Sprint(g.Points), fmt.Sprint(want); got != want {
+			t.Errorf("%s: wght=900: Points:\ngot  %v\nwant %v", tc.desc, got, want)
+		}
+		if got, want := g.AdvanceWidth, g0.AdvanceWidth+tc.advance; got != want {
+			t.Errorf("%s: wght=900: AdvanceWidth: got %v, want %v", tc.desc, got, want)
+		}
+		if got, want := g.AdvanceHeight, g0.AdvanceHeight+20; got != want {
+			t.Errorf("%s: wght=900: AdvanceHeight: got %v, want %v", tc.desc, got, want)
+		}
+		if got, want := v.HMetric(fupe, i).AdvanceWidth, f.HMetric(fupe, i).AdvanceWidth+tc.advance; got != want {
+			t.Errorf("%s: wght=900: HMetric.AdvanceWidth: got %v, want %v", tc.desc, got, want)
+		}
+
+		// Glyphs without variation data are unaffected.
+		j := f.Index('V')
+		gv0, gv := &GlyphBuf{}, &GlyphBuf{}
+		if err := gv0.Load(f, fupe, j, font.HintingNone); err != nil {
+			t.Fatalf("%s: 'V': Load: %v", tc.desc, err)
+		}
+		if err := gv.Load(v, fupe, j, font.HintingNone); err != nil {
+			t.Fatalf("%s: 'V': Load: %v", tc.desc, err)
+		}
+		if fmt.Sprint(gv.Points) != fmt.Sprint(gv0.Points) || gv.AdvanceWidth != gv0.AdvanceWidth {
+			t.Errorf("%s: 'V' varied: got %v, want %v", tc.desc, gv.Points, gv0.Points)
+		}
+	}
+}
+
+func TestFaceVariations(t *testing.T) {
+	f := makeTestVariableFont(t, map[string][]byte{"HVAR": testHVAR})
+	a0, _ := NewFace(f, &Options{Size: 64}).GlyphAdvance('A')
+	a1, _ := NewFace(f, &Options{Size: 64, Variations: map[string]float64{"wght": 900}}).GlyphAdvance('A')
+	if a1 <= a0 {
+		t.Errorf("GlyphAdvance: got %v at wght=900, want more than %v", a1, a0)
+	}
+}
+
+func TestCFF2BlendVariations(t *testing.T) {
+	c := &cffFont{
+		cff2:         true,
+		localSubrs:   []cffIndex{{}},
+		vsindexes:    []int{0},
+		regionCounts: []int{2},
+		vstore: &itemVariationStore{
+			regions: [][][3]float64{{{0, 1, 1}}, {{0, 0.5, 1}}},
+			data:    []itemVariationData{{regionIndexes: []int{0, 1}}},
+		},
+	}
+	d := &cffDecoder{c: c, g: &GlyphBuf{}, localSubrs: &c.localSubrs[0], np0: -1, coords: []float64{0.5}}
+	// At 0.5, the first region's scalar is 0.5 and the second's is 1.
+	cs := cffTestCharstring(100, 200, 10, 20, 30, 40, 2, t2blend, t2rmoveto)
+	if err := d.run(cs, 0); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	want := []Point{{X: 125, Y: 255, Flags: flagOnCurve}}
+	if got := d.g.Points; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestTupleScalar(t *testing.T) {
+	testCases := []struct {
+		coord, peak, start, end float64
+		intermediate            bool
+		want                    float64
+	}{
+		{0, 1, 0, 0, false, 0},
+		{0.5, 1, 0, 0, false, 0.5},
+		{1, 1, 0, 0, false, 1},
+		{-0.5, 1, 0, 0, false, 0},
+		{-0.5, -1, 0, 0, false, 0.5},
+		{0.25, 0.5, 0.25, 1, true, 0},
+		{0.75, 0.5, 0.25, 1, true, 0.5},
+		{0.375, 0.5, 0.25, 1, true, 0.5},
+	}
+	for _, tc := range testCases {
+		got := tupleScalar([]float64{tc.coord}, []float64{tc.peak}, []float64{tc.start}, []float64{tc.end}, tc.intermediate)
+		if got != tc.want {
+			t.Errorf("coord=%v peak=%v start=%v end=%v: got %v, want %v",
+				tc.coord, tc.peak, tc.start, tc.end, got, tc.want)
+		}
+	}
+}